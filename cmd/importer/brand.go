@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/brand"
+
+	"github.com/dropezy/storefront-backend/ems-api/validation"
+)
+
+var (
+	errBrandNotFound      = errors.New("brand not found")
+	errBrandDuplicateSlug = errors.New("brand slug is shown on more than one row")
+	errBrandDuplicateName = errors.New("brand name is shown on more than one row")
+	errBrandNameExists    = errors.New("brand name is used by a brand with another slug")
+)
+
+// brandColumns maps brand validation fields to the brands file columns.
+var brandColumns = map[string]string{
+	"name":     "brand_name",
+	"logo_url": "logo_url",
+	"slug":     "slug",
+}
+
+// brandFileColumns are the columns of the brands file.
+var brandFileColumns = fileColumns{
+	required: []string{
+		"brand_name",
+		"logo_url",
+		"slug",
+	},
+}
+
+// importBrands looks into the path given and upserts all the brands,
+// brands are matched by their slug so existing brand ids are kept.
+func importBrands(ctx context.Context, db *mongo.Database, path string, opts importOptions) error {
+	var (
+		brand_name int
+		logo_url   int
+		slug       int
+	)
+
+	// existing brands are matched by slug, their names can't be taken by another slug.
+	existing, err := getBrands(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	var brands []*brand.Brand
+	slugs := map[string]bool{}
+	names := map[string]bool{}
+	errReport := newErrorReport(brandColumns)
+	// reads the brands file row by row, on the format of the file.
+	rows, err := openRowReader(path, opts)
+	if err != nil {
+		return fmt.Errorf("failed to read brands file: %w", err)
+	}
+	defer rows.close()
+	for {
+		line, rowNumber, err := rows.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read brands file lines: %w", err)
+		}
+		if rowNumber == 1 {
+			header, err := readHeader(line, brandFileColumns, opts.headerAliases)
+			if err != nil {
+				return fmt.Errorf("failed to read brands file header: %w", err)
+			}
+			errReport.setHeader(header.names)
+
+			// get all the index for the headers
+			brand_name = header.column("brand_name")
+			logo_url = header.column("logo_url")
+			slug = header.column("slug")
+			continue
+		}
+
+		// every invalid column of the row is recorded, the row is skipped when any is invalid.
+		rowErrors := errReport.len()
+
+		b := &brand.Brand{
+			Name:    strings.TrimSpace(line[brand_name]),
+			LogoURL: line[logo_url],
+			Slug:    line[slug],
+		}
+		// check brand parameter values
+		if err := validation.ValidateBrand(b); err != nil {
+			errReport.add(rowNumber, line, err)
+		}
+
+		// products are linked to brands by name, so names must be unique as well.
+		if slugs[b.Slug] {
+			errReport.addColumn(rowNumber, line, "slug", errBrandDuplicateSlug)
+		}
+		if names[strings.ToLower(b.Name)] {
+			errReport.addColumn(rowNumber, line, "brand_name", errBrandDuplicateName)
+		}
+		if e, ok := existing[strings.ToLower(b.Name)]; ok && e.Slug != b.Slug {
+			errReport.addColumn(rowNumber, line, "brand_name", errBrandNameExists)
+		}
+		if errReport.len() > rowErrors {
+			continue
+		}
+		slugs[b.Slug] = true
+		names[strings.ToLower(b.Name)] = true
+
+		brands = append(brands, b)
+	}
+
+	// fails the import when any row is invalid, so nothing is partially imported.
+	if err := errReport.check(opts.errorsReportPath); err != nil {
+		return err
+	}
+
+	collection := db.Collection(brandCollection)
+
+	// converts all brands to mongo write model for bulk upsert.
+	models := []mongo.WriteModel{}
+	for _, b := range brands {
+		writeModel := mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"slug": b.Slug}).
+			SetUpdate(bson.M{
+				"$set": bson.M{
+					"name":     b.Name,
+					"logo_url": b.LogoURL,
+				},
+				"$setOnInsert": bson.M{"_id": primitive.NewObjectID()},
+			}).
+			SetUpsert(true)
+		models = append(models, writeModel)
+	}
+
+	// bulk upsert brands.
+	if _, err := bulkWrite(ctx, collection, models, opts); err != nil {
+		return fmt.Errorf("failed to execute BulkWrite, on import brands: %w", err)
+	}
+
+	log.Print("successfully imported brands!")
+	return nil
+}
+
+// getBrands fetches all brands in our database indexed by their lowercase name,
+// which will be used to link products to their brand.
+func getBrands(ctx context.Context, db *mongo.Database) (map[string]*brand.Brand, error) {
+	cur, err := db.Collection(brandCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Find brands: %w", err)
+	}
+
+	var brands []*brand.Brand
+	if err := cur.All(ctx, &brands); err != nil {
+		return nil, fmt.Errorf("failed to decode brands: %w", err)
+	}
+
+	brandsByName := make(map[string]*brand.Brand, len(brands))
+	for _, b := range brands {
+		brandsByName[strings.ToLower(b.Name)] = b
+	}
+	return brandsByName, nil
+}
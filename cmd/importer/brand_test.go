@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dropezy/storefront-backend/ems-api/validation"
+)
+
+func TestImportBrands(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		defaultTestTimeout,
+	)
+	t.Cleanup(cancel)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+
+		path := "testdata/brand/success.csv"
+		if err := importBrands(ctx, testDb, path, importOptions{}); err != nil {
+			t.Fatalf("expected nil error, got = %v", err)
+		}
+	})
+
+	// failed scenarios
+	tests := []struct {
+		name    string
+		path    string
+		wantErr error
+	}{
+		{
+			name:    "EmptyBrandName",
+			path:    "testdata/brand/empty_brand_name.csv",
+			wantErr: validation.ErrBrandNameIsRequired,
+		},
+		{
+			name:    "EmptySlug",
+			path:    "testdata/brand/empty_slug.csv",
+			wantErr: validation.ErrBrandSlugIsRequired,
+		},
+		{
+			name:    "InvalidSlug",
+			path:    "testdata/brand/invalid_slug.csv",
+			wantErr: validation.ErrBrandInvalidSlug,
+		},
+		{
+			name:    "DuplicateSlug",
+			path:    "testdata/brand/duplicate_slug.csv",
+			wantErr: errBrandDuplicateSlug,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := importBrands(ctx, testDb, test.path, importOptions{})
+			if !errors.Is(errors.Unwrap(err), test.wantErr) {
+				t.Fatalf("importBrands(_, _) error, got = %v, want = %v", err, test.wantErr)
+			}
+		})
+	}
+}
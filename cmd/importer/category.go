@@ -2,54 +2,57 @@ package main
 
 import (
 	"context"
-	"encoding/csv"
 	"errors"
 	"fmt"
+	"io"
 	"log"
-	"os"
-	"path/filepath"
+	"strings"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/dropezy/storefront-backend/internal/storage/model/category"
 
+	"github.com/dropezy/storefront-backend/ems-api/validation"
+
 	// protobuf
 	ctpb "github.com/dropezy/proto/v1/category"
 )
 
 const categoryCollection = "category"
 
-var (
-	errCategoryNameENIsRequired   = errors.New("category name EN is required")
-	errCategoryNameIDIsRequired   = errors.New("category name ID is required")
-	errAbbreviationIsRequired     = errors.New("abbreviation is required")
-	errCategoryImageURLIsRequired = errors.New("category image url is required")
+var errCategoryDuplicateAbbreviation = errors.New("abbreviation shown is the same on two different categories")
 
-	errSubcategoryNameENIsRequired   = errors.New("subcategory name EN is required")
-	errSubcategoryNameIDIsRequired   = errors.New("subcategory name ID is required")
-	errSubcategoryImageURLIsRequired = errors.New("subcategory image url is required")
-)
+// categoryColumns maps level 1 category validation fields to the categories file columns.
+var categoryColumns = map[string]string{
+	"name_en":      "category_name_EN",
+	"name_id":      "category_name_ID",
+	"abbreviation": "abbreviation",
+	"images_urls":  "category_image",
+}
 
-// importCategories looks into the path given and bulk insert all the categories.
-func importCategories(ctx context.Context, db *mongo.Database, path string) error {
-	categoriesFile, err := os.Open(filepath.Clean(path))
-	if err != nil {
-		return fmt.Errorf("failed to open categories file: %w", err)
-	}
-	defer func() {
-		err := categoriesFile.Close()
-		if err != nil {
-			log.Fatalf("failed to close categories file: %v", err)
-		}
-	}()
+// categoryFileColumns are the columns of the categories file.
+var categoryFileColumns = fileColumns{
+	required: []string{
+		"category_name_EN",
+		"category_name_ID",
+		"abbreviation",
+		"subcategory_name_EN",
+		"subcategory_name_ID",
+	},
+}
 
-	// reads the category file
-	categoriesFileLines, err := csv.NewReader(categoriesFile).ReadAll()
-	if err != nil {
-		return fmt.Errorf("failed to read categories file lines: %w", err)
-	}
+// subcategoryColumns maps level 2 category validation fields to the categories file columns.
+var subcategoryColumns = map[string]string{
+	"name_en":     "subcategory_name_EN",
+	"name_id":     "subcategory_name_ID",
+	"images_urls": "subcategory_image",
+}
 
+// importCategories looks into the path given and bulk insert all the categories,
+// on upsert mode categories are matched with the existing categories instead.
+func importCategories(ctx context.Context, db *mongo.Database, path string, opts importOptions) error {
 	var (
 		category_name_EN    int
 		category_name_ID    int
@@ -59,41 +62,47 @@ func importCategories(ctx context.Context, db *mongo.Database, path string) erro
 	)
 
 	var categories []*category.Category
-	for i, line := range categoriesFileLines {
-		rowNumber := i + 1
+	// categoryIndexes indexes level 1 categories by their abbreviation.
+	categoryIndexes := map[string]int{}
+	errReport := newErrorReport(categoryColumns)
+	// reads the category file row by row, on the format of the file.
+	rows, err := openRowReader(path, opts)
+	if err != nil {
+		return fmt.Errorf("failed to read categories file: %w", err)
+	}
+	defer rows.close()
+	for {
+		line, rowNumber, err := rows.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read categories file lines: %w", err)
+		}
 		if rowNumber == 1 {
-			// get all the index for the headers
-			for idx, header := range line {
-				switch header {
-				case "category_name_EN":
-					category_name_EN = idx
-				case "category_name_ID":
-					category_name_ID = idx
-				case "abbreviation":
-					abbreviation = idx
-				case "subcategory_name_EN":
-					subcategory_name_EN = idx
-				case "subcategory_name_ID":
-					subcategory_name_ID = idx
-				}
+			header, err := readHeader(line, categoryFileColumns, opts.headerAliases)
+			if err != nil {
+				return fmt.Errorf("failed to read categories file header: %w", err)
 			}
+			errReport.setHeader(header.names)
+
+			// get all the index for the headers
+			category_name_EN = header.column("category_name_EN")
+			category_name_ID = header.column("category_name_ID")
+			abbreviation = header.column("abbreviation")
+			subcategory_name_EN = header.column("subcategory_name_EN")
+			subcategory_name_ID = header.column("subcategory_name_ID")
 			continue
 		}
 
+		// every invalid column of the row is recorded, the row is skipped when any is invalid.
+		rowErrors := errReport.len()
+
 		// look for c1 category in categories
-		var categoriesIdx int
-		found := false
-		for i, c := range categories {
-			if c.Abbreviation == line[abbreviation] {
-				// extra validation to check category name, when abbreviation is the same
-				if c.Name_EN != line[category_name_EN] {
-					return fmt.Errorf("abbreviation shown is the same on two different categories, on row: %d", rowNumber)
-				}
-
-				categoriesIdx = i
-				found = true
-				break
-			}
+		categoriesIdx, found := categoryIndexes[line[abbreviation]]
+		// extra validation to check category name, when abbreviation is the same
+		if found && categories[categoriesIdx].Name_EN != line[category_name_EN] {
+			errReport.addColumn(rowNumber, line, "abbreviation", errCategoryDuplicateAbbreviation)
 		}
 
 		categoryl2ID := primitive.NewObjectID()
@@ -106,11 +115,14 @@ func importCategories(ctx context.Context, db *mongo.Database, path string) erro
 			ImagesURLs: []string{categoryl2ImageURL},
 		}
 		// check categoryl2 parameter values
-		if err := validateC2(&categoryl2); err != nil {
-			return fmt.Errorf("error on row: %d, error: %w", rowNumber, err)
+		if err := validation.ValidateChildCategory(&categoryl2); err != nil {
+			errReport.addFields(rowNumber, line, subcategoryColumns, err)
 		}
 
 		if found {
+			if errReport.len() > rowErrors {
+				continue
+			}
 			// append c2 categories to c1.
 			categories[categoriesIdx].ChildCategories =
 				append(categories[categoriesIdx].ChildCategories, categoryl2)
@@ -127,57 +139,146 @@ func importCategories(ctx context.Context, db *mongo.Database, path string) erro
 				ChildCategories: []category.Category{categoryl2},
 			}
 			// check categoryl1 parameter values
-			if err := validateC1(categoryl1); err != nil {
-				return fmt.Errorf("error on row: %d, error: %w", rowNumber, err)
+			if err := validation.ValidateParentCategory(categoryl1); err != nil {
+				errReport.add(rowNumber, line, err)
+			}
+			if errReport.len() > rowErrors {
+				continue
 			}
+			categoryIndexes[categoryl1.Abbreviation] = len(categories)
 			categories = append(categories, categoryl1)
 		}
 	}
 
+	// fails the import when any row is invalid, so nothing is partially imported.
+	if err := errReport.check(opts.errorsReportPath); err != nil {
+		return err
+	}
+
 	collection := db.Collection(categoryCollection)
+	report := newImportReport(categoryCollection)
 
-	// converts all categories to mongo write model for bulk insert.
+	// converts all categories to mongo write model for bulk write.
 	models := []mongo.WriteModel{}
-	for _, c := range categories {
-		writeModel := mongo.NewInsertOneModel().SetDocument(c)
-		models = append(models, writeModel)
+	switch opts.mode {
+	case modeUpsert:
+		existingCategories, err := findCategories(ctx, db)
+		if err != nil {
+			return err
+		}
+		models = categoryUpsertModels(existingCategories, categories, report)
+	default:
+		for _, c := range categories {
+			writeModel := mongo.NewInsertOneModel().SetDocument(c)
+			models = append(models, writeModel)
+			reportCategory(report, changeCreated, c)
+		}
 	}
 
-	// bulk insert categories.
-	if _, err = collection.BulkWrite(ctx, models); err != nil {
-		return fmt.Errorf("failed to execute BulkWrite, on import categories: %w", err)
+	// bulk write categories, nothing is written on dry run or when all categories are unchanged.
+	if !opts.dryRun && len(models) > 0 {
+		if _, err := bulkWrite(ctx, collection, models, opts); err != nil {
+			return fmt.Errorf("failed to execute BulkWrite, on import categories: %w", err)
+		}
 	}
 
-	log.Print("successfully imported categories!")
+	if err := report.finish(opts); err != nil {
+		return err
+	}
+	if !opts.dryRun {
+		log.Print("successfully imported categories!")
+	}
 	return nil
 }
 
-// validateC1 checks whether all required parameters are fulfilled
-func validateC1(ct *category.Category) error {
-	switch "" {
-	case ct.Name_EN:
-		return errCategoryNameENIsRequired
-	case ct.Name_ID:
-		return errCategoryNameIDIsRequired
-	case ct.Abbreviation:
-		return errAbbreviationIsRequired
-	// for now checking the 0 index should suffice
-	case ct.ImagesURLs[0]:
-		return errCategoryImageURLIsRequired
+// categoryUpsertModels matches categories with existingCategories by their abbreviation
+// and their child categories by EN name, keeping the existing ids and images.
+// Changed names are updated and new child categories are added, existing child
+// categories missing from categories are kept as they are.
+func categoryUpsertModels(existingCategories, categories []*category.Category, report *importReport) []mongo.WriteModel {
+	existingByAbbreviation := make(map[string]*category.Category, len(existingCategories))
+	for _, c := range existingCategories {
+		existingByAbbreviation[c.Abbreviation] = c
 	}
-	return nil
+
+	models := []mongo.WriteModel{}
+	for _, c := range categories {
+		existing, ok := existingByAbbreviation[c.Abbreviation]
+		if !ok {
+			models = append(models, mongo.NewInsertOneModel().SetDocument(c))
+			reportCategory(report, changeCreated, c)
+			continue
+		}
+
+		if !mergeCategory(existing, c, report) {
+			continue
+		}
+		writeModel := mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": existing.ID}).
+			SetUpdate(bson.M{
+				"$set": bson.M{
+					"name_en":          existing.Name_EN,
+					"name_id":          existing.Name_ID,
+					"child_categories": existing.ChildCategories,
+				},
+			})
+		models = append(models, writeModel)
+	}
+	return models
+}
+
+// mergeCategory applies the names and child categories of c to existing,
+// it returns whether existing has been changed.
+func mergeCategory(existing, c *category.Category, report *importReport) bool {
+	fields := changedFields(existing, c, "name_en", "name_id")
+	changed := len(fields) > 0
+	existing.Name_EN = c.Name_EN
+	existing.Name_ID = c.Name_ID
+	report.addDiff(categoryKey(c, nil), fields)
+
+	for _, child := range c.ChildCategories {
+		child := child
+		idx := -1
+		for i, existingChild := range existing.ChildCategories {
+			if strings.EqualFold(existingChild.Name_EN, child.Name_EN) {
+				idx = i
+				break
+			}
+		}
+
+		if idx < 0 {
+			existing.ChildCategories = append(existing.ChildCategories, child)
+			changed = true
+			report.add(changeCreated, categoryKey(c, &child))
+			continue
+		}
+
+		existingChild := &existing.ChildCategories[idx]
+		childFields := changedFields(existingChild, &child, "name_en", "name_id")
+		if len(childFields) > 0 {
+			changed = true
+		}
+		existingChild.Name_EN = child.Name_EN
+		existingChild.Name_ID = child.Name_ID
+		report.addDiff(categoryKey(c, &child), childFields)
+	}
+	return changed
 }
 
-// validateC2 checks whether all required parameters are fulfilled
-func validateC2(ct *category.Category) error {
-	switch "" {
-	case ct.Name_EN:
-		return errSubcategoryNameENIsRequired
-	case ct.Name_ID:
-		return errSubcategoryNameIDIsRequired
-	// for now checking the 0 index should suffice
-	case ct.ImagesURLs[0]:
-		return errSubcategoryImageURLIsRequired
+// reportCategory adds the same change for a level 1 category and all its child categories.
+func reportCategory(report *importReport, kind changeKind, c *category.Category) {
+	report.add(kind, categoryKey(c, nil))
+	for _, child := range c.ChildCategories {
+		child := child
+		report.add(kind, categoryKey(c, &child))
 	}
-	return nil
+}
+
+// categoryKey identifies a category on import reports by the level 1 category abbreviation,
+// followed by the child category EN name for level 2 categories.
+func categoryKey(c *category.Category, child *category.Category) string {
+	if child == nil {
+		return c.Abbreviation
+	}
+	return c.Abbreviation + " > " + child.Name_EN
 }
@@ -2,8 +2,17 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/category"
+
+	"github.com/dropezy/storefront-backend/ems-api/validation"
 )
 
 func TestImportCategories(t *testing.T) {
@@ -19,7 +28,7 @@ func TestImportCategories(t *testing.T) {
 		t.Parallel()
 
 		path := "testdata/category/success.csv"
-		if err := importCategories(ctx, testDb, path); err != nil {
+		if err := importCategories(ctx, testDb, path, importOptions{mode: modeInsert}); err != nil {
 			t.Fatalf("expected nil error, got = %v", err)
 		}
 	})
@@ -33,37 +42,37 @@ func TestImportCategories(t *testing.T) {
 		{
 			name:    "EmptyCategoryNameEN",
 			path:    "testdata/category/empty_category_name_en.csv",
-			wantErr: errCategoryNameENIsRequired,
+			wantErr: validation.ErrCategoryNameENIsRequired,
 		},
 		{
 			name:    "EmptyCategoryNameID",
 			path:    "testdata/category/empty_category_name_id.csv",
-			wantErr: errCategoryNameIDIsRequired,
+			wantErr: validation.ErrCategoryNameIDIsRequired,
 		},
 		{
 			name:    "EmptyAbbreviation",
 			path:    "testdata/category/empty_abbreviation.csv",
-			wantErr: errAbbreviationIsRequired,
+			wantErr: validation.ErrAbbreviationIsRequired,
 		},
 		{
 			name:    "EmptyCategoryImageURL",
 			path:    "testdata/category/empty_category_image_url.csv",
-			wantErr: errCategoryImageURLIsRequired,
+			wantErr: validation.ErrCategoryImageURLIsRequired,
 		},
 		{
 			name:    "EmptySubcategoryNameEN",
 			path:    "testdata/category/empty_subcategory_name_en.csv",
-			wantErr: errSubcategoryNameENIsRequired,
+			wantErr: validation.ErrSubcategoryNameENIsRequired,
 		},
 		{
 			name:    "EmptySubcategoryNameID",
 			path:    "testdata/category/empty_subcategory_name_id.csv",
-			wantErr: errSubcategoryNameIDIsRequired,
+			wantErr: validation.ErrSubcategoryNameIDIsRequired,
 		},
 		{
 			name:    "EmptySubcategoryImageURL",
 			path:    "testdata/category/empty_subcategory_image_url.csv",
-			wantErr: errSubcategoryImageURLIsRequired,
+			wantErr: validation.ErrSubcategoryImageURLIsRequired,
 		},
 	}
 
@@ -72,10 +81,112 @@ func TestImportCategories(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 
-			err := importCategories(ctx, testDb, test.path)
+			err := importCategories(ctx, testDb, test.path, importOptions{mode: modeInsert})
 			if !errors.Is(errors.Unwrap(err), test.wantErr) {
 				t.Fatalf("importCategories(_, _) error, got = %v, want = %v", err, test.wantErr)
 			}
 		})
 	}
 }
+
+func TestImportCategoriesUpsert(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		defaultTestTimeout,
+	)
+	t.Cleanup(cancel)
+
+	opts := importOptions{mode: modeUpsert}
+	getCategory := func() *category.Category {
+		t.Helper()
+
+		var categories []*category.Category
+		cur, err := testDb.Collection(categoryCollection).Find(ctx, bson.M{"abbreviation": "FNV"})
+		if err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if err := cur.All(ctx, &categories); err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if len(categories) != 1 {
+			t.Fatalf("expected 1 category, got = %d", len(categories))
+		}
+		return categories[0]
+	}
+
+	if err := importCategories(ctx, testDb, "testdata/category/upsert.csv", opts); err != nil {
+		t.Fatalf("expected nil error, got = %v", err)
+	}
+	// importing the same file again must not duplicate categories.
+	if err := importCategories(ctx, testDb, "testdata/category/upsert.csv", opts); err != nil {
+		t.Fatalf("expected nil error, got = %v", err)
+	}
+	before := getCategory()
+
+	if err := importCategories(ctx, testDb, "testdata/category/upsert_update.csv", opts); err != nil {
+		t.Fatalf("expected nil error, got = %v", err)
+	}
+	after := getCategory()
+
+	if after.ID != before.ID {
+		t.Fatalf("expected category id to be kept, got = %s, want = %s", after.ID.Hex(), before.ID.Hex())
+	}
+	if after.Name_ID != "Buah & Sayur" {
+		t.Fatalf("expected category name ID to be updated, got = %s", after.Name_ID)
+	}
+	if len(after.ChildCategories) != 3 {
+		t.Fatalf("expected 3 child categories, got = %d", len(after.ChildCategories))
+	}
+	for i, c := range before.ChildCategories {
+		if after.ChildCategories[i].ID != c.ID {
+			t.Fatalf("expected child category id to be kept, got = %s, want = %s", after.ChildCategories[i].ID.Hex(), c.ID.Hex())
+		}
+	}
+	if after.ChildCategories[1].Name_ID != "Sayuran" {
+		t.Fatalf("expected child category name ID to be updated, got = %s", after.ChildCategories[1].Name_ID)
+	}
+}
+
+func TestImportCategoriesDryRun(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		defaultTestTimeout,
+	)
+	t.Cleanup(cancel)
+
+	opts := importOptions{
+		mode:       modeUpsert,
+		dryRun:     true,
+		reportPath: filepath.Join(t.TempDir(), "report.json"),
+	}
+	if err := importCategories(ctx, testDb, "testdata/category/dry_run.csv", opts); err != nil {
+		t.Fatalf("expected nil error, got = %v", err)
+	}
+
+	n, err := testDb.Collection(categoryCollection).CountDocuments(ctx, bson.M{"abbreviation": "FRZ"})
+	if err != nil {
+		t.Fatalf("unexpected error, got = %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("expected no categories to be written on dry run, got = %d", n)
+	}
+
+	b, err := os.ReadFile(opts.reportPath)
+	if err != nil {
+		t.Fatalf("unexpected error, got = %v", err)
+	}
+	var report struct {
+		DryRun  bool `json:"dry_run"`
+		Created int  `json:"created"`
+	}
+	if err := json.Unmarshal(b, &report); err != nil {
+		t.Fatalf("unexpected error, got = %v", err)
+	}
+	if !report.DryRun || report.Created != 2 {
+		t.Fatalf("expected dry run report with 2 created categories, got = %s", b)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dropezy/storefront-backend/ems-api/validation"
+)
+
+// rowError is a single problem found on a row of an imported file.
+type rowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+
+	err error
+}
+
+// errorReport collects the errors of every row of an imported file,
+// so all of them can be fixed on the spreadsheet in one pass.
+type errorReport struct {
+	// header is the header row of the imported file.
+	header []string
+	// columns maps validation error fields to the file columns.
+	columns map[string]string
+	errors  []*rowError
+}
+
+func newErrorReport(columns map[string]string) *errorReport {
+	return &errorReport{columns: columns}
+}
+
+// setHeader sets the header row, used to get the value of an error column.
+func (r *errorReport) setHeader(header []string) {
+	r.header = header
+}
+
+// add records err found on row, the column of validation field errors is
+// looked up on the report columns.
+func (r *errorReport) add(row int, line []string, err error) {
+	r.addFields(row, line, r.columns, err)
+}
+
+// addFields records err found on row, the column of validation field errors
+// is looked up on columns. Every field of a validation failing on several
+// fields is recorded on its own column.
+func (r *errorReport) addFields(row int, line []string, columns map[string]string, err error) {
+	var fes validation.FieldErrors
+	if !errors.As(err, &fes) {
+		r.addColumn(row, line, fieldColumn(err, columns), err)
+		return
+	}
+	for _, fe := range fes {
+		r.addColumn(row, line, fieldColumn(fe, columns), fe)
+	}
+}
+
+// addColumn records err found on the column of row, column may be empty
+// when the error is not caused by a single column. Only the first error
+// of a column is recorded, e.g. a missing brand is not reported twice by
+// the brand lookup and the product validation.
+func (r *errorReport) addColumn(row int, line []string, column string, err error) {
+	// rows are read in order, so the errors of row are the last ones.
+	for i := len(r.errors) - 1; column != "" && i >= 0 && r.errors[i].Row == row; i-- {
+		if r.errors[i].Column == column {
+			return
+		}
+	}
+
+	e := &rowError{
+		Row:     row,
+		Column:  column,
+		Code:    errorCode(err),
+		Message: err.Error(),
+		err:     err,
+	}
+	for idx, header := range r.header {
+		if header == column && idx < len(line) {
+			e.Value = line[idx]
+			break
+		}
+	}
+	r.errors = append(r.errors, e)
+}
+
+// len returns the number of recorded errors, used to check whether
+// a row added any error.
+func (r *errorReport) len() int {
+	return len(r.errors)
+}
+
+// check logs all the row errors and writes them to path when it is not empty,
+// the file is written as JSON when path has .json extension or CSV otherwise.
+// It returns an error wrapping the first row error when any error was found.
+func (r *errorReport) check(path string) error {
+	if len(r.errors) < 1 {
+		return nil
+	}
+
+	for _, e := range r.errors {
+		log.Printf("error on row: %d, column: %s, value: %q, code: %s, error: %s",
+			e.Row, e.Column, e.Value, e.Code, e.Message)
+	}
+	if path != "" {
+		if err := r.write(path); err != nil {
+			return err
+		}
+	}
+
+	first := r.errors[0]
+	return fmt.Errorf("found %d errors, first error on row: %d, error: %w", len(r.errors), first.Row, first.err)
+}
+
+func (r *errorReport) write(path string) error {
+	f, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("failed to create error report file: %w", err)
+	}
+	defer func() {
+		err := f.Close()
+		if err != nil {
+			log.Fatalf("failed to close error report file: %v", err)
+		}
+	}()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r.errors); err != nil {
+			return fmt.Errorf("failed to write error report: %w", err)
+		}
+		return nil
+	}
+
+	w := csv.NewWriter(f)
+	records := [][]string{{"row", "column", "value", "code", "message"}}
+	for _, e := range r.errors {
+		records = append(records, []string{strconv.Itoa(e.Row), e.Column, e.Value, e.Code, e.Message})
+	}
+	if err := w.WriteAll(records); err != nil {
+		return fmt.Errorf("failed to write error report: %w", err)
+	}
+	return nil
+}
+
+// fieldColumn returns the file column of a validation field error, indexed fields
+// such as opening_hours[0] are looked up without their index.
+func fieldColumn(err error, columns map[string]string) string {
+	var fe *validation.FieldError
+	if !errors.As(err, &fe) {
+		return ""
+	}
+	field, _, _ := strings.Cut(fe.Field, "[")
+	return columns[field]
+}
+
+// errorCode converts the innermost wrapped error message to an upper snake case code,
+// e.g. "sku is required" to SKU_IS_REQUIRED.
+func errorCode(err error) string {
+	for unwrapped := errors.Unwrap(err); unwrapped != nil; unwrapped = errors.Unwrap(err) {
+		err = unwrapped
+	}
+	code := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, err.Error())
+	for strings.Contains(code, "__") {
+		code = strings.ReplaceAll(code, "__", "_")
+	}
+	return strings.Trim(code, "_")
+}
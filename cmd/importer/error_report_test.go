@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dropezy/storefront-backend/ems-api/validation"
+)
+
+func TestErrorReport(t *testing.T) {
+	t.Parallel()
+
+	r := newErrorReport(productColumns)
+	r.setHeader([]string{"sku_structured", "barcodes"})
+	r.add(2, []string{"", "111199"}, &validation.FieldError{Field: "sku", Err: validation.ErrSKUIsRequired})
+	r.addColumn(3, []string{"DNE0001", "abc"}, "barcodes", errBrandNotFound)
+	r.add(4, []string{"", ""}, validation.FieldErrors{
+		{Field: "sku", Err: validation.ErrSKUIsRequired},
+		{Field: "barcode", Err: validation.ErrBarcodeIsRequired},
+	})
+	// a column is only reported once per row.
+	r.addColumn(4, []string{"", ""}, "barcodes", errBrandNotFound)
+
+	path := filepath.Join(t.TempDir(), "errors.csv")
+	err := r.check(path)
+	if !errors.Is(errors.Unwrap(err), validation.ErrSKUIsRequired) {
+		t.Fatalf("check(_) error, got = %v, want = %v", err, validation.ErrSKUIsRequired)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error, got = %v", err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("unexpected error, got = %v", err)
+	}
+
+	want := [][]string{
+		{"row", "column", "value", "code", "message"},
+		{"2", "sku_structured", "", "SKU_IS_REQUIRED", "sku is required"},
+		{"3", "barcodes", "abc", "BRAND_NOT_FOUND", "brand not found"},
+		{"4", "sku_structured", "", "SKU_IS_REQUIRED", "sku is required"},
+		{"4", "barcodes", "", "BARCODE_IS_REQUIRED", "barcode is required"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("expected %d records, got = %v", len(want), records)
+	}
+	for i := range want {
+		for j := range want[i] {
+			if records[i][j] != want[i][j] {
+				t.Fatalf("record %d, got = %v, want = %v", i, records[i], want[i])
+			}
+		}
+	}
+}
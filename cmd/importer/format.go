@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// fileFormat is the format of an imported file.
+type fileFormat string
+
+const (
+	// formatAuto detects the format from the file extension.
+	formatAuto   fileFormat = ""
+	formatCSV    fileFormat = "csv"
+	formatXLSX   fileFormat = "xlsx"
+	formatJSON   fileFormat = "json"
+	formatNDJSON fileFormat = "ndjson"
+)
+
+var (
+	errUnsupportedFormat = errors.New("unsupported file format")
+	errSheetNotFound     = errors.New("sheet not found")
+	errJSONNotArray      = errors.New("json file must be an array of objects")
+	errJSONNestedObject  = errors.New("json nested objects are not supported")
+	errJSONUnknownKey    = errors.New("json key is not a key of the first object")
+)
+
+// parseFileFormat parses the format flag, an empty format detects it from the file extension.
+func parseFileFormat(s string) (fileFormat, error) {
+	switch f := fileFormat(strings.ToLower(s)); f {
+	case formatAuto, formatCSV, formatXLSX, formatJSON, formatNDJSON:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w: %s", errUnsupportedFormat, s)
+	}
+}
+
+// detectFileFormat detects the format of path from its extension, defaulting to CSV.
+func detectFileFormat(path string) fileFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xlsx":
+		return formatXLSX
+	case ".json":
+		return formatJSON
+	case ".ndjson", ".jsonl":
+		return formatNDJSON
+	default:
+		return formatCSV
+	}
+}
+
+// rowReader reads the rows of an imported file one by one, the first row is the header.
+type rowReader interface {
+	// next returns the next row along with its row number starting from 1 for the header,
+	// io.EOF is returned when there are no more rows.
+	next() ([]string, int, error)
+	// close releases the resources held by the reader and closes the file it reads.
+	close()
+}
+
+// openRowReader opens the file on path and returns its row reader,
+// the file is closed along with the reader.
+func openRowReader(path string, opts importOptions) (rowReader, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	rows, err := newRowReader(f, path, opts)
+	if err != nil {
+		closeFile(f)
+		return nil, err
+	}
+	return rows, nil
+}
+
+// closeFile closes r when it is a file or any other io.Closer, errors are
+// logged since the rows have already been read.
+func closeFile(r io.Reader) {
+	c, ok := r.(io.Closer)
+	if !ok {
+		return
+	}
+	if err := c.Close(); err != nil {
+		log.Printf("failed to close file: %v", err)
+	}
+}
+
+// newRowReader returns the row reader of the file r opened from path, on the format
+// given by the options or detected from the path extension.
+func newRowReader(r io.Reader, path string, opts importOptions) (rowReader, error) {
+	format := opts.format
+	if format == formatAuto {
+		format = detectFileFormat(path)
+	}
+
+	switch format {
+	case formatCSV:
+		return newCSVRows(r), nil
+	case formatXLSX:
+		return newXLSXRows(r, opts.sheet)
+	case formatJSON, formatNDJSON:
+		return newJSONRows(r, format)
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedFormat, format)
+	}
+}
+
+// xlsxRows reads the rows of a worksheet of an XLSX file.
+type xlsxRows struct {
+	file      io.Reader
+	f         *excelize.File
+	rows      *excelize.Rows
+	rowNumber int
+	// columns is the number of columns of the header, shorter rows are padded to it.
+	columns int
+}
+
+// newXLSXRows reads the rows of sheet, or the first sheet of the file when sheet is empty.
+func newXLSXRows(r io.Reader, sheet string) (*xlsxRows, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open xlsx file: %w", err)
+	}
+
+	sheets := f.GetSheetList()
+	if sheet == "" && len(sheets) > 0 {
+		sheet = sheets[0]
+	}
+	found := false
+	for _, s := range sheets {
+		if s == sheet {
+			found = true
+			break
+		}
+	}
+	if !found {
+		_ = f.Close()
+		return nil, fmt.Errorf("%w: %s", errSheetNotFound, sheet)
+	}
+
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to read xlsx sheet: %s, error: %w", sheet, err)
+	}
+	return &xlsxRows{file: r, f: f, rows: rows}, nil
+}
+
+func (r *xlsxRows) next() ([]string, int, error) {
+	for r.rows.Next() {
+		r.rowNumber++
+		line, err := r.rows.Columns()
+		if err != nil {
+			return nil, r.rowNumber, err
+		}
+		// empty rows are skipped, keeping the row numbers of the sheet.
+		if r.rowNumber > 1 && strings.Join(line, "") == "" {
+			continue
+		}
+
+		if r.rowNumber == 1 {
+			r.columns = len(line)
+		}
+		for len(line) < r.columns {
+			line = append(line, "")
+		}
+		if r.rowNumber%progressInterval == 0 {
+			log.Printf("read %d rows", r.rowNumber)
+		}
+		return line, r.rowNumber, nil
+	}
+	if err := r.rows.Error(); err != nil {
+		return nil, r.rowNumber, err
+	}
+	return nil, r.rowNumber, io.EOF
+}
+
+func (r *xlsxRows) close() {
+	if err := r.rows.Close(); err != nil {
+		log.Printf("failed to close xlsx rows: %v", err)
+	}
+	if err := r.f.Close(); err != nil {
+		log.Printf("failed to close xlsx file: %v", err)
+	}
+	closeFile(r.file)
+}
+
+// jsonRows reads the objects of a JSON array or of newline delimited JSON as rows,
+// the header is made of the sorted keys of the first object. Keys missing from an
+// object are read as empty values, objects with keys which are not keys of the
+// first object are rejected, since their values would be silently ignored.
+type jsonRows struct {
+	file   io.Reader
+	dec    *json.Decoder
+	header []string
+	// keys are the keys of the header.
+	keys map[string]bool
+	// first is the first object, read along with the header.
+	first     map[string]json.RawMessage
+	rowNumber int
+}
+
+func newJSONRows(r io.Reader, format fileFormat) (*jsonRows, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	dec.UseNumber()
+	if format == formatJSON {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read json file: %w", err)
+		}
+		if d, ok := tok.(json.Delim); !ok || d != '[' {
+			return nil, errJSONNotArray
+		}
+	}
+	return &jsonRows{file: r, dec: dec}, nil
+}
+
+func (r *jsonRows) next() ([]string, int, error) {
+	if r.header == nil {
+		obj, err := r.decode()
+		if err != nil {
+			return nil, r.rowNumber, err
+		}
+		r.keys = make(map[string]bool, len(obj))
+		for key := range obj {
+			r.header = append(r.header, key)
+			r.keys[key] = true
+		}
+		sort.Strings(r.header)
+		r.first = obj
+		r.rowNumber++
+		return r.header, r.rowNumber, nil
+	}
+
+	obj := r.first
+	r.first = nil
+	if obj == nil {
+		var err error
+		if obj, err = r.decode(); err != nil {
+			return nil, r.rowNumber, err
+		}
+	}
+	r.rowNumber++
+
+	var unknown []string
+	for key := range obj {
+		if !r.keys[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, r.rowNumber, fmt.Errorf("%w: %s, on row: %d", errJSONUnknownKey, strings.Join(unknown, ", "), r.rowNumber)
+	}
+
+	line := make([]string, len(r.header))
+	for idx, key := range r.header {
+		value, err := jsonCell(obj[key])
+		if err != nil {
+			return nil, r.rowNumber, fmt.Errorf("failed to read json key: %s, on row: %d, error: %w", key, r.rowNumber, err)
+		}
+		line[idx] = value
+	}
+	if r.rowNumber%progressInterval == 0 {
+		log.Printf("read %d rows", r.rowNumber)
+	}
+	return line, r.rowNumber, nil
+}
+
+// decode decodes the next object, returning io.EOF at the end of the array or the file.
+func (r *jsonRows) decode() (map[string]json.RawMessage, error) {
+	if !r.dec.More() {
+		return nil, io.EOF
+	}
+	var obj map[string]json.RawMessage
+	if err := r.dec.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("failed to decode json object on row: %d, error: %w", r.rowNumber+1, err)
+	}
+	return obj, nil
+}
+
+func (r *jsonRows) close() {
+	closeFile(r.file)
+}
+
+// jsonCell converts a JSON value to a cell value, booleans are converted to
+// yes or no and arrays are joined with ";" as on the CSV files.
+func jsonCell(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+
+	var v interface{}
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return "", err
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		if v {
+			return "yes", nil
+		}
+		return "no", nil
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			b, err := json.Marshal(item)
+			if err != nil {
+				return "", err
+			}
+			value, err := jsonCell(b)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, value)
+		}
+		return strings.Join(values, ";"), nil
+	default:
+		return "", errJSONNestedObject
+	}
+}
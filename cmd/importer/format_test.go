@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// readAllRows reads all the rows of r.
+func readAllRows(t *testing.T, r rowReader) [][]string {
+	t.Helper()
+
+	defer r.close()
+	var rows [][]string
+	for {
+		line, _, err := r.next()
+		if errors.Is(err, io.EOF) {
+			return rows
+		}
+		if err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		rows = append(rows, line)
+	}
+}
+
+func TestRowReader(t *testing.T) {
+	t.Parallel()
+
+	want := [][]string{
+		{"active", "location_code", "opening_hours", "stock"},
+		{"yes", "WHT", "mon-fri 07:00-22:00;sat-sun 08:00-21:00", "10"},
+		{"no", "KMG", "", "0"},
+	}
+
+	xlsx := excelize.NewFile()
+	for i, row := range [][]interface{}{
+		{"active", "location_code", "opening_hours", "stock"},
+		{"yes", "WHT", "mon-fri 07:00-22:00;sat-sun 08:00-21:00", 10},
+		{},
+		{"no", "KMG", "", 0},
+	} {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if err := xlsx.SetSheetRow("Sheet1", cell, &row); err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+	}
+	var xlsxData bytes.Buffer
+	if err := xlsx.Write(&xlsxData); err != nil {
+		t.Fatalf("unexpected error, got = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		data string
+		opts importOptions
+	}{
+		{
+			name: "CSV",
+			path: "stores.csv",
+			data: "active,location_code,opening_hours,stock\n" +
+				"yes,WHT,mon-fri 07:00-22:00;sat-sun 08:00-21:00,10\n" +
+				"no,KMG,,0\n",
+		},
+		{
+			name: "XLSX",
+			path: "stores.xlsx",
+			data: xlsxData.String(),
+			opts: importOptions{sheet: "Sheet1"},
+		},
+		{
+			name: "JSON",
+			path: "stores.json",
+			data: `[
+				{"location_code": "WHT", "active": true, "stock": 10, "opening_hours": ["mon-fri 07:00-22:00", "sat-sun 08:00-21:00"]},
+				{"location_code": "KMG", "active": false, "stock": 0, "opening_hours": null}
+			]`,
+		},
+		{
+			name: "NDJSON",
+			path: "stores",
+			data: `{"location_code": "WHT", "active": true, "stock": 10, "opening_hours": "mon-fri 07:00-22:00;sat-sun 08:00-21:00"}
+{"location_code": "KMG", "active": false, "stock": 0}
+`,
+			opts: importOptions{format: formatNDJSON},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := newRowReader(strings.NewReader(test.data), test.path, test.opts)
+			if err != nil {
+				t.Fatalf("unexpected error, got = %v", err)
+			}
+			rows := readAllRows(t, r)
+			if len(rows) != len(want) {
+				t.Fatalf("expected %d rows, got = %v", len(want), rows)
+			}
+			for i := range want {
+				if strings.Join(rows[i], ",") != strings.Join(want[i], ",") {
+					t.Fatalf("row %d, got = %v, want = %v", i+1, rows[i], want[i])
+				}
+			}
+		})
+	}
+
+	t.Run("JSONUnknownKey", func(t *testing.T) {
+		t.Parallel()
+
+		data := `{"location_code": "WHT", "active": true}
+{"location_code": "KMG", "active": false, "stock": 0}
+`
+		r, err := newRowReader(strings.NewReader(data), "stores", importOptions{format: formatNDJSON})
+		if err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if _, _, err := r.next(); err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if _, _, err := r.next(); err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if _, _, err := r.next(); !errors.Is(err, errJSONUnknownKey) {
+			t.Fatalf("next() error, got = %v, want = %v", err, errJSONUnknownKey)
+		}
+	})
+
+	t.Run("SheetNotFound", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := newRowReader(bytes.NewReader(xlsxData.Bytes()), "stores.xlsx", importOptions{sheet: "Stores"})
+		if !errors.Is(err, errSheetNotFound) {
+			t.Fatalf("newRowReader(_, _, _) error, got = %v, want = %v", err, errSheetNotFound)
+		}
+	})
+
+	t.Run("CloseFile", func(t *testing.T) {
+		t.Parallel()
+
+		r, err := openRowReader("testdata/brand/success.csv", importOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if _, _, err := r.next(); err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		r.close()
+
+		f, ok := r.(*csvRows).file.(*os.File)
+		if !ok {
+			t.Fatalf("expected csv rows to read a file, got = %T", r.(*csvRows).file)
+		}
+		if err := f.Close(); !errors.Is(err, os.ErrClosed) {
+			t.Fatalf("expected file to be closed along with the rows, got = %v", err)
+		}
+	})
+}
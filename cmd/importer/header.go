@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var errMissingHeaders = errors.New("missing required headers")
+
+// headerAliases maps alternative header names, e.g. of sheets exported from
+// different tools, to the header names known by the importers.
+type headerAliases map[string]string
+
+// defaultHeaderAliases are the aliases always accepted by the importers,
+// unless they are overridden.
+var defaultHeaderAliases = headerAliases{
+	"ERP SKU ID":             "product_variant_id",
+	"product_name_EN":        "product_name_ENG",
+	"product_name_ID":        "product_name_IND",
+	"quantifier_EN":          "quantifier_ENG",
+	"quantifier_ID":          "quantifier_IND",
+	"product_description_EN": "product_description_ENG",
+	"product_description_ID": "product_description_IND",
+	"Image":                  "image_link",
+}
+
+// readHeaderAliases reads a JSON object of alias to header names from path.
+func readHeaderAliases(path string) (headerAliases, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header aliases file: %w", err)
+	}
+	aliases := headerAliases{}
+	if err := json.Unmarshal(data, &aliases); err != nil {
+		return nil, fmt.Errorf("failed to decode header aliases file: %w", err)
+	}
+	return aliases, nil
+}
+
+// fileColumns are the required and optional columns of an imported file.
+type fileColumns struct {
+	required []string
+	optional []string
+}
+
+// fileHeader is the header row of an imported file with its aliases resolved.
+type fileHeader struct {
+	// names are the header names known by the importer, or the
+	// original names for unknown headers.
+	names []string
+	index map[string]int
+}
+
+// readHeader resolves the aliases of line, falling back to the default aliases,
+// and indexes its known columns, it fails listing
+// all the missing required columns and logs the unknown ones, which are ignored.
+func readHeader(line []string, columns fileColumns, aliases headerAliases) (*fileHeader, error) {
+	known := map[string]bool{}
+	for _, name := range columns.required {
+		known[name] = true
+	}
+	for _, name := range columns.optional {
+		known[name] = true
+	}
+
+	h := &fileHeader{
+		names: make([]string, len(line)),
+		index: map[string]int{},
+	}
+	for idx, name := range line {
+		name = strings.TrimSpace(name)
+		if !known[name] {
+			if alias, ok := aliases[name]; ok && known[alias] {
+				name = alias
+			} else if known[defaultHeaderAliases[name]] {
+				name = defaultHeaderAliases[name]
+			}
+		}
+		h.names[idx] = name
+		if !known[name] {
+			log.Printf("unknown header: %q on column: %d, is ignored", line[idx], idx+1)
+			continue
+		}
+		if _, ok := h.index[name]; !ok {
+			h.index[name] = idx
+		}
+	}
+
+	var missing []string
+	for _, name := range columns.required {
+		if _, ok := h.index[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", errMissingHeaders, strings.Join(missing, ", "))
+	}
+	return h, nil
+}
+
+// column returns the index of column name, or -1 when the file doesn't have the column.
+func (h *fileHeader) column(name string) int {
+	if idx, ok := h.index[name]; ok {
+		return idx
+	}
+	return -1
+}
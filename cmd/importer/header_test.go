@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReadHeader(t *testing.T) {
+	t.Parallel()
+
+	columns := fileColumns{
+		required: []string{"product_name_ENG", "sku_structured"},
+		optional: []string{"brand_name"},
+	}
+
+	t.Run("Aliases", func(t *testing.T) {
+		t.Parallel()
+
+		line := []string{"SKU", "product_name_EN", "unknown"}
+		header, err := readHeader(line, columns, headerAliases{"SKU": "sku_structured"})
+		if err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+
+		want := map[string]int{"sku_structured": 0, "product_name_ENG": 1, "brand_name": -1}
+		for name, idx := range want {
+			if got := header.column(name); got != idx {
+				t.Fatalf("column(%q), got = %d, want = %d", name, got, idx)
+			}
+		}
+		if header.names[2] != "unknown" {
+			t.Fatalf("unknown header name, got = %q, want = %q", header.names[2], "unknown")
+		}
+	})
+
+	t.Run("DefaultAliases", func(t *testing.T) {
+		t.Parallel()
+
+		columns := fileColumns{required: []string{"product_variant_id", "image_link"}}
+		for _, line := range [][]string{
+			{"ERP SKU ID", "Image"},
+			{"ERP SKU ID", "Image", "product_variant_id"},
+		} {
+			header, err := readHeader(line, columns, nil)
+			if err != nil {
+				t.Fatalf("unexpected error, got = %v", err)
+			}
+			if got := header.column("product_variant_id"); got != 0 {
+				t.Fatalf("column(%q) of %v, got = %d, want = 0", "product_variant_id", line, got)
+			}
+		}
+	})
+
+	t.Run("MissingHeaders", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := readHeader([]string{"brand_name"}, columns, nil)
+		if !errors.Is(err, errMissingHeaders) {
+			t.Fatalf("readHeader(_, _, _) error, got = %v, want = %v", err, errMissingHeaders)
+		}
+		want := "missing required headers: product_name_ENG, sku_structured"
+		if err.Error() != want {
+			t.Fatalf("readHeader(_, _, _) error, got = %q, want = %q", err, want)
+		}
+	})
+}
@@ -2,22 +2,26 @@ package main
 
 import (
 	"context"
-	"encoding/csv"
 	"errors"
 	"fmt"
+	"io"
 	"log"
-	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/dropezy/storefront-backend/internal/storage/model"
+	"github.com/dropezy/storefront-backend/internal/storage/model/darkstore"
 	"github.com/dropezy/storefront-backend/internal/storage/model/inventory"
 	"github.com/dropezy/storefront-backend/internal/storage/model/product"
 
+	"github.com/dropezy/storefront-backend/ems-api/money"
+	inventorysvc "github.com/dropezy/storefront-backend/ems-api/services/inventory"
+
 	// protobuf
 	mpb "github.com/dropezy/proto/meta"
 	prpb "github.com/dropezy/proto/v1/product"
@@ -36,138 +40,373 @@ var (
 	errInventoryProductProductIDIsRequired         = errors.New("inventory product product id is required")
 	errInventoryProductVariantIDIsRequired         = errors.New("inventory product variant id is required")
 	errInventoryProductShoptreeVariantIDIsRequired = errors.New("inventory product shoptree variant id is required")
+
+	errInventoryInvalidStock     = errors.New("inventory stock must be zero or a positive number")
+	errInventoryDuplicateVariant = errors.New("inventory variant is shown more than once for the same store")
+
+	errInventoryModified = errors.New("inventory items have been modified during the import, run the import again")
+
+	errStoreLocationNotFound  = errors.New("store not found")
+	errProductVariantNotFound = errors.New("product variant not found")
 )
 
-// importInventories opens products file to get price and bulk inserts inventory.
-// NOTE: Currently this is just a dummy importer
-func importInventories(ctx context.Context, db *mongo.Database, path string) error {
+// inventoryFileColumns are the columns of the inventories file.
+var inventoryFileColumns = fileColumns{
+	required: []string{
+		"location_code",
+		"shoptree_variant_id",
+		"sku",
+		"stock",
+		"price",
+		"sellable",
+	},
+	optional: []string{
+		"currency",
+	},
+}
+
+// inventoryDocument is a store inventory as stored in our database.
+type inventoryDocument struct {
+	ID                 primitive.ObjectID   `bson:"_id"`
+	StoreID            primitive.ObjectID   `bson:"store_id"`
+	ShoptreeLocationID string               `bson:"shoptree_location_id"`
+	Products           []*inventorysvc.Item `bson:"products"`
+}
+
+// variantRef is a product variant along with the product owning it.
+type variantRef struct {
+	product *product.Product
+	variant *product.ProductVariant
+}
+
+// importInventories looks into the path given and upserts the stock, price and status
+// of the variants of each store inventory. Inventories are matched by their store and
+// items by their variant, so existing ids are kept and items missing from the file
+// are left untouched.
+func importInventories(ctx context.Context, db *mongo.Database, path string, opts importOptions) error {
 	// check if stores exist in our database.
 	darkstores, err := getStores(ctx, db)
 	if err != nil {
 		return err
 	}
-	_ = darkstores
+	storesByLocationCode := make(map[string]*darkstore.Store, len(darkstores))
+	for _, ds := range darkstores {
+		storesByLocationCode[ds.LocationCode] = ds
+	}
 
 	// check if products exist in our database.
 	products, err := getProducts(ctx, db)
 	if err != nil {
 		return err
 	}
-	_ = products
-
-	// open products file to get price (currently price is stored in products file)
-	// TODO(wilson): change this to inventories file
-	productsFile, err := os.Open(filepath.Clean(path))
-	if err != nil {
-		return fmt.Errorf("failed to open products file: %w", err)
-	}
-	defer func() {
-		err := productsFile.Close()
-		if err != nil {
-			log.Fatalf("failed to close categories file: %v", err)
+	variantsByShoptreeID := map[string]variantRef{}
+	variantsBySKU := map[string]variantRef{}
+	for _, p := range products {
+		for _, pv := range p.Variants {
+			ref := variantRef{product: p, variant: pv}
+			if pv.ShoptreeVariantID != "" {
+				variantsByShoptreeID[pv.ShoptreeVariantID] = ref
+			}
+			variantsBySKU[pv.SKU] = ref
 		}
-	}()
-
-	// reads the products file
-	// TODO(wilson): change this to inventories file
-	productsFileLines, err := csv.NewReader(productsFile).ReadAll()
-	if err != nil {
-		return fmt.Errorf("failed to read products file lines: %w", err)
 	}
 
 	var (
-		product_name_EN          int
-		product_variant_id       int
-		variant_product_sellable int
-		price                    int
+		location_code       int
+		shoptree_variant_id int
+		sku                 int
+		stock               int
+		price               int
+		sellable            int
+		// currency column is optional, -1 means the column doesn't exist.
+		currency int
 	)
 
-	// TODO(wilson): update this to list of inventories.
-	inv := &inventory.Inventory{
-		ID:                 primitive.NewObjectID(),
-		StoreID:            darkstores[0].ID,
-		ShoptreeLocationID: darkstores[0].ShoptreeLocationID,
-		Products:           []*inventory.Product{},
+	// inventories are grouped by store, keeping the order of the file.
+	var storeIDs []primitive.ObjectID
+	inventories := map[primitive.ObjectID]*inventory.Inventory{}
+	seen := map[primitive.ObjectID]map[primitive.ObjectID]bool{}
+	errReport := newErrorReport(nil)
+	// reads the inventories file row by row, on the format of the file.
+	rows, err := openRowReader(path, opts)
+	if err != nil {
+		return fmt.Errorf("failed to read inventories file: %w", err)
 	}
-	for i, line := range productsFileLines {
-		rowNumber := i + 1
+	defer rows.close()
+	for {
+		line, rowNumber, err := rows.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read inventories file lines: %w", err)
+		}
 		if rowNumber == 1 {
-			// get required index for headers
-			for idx, header := range line {
-				switch header {
-				case "product_name_ENG":
-					product_name_EN = idx
-				case "product_variant_id":
-					product_variant_id = idx
-				case "variant_product_sellable":
-					variant_product_sellable = idx
-				case "selling_price":
-					price = idx
-				}
+			header, err := readHeader(line, inventoryFileColumns, opts.headerAliases)
+			if err != nil {
+				return fmt.Errorf("failed to read inventories file header: %w", err)
 			}
+			errReport.setHeader(header.names)
+
+			// get all the index for the headers
+			location_code = header.column("location_code")
+			shoptree_variant_id = header.column("shoptree_variant_id")
+			sku = header.column("sku")
+			stock = header.column("stock")
+			price = header.column("price")
+			sellable = header.column("sellable")
+			currency = header.column("currency")
 			continue
 		}
 
-		// look for product in products
-		p, err := getProduct(products, line[product_name_EN], rowNumber)
-		if err != nil {
-			return err
+		// every invalid column of the row is recorded, the row is skipped when any is invalid.
+		rowErrors := errReport.len()
+
+		// look for store
+		ds, ok := storesByLocationCode[line[location_code]]
+		if !ok {
+			errReport.addColumn(rowNumber, line, "location_code", errStoreLocationNotFound)
 		}
 
-		// look for product variant in product
-		pv, err := getProductVariant(p.Variants, line[product_variant_id], rowNumber)
-		if err != nil {
-			fmt.Printf("%+v PV\n", p.Variants[0].ShoptreeVariantID)
-			fmt.Println(line[product_variant_id])
-			return err
+		// look for product variant, variants without shoptree variant id are matched by sku.
+		ref, column := variantsByShoptreeID[line[shoptree_variant_id]], "shoptree_variant_id"
+		if line[shoptree_variant_id] == "" {
+			ref, column = variantsBySKU[line[sku]], "sku"
+		}
+		if ref.variant == nil {
+			errReport.addColumn(rowNumber, line, column, errProductVariantNotFound)
+		}
+
+		stockValue, err := strconv.ParseInt(strings.TrimSpace(line[stock]), 10, 32)
+		if err != nil || stockValue < 0 {
+			errReport.addColumn(rowNumber, line, "stock", errInventoryInvalidStock)
+		}
+
+		// prices are in IDR unless the row has a currency,
+		// the price is not parsed when the currency is invalid.
+		cur, validCurrency := mpb.Currency_CURRENCY_IDR, true
+		if currency >= 0 && strings.TrimSpace(line[currency]) != "" {
+			if cur, err = money.ParseCurrency(line[currency]); err != nil {
+				errReport.addColumn(rowNumber, line, "currency", err)
+				validCurrency = false
+			}
+		}
+		var amount *model.Amount
+		if validCurrency {
+			if amount, err = money.Parse(line[price], cur); err != nil {
+				errReport.addColumn(rowNumber, line, "price", err)
+			}
+		}
+		if errReport.len() > rowErrors {
+			continue
 		}
 
 		inventoryProduct := &inventory.Product{
-			ID:    primitive.NewObjectID(),
-			Stock: int32(10), // TODO(wilson): update this to use real stock data
-			Price: &model.Amount{
-				Num: line[price] + "00",
-				Cur: mpb.Currency_CURRENCY_IDR,
-			},
-			ProductID:         p.ID,
-			VariantID:         pv.ID,
-			ShoptreeVariantID: pv.ShoptreeVariantID,
-		}
-
-		if strings.Compare(line[variant_product_sellable], "yes") == 0 {
+			ID:                primitive.NewObjectID(),
+			Stock:             int32(stockValue),
+			Price:             amount,
+			ProductID:         ref.product.ID,
+			VariantID:         ref.variant.ID,
+			ShoptreeVariantID: ref.variant.ShoptreeVariantID,
+		}
+		if strings.EqualFold(line[sellable], "yes") {
 			inventoryProduct.Status = prpb.ProductStatus_PRODUCT_STATUS_ENABLED
 		} else {
 			inventoryProduct.Status = prpb.ProductStatus_PRODUCT_STATUS_DISABLED
 		}
-
 		if err := validateInventoryProduct(inventoryProduct); err != nil {
-			return err
+			errReport.add(rowNumber, line, err)
+			continue
+		}
+
+		inv, ok := inventories[ds.ID]
+		if !ok {
+			inv = &inventory.Inventory{
+				ID:                 primitive.NewObjectID(),
+				StoreID:            ds.ID,
+				ShoptreeLocationID: ds.ShoptreeLocationID,
+			}
+			inventories[ds.ID] = inv
+			seen[ds.ID] = map[primitive.ObjectID]bool{}
+			storeIDs = append(storeIDs, ds.ID)
+		}
+		if seen[ds.ID][inventoryProduct.VariantID] {
+			errReport.addColumn(rowNumber, line, column, errInventoryDuplicateVariant)
+			continue
 		}
+		seen[ds.ID][inventoryProduct.VariantID] = true
 
 		inv.Products = append(inv.Products, inventoryProduct)
 	}
 
-	if err := validateInventory(inv); err != nil {
+	// fails the import when any row is invalid, so nothing is partially imported.
+	if err := errReport.check(opts.errorsReportPath); err != nil {
+		return err
+	}
+
+	existingInventories, err := findInventories(ctx, db, storeIDs)
+	if err != nil {
 		return err
 	}
 
 	collection := db.Collection(inventoryCollection)
+	report := newImportReport(inventoryCollection)
+
+	// converts all store inventories to mongo write model for bulk write.
+	models := []mongo.WriteModel{}
+	// updateCount is the number of update models of existing inventories.
+	updateCount := 0
+	for _, storeID := range storeIDs {
+		inv := inventories[storeID]
+		if err := validateInventory(inv); err != nil {
+			return err
+		}
+
+		locationCode := storeLocationCode(darkstores, storeID)
+		existing, ok := existingInventories[storeID]
+		if !ok {
+			doc := &inventoryDocument{
+				ID:                 inv.ID,
+				StoreID:            inv.StoreID,
+				ShoptreeLocationID: inv.ShoptreeLocationID,
+			}
+			for _, p := range inv.Products {
+				doc.Products = append(doc.Products, &inventorysvc.Item{Product: *p})
+				report.add(changeCreated, inventoryKey(locationCode, p))
+			}
+			models = append(models, mongo.NewInsertOneModel().SetDocument(doc))
+			continue
+		}
+
+		updates := mergeInventory(existing, inv, locationCode, report)
+		models = append(models, updates...)
+		updateCount += len(updates)
+	}
 
-	// TODO(wilson): change this to bulk write
-	// insert inventory
-	if _, err := collection.InsertOne(ctx, inv); err != nil {
-		return fmt.Errorf("failed to execute InsertOne, on import inventories: %w", err)
+	// bulk write inventories, nothing is written on dry run or when all inventories are unchanged.
+	if !opts.dryRun && len(models) > 0 {
+		res, err := bulkWrite(ctx, collection, models, opts)
+		if err != nil {
+			return fmt.Errorf("failed to execute BulkWrite, on import inventories: %w", err)
+		}
+		// every update modifies its inventory unless the version of its item changed
+		// since it was read, the import fails so it is rolled back.
+		if res.ModifiedCount < int64(updateCount) {
+			return errInventoryModified
+		}
 	}
 
-	log.Print("successfully imported inventories!")
+	if err := report.finish(opts); err != nil {
+		return err
+	}
+	if !opts.dryRun {
+		log.Print("successfully imported inventories!")
+	}
 	return nil
 }
 
+// mergeInventory returns the models applying the stock, price and status of the inv
+// products to the existing inventory items matched by variant id. Like the inventory
+// service, every changed item is updated only while its version is still the version
+// read and its version is increased, so changes made in the meantime are not overwritten.
+// New items are pushed to the existing inventory.
+func mergeInventory(existing *inventoryDocument, inv *inventory.Inventory, locationCode string, report *importReport) []mongo.WriteModel {
+	itemsByVariantID := make(map[primitive.ObjectID]*inventorysvc.Item, len(existing.Products))
+	for _, item := range existing.Products {
+		itemsByVariantID[item.VariantID] = item
+	}
+
+	var (
+		models  []mongo.WriteModel
+		created []*inventorysvc.Item
+	)
+	for _, p := range inv.Products {
+		item, ok := itemsByVariantID[p.VariantID]
+		if !ok {
+			created = append(created, &inventorysvc.Item{Product: *p})
+			report.add(changeCreated, inventoryKey(locationCode, p))
+			continue
+		}
+
+		fields := changedFields(&item.Product, p, "stock", "price", "status")
+		report.addDiff(inventoryKey(locationCode, p), fields)
+		if len(fields) < 1 {
+			continue
+		}
+
+		var version interface{} = item.Version
+		if item.Version == 0 {
+			// items which have never been updated don't have version yet.
+			version = bson.M{"$in": bson.A{0, nil}}
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": existing.ID}).
+			SetUpdate(bson.M{
+				"$set": bson.M{
+					"products.$[item].stock":  p.Stock,
+					"products.$[item].price":  p.Price,
+					"products.$[item].status": p.Status,
+				},
+				"$inc": bson.M{"products.$[item].version": 1},
+			}).
+			SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+				bson.M{"item.variant_id": p.VariantID, "item.version": version},
+			}}))
+	}
+
+	if len(created) > 0 {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": existing.ID}).
+			SetUpdate(bson.M{
+				"$push": bson.M{"products": bson.M{"$each": created}},
+			}))
+	}
+	return models
+}
+
+// findInventories fetches the inventories of the stores indexed by their store id.
+func findInventories(ctx context.Context, db *mongo.Database, storeIDs []primitive.ObjectID) (map[primitive.ObjectID]*inventoryDocument, error) {
+	inventories := map[primitive.ObjectID]*inventoryDocument{}
+	if len(storeIDs) < 1 {
+		return inventories, nil
+	}
+
+	cur, err := db.Collection(inventoryCollection).Find(ctx, bson.M{"store_id": bson.M{"$in": storeIDs}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Find inventories: %w", err)
+	}
+
+	var docs []*inventoryDocument
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode inventories: %w", err)
+	}
+	for _, doc := range docs {
+		inventories[doc.StoreID] = doc
+	}
+	return inventories, nil
+}
+
+// storeLocationCode returns the location code of the store with id.
+func storeLocationCode(darkstores []*darkstore.Store, id primitive.ObjectID) string {
+	for _, ds := range darkstores {
+		if ds.ID == id {
+			return ds.LocationCode
+		}
+	}
+	return ""
+}
+
+// inventoryKey identifies an inventory item on import reports by the store
+// location code followed by the shoptree variant id.
+func inventoryKey(locationCode string, p *inventory.Product) string {
+	return locationCode + " > " + p.ShoptreeVariantID
+}
+
 // getProducts get list of products saved in our database.
 func getProducts(ctx context.Context, db *mongo.Database) ([]*product.Product, error) {
 	cur, err := db.Collection(productCollection).Find(ctx, bson.M{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute ")
+		return nil, fmt.Errorf("failed to execute Find products: %w", err)
 	}
 
 	var products []*product.Product
@@ -181,26 +420,6 @@ func getProducts(ctx context.Context, db *mongo.Database) ([]*product.Product, e
 	return products, nil
 }
 
-// getProduct looks for product with "Name_EN" on products list in our db.
-func getProduct(products []*product.Product, name string, rowNumber int) (*product.Product, error) {
-	for _, p := range products {
-		if strings.Compare(p.Name_EN, name) == 0 {
-			return p, nil
-		}
-	}
-	return nil, fmt.Errorf("failed to find product with EN name: %s, on row: %d", name, rowNumber)
-}
-
-// getProductVariant looks for product variant with "ShoptreeVariantID" in a product.
-func getProductVariant(variants []*product.ProductVariant, shoptreeVariantID string, rowNumber int) (*product.ProductVariant, error) {
-	for _, pv := range variants {
-		if strings.Compare(pv.ShoptreeVariantID, shoptreeVariantID) == 0 {
-			return pv, nil
-		}
-	}
-	return nil, fmt.Errorf("failed to find product variant with shoptree variant id: %s, on row: %d", shoptreeVariantID, rowNumber)
-}
-
 // validateInventory checks whether all inventory parameters are valid.
 func validateInventory(i *inventory.Inventory) error {
 	switch {
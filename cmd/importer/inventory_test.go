@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/darkstore"
+	"github.com/dropezy/storefront-backend/internal/storage/model/inventory"
+	"github.com/dropezy/storefront-backend/internal/storage/model/product"
+
+	"github.com/dropezy/storefront-backend/ems-api/money"
+)
+
+func TestImportInventories(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		defaultTestTimeout,
+	)
+	t.Cleanup(cancel)
+
+	// insert dummy store
+	ds := &darkstore.Store{
+		ID:                 primitive.NewObjectID(),
+		Name:               "Inventory Test Store",
+		LocationCode:       "INV-TEST",
+		ShoptreeLocationID: "inv-test-location",
+	}
+	if _, err := testDb.Collection(darkstoreCollection).InsertOne(ctx, ds); err != nil {
+		t.Fatalf("unexpected error, got = %v", err)
+	}
+
+	// insert dummy product
+	p := &product.Product{
+		ID:      primitive.NewObjectID(),
+		Name_EN: "Inventory Test Product",
+		Variants: []*product.ProductVariant{
+			{ID: primitive.NewObjectID(), ShoptreeVariantID: "inv-test-variant-1", SKU: "INV0001"},
+			{ID: primitive.NewObjectID(), ShoptreeVariantID: "inv-test-variant-2", SKU: "INV0002"},
+		},
+	}
+	if _, err := testDb.Collection(productCollection).InsertOne(ctx, p); err != nil {
+		t.Fatalf("unexpected error, got = %v", err)
+	}
+
+	getInventory := func() *inventoryDocument {
+		t.Helper()
+
+		var inventories []*inventoryDocument
+		cur, err := testDb.Collection(inventoryCollection).Find(ctx, bson.M{"store_id": ds.ID})
+		if err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if err := cur.All(ctx, &inventories); err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if len(inventories) != 1 {
+			t.Fatalf("expected 1 inventory, got = %d", len(inventories))
+		}
+		return inventories[0]
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		if err := importInventories(ctx, testDb, "testdata/inventory/success.csv", importOptions{}); err != nil {
+			t.Fatalf("expected nil error, got = %v", err)
+		}
+		before := getInventory()
+		if len(before.Products) != 2 {
+			t.Fatalf("expected 2 inventory products, got = %d", len(before.Products))
+		}
+
+		// importing again must update the existing inventory instead of inserting a new one.
+		if err := importInventories(ctx, testDb, "testdata/inventory/update.csv", importOptions{}); err != nil {
+			t.Fatalf("expected nil error, got = %v", err)
+		}
+		after := getInventory()
+		if after.ID != before.ID || after.Products[0].ID != before.Products[0].ID {
+			t.Fatalf("expected inventory ids to be kept, got = %+v, want = %+v", after, before)
+		}
+		if after.Products[0].Stock != 7 || after.Products[0].Version != before.Products[0].Version+1 {
+			t.Fatalf("expected inventory product to be updated, got = %+v", after.Products[0])
+		}
+		if after.Products[0].Price.Num != "2400000" || after.Products[1].Price.Num != "1200050" {
+			t.Fatalf("expected inventory product prices to be parsed, got = %s, %s", after.Products[0].Price.Num, after.Products[1].Price.Num)
+		}
+		if after.Products[1].Stock != 5 {
+			t.Fatalf("expected inventory product missing from file to be kept, got = %+v", after.Products[1])
+		}
+	})
+
+	t.Run("ConcurrentUpdate", func(t *testing.T) {
+		stale := getInventory()
+
+		// the item is updated through the inventory service after the import read it.
+		if _, err := testDb.Collection(inventoryCollection).UpdateOne(ctx,
+			bson.M{"_id": stale.ID, "products.variant_id": stale.Products[0].VariantID},
+			bson.M{"$set": bson.M{"products.$.stock": 3}, "$inc": bson.M{"products.$.version": 1}},
+		); err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+
+		changed := stale.Products[0].Product
+		changed.Stock = 20
+		inv := &inventory.Inventory{Products: []*inventory.Product{&changed}}
+		models := mergeInventory(stale, inv, ds.LocationCode, newImportReport(inventoryCollection))
+		res, err := bulkWrite(ctx, testDb.Collection(inventoryCollection), models, importOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if res.ModifiedCount != 0 {
+			t.Fatalf("expected stale item not to be modified, got = %d modified", res.ModifiedCount)
+		}
+		if got := getInventory().Products[0]; got.Stock != 3 || got.Version != stale.Products[0].Version+1 {
+			t.Fatalf("expected inventory service update to be kept, got = %+v", got)
+		}
+	})
+
+	// failed scenarios
+	tests := []struct {
+		name    string
+		path    string
+		wantErr error
+	}{
+		{
+			name:    "UnknownStore",
+			path:    "testdata/inventory/unknown_store.csv",
+			wantErr: errStoreLocationNotFound,
+		},
+		{
+			name:    "UnknownVariant",
+			path:    "testdata/inventory/unknown_variant.csv",
+			wantErr: errProductVariantNotFound,
+		},
+		{
+			name:    "InvalidStock",
+			path:    "testdata/inventory/invalid_stock.csv",
+			wantErr: errInventoryInvalidStock,
+		},
+		{
+			name:    "InvalidPrice",
+			path:    "testdata/inventory/invalid_price.csv",
+			wantErr: money.ErrInvalidAmount,
+		},
+		{
+			name:    "InvalidCurrency",
+			path:    "testdata/inventory/invalid_currency.csv",
+			wantErr: money.ErrUnsupportedCurrency,
+		},
+		{
+			name:    "DuplicateVariant",
+			path:    "testdata/inventory/duplicate_variant.csv",
+			wantErr: errInventoryDuplicateVariant,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := importInventories(ctx, testDb, test.path, importOptions{})
+			if !errors.Is(errors.Unwrap(err), test.wantErr) {
+				t.Fatalf("importInventories(_, _) error, got = %v, want = %v", err, test.wantErr)
+			}
+		})
+	}
+}
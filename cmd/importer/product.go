@@ -2,23 +2,24 @@ package main
 
 import (
 	"context"
-	"encoding/csv"
 	"errors"
 	"fmt"
+	"io"
 	"log"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/dropezy/storefront-backend/internal/storage/model/brand"
 	"github.com/dropezy/storefront-backend/internal/storage/model/category"
 	"github.com/dropezy/storefront-backend/internal/storage/model/product"
 
+	"github.com/dropezy/storefront-backend/ems-api/validation"
+
 	// protobuf
 	prpb "github.com/dropezy/proto/v1/product"
 )
@@ -30,26 +31,53 @@ const (
 )
 
 var (
-	errProductIDIsRequired          = errors.New("product id is required")
-	errProductNameENIsRequired      = errors.New("product name EN is required")
-	errProductNameIDIsRequired      = errors.New("product name ID is required")
-	errProductBrandIDIsRequired     = errors.New("product brand id is required")
-	errProductCategory1IDIsRequired = errors.New("product category 1 id is required")
-	errProductCategory2IDIsRequired = errors.New("product categories 2 id is required")
-	errProductVariantsIsRequired    = errors.New("product variants is required")
-
-	errProductVariantIDIsRequired            = errors.New("product variant id is required")
-	errShoptreeVariantIDIsRequired           = errors.New("shoptree variant id is required")
-	errProductVariantImageURLIsRequired      = errors.New("product variant image url is required")
-	errProductVariantVariantTypeIDIsRequired = errors.New("product variant variant id is required")
-	errVariantValueIsRequired                = errors.New("variant value is required")
-	errVariantQuantifierIDIsRequired         = errors.New("variant quantifier ID is required")
-	errVariantQuantifierENIsRequired         = errors.New("variant quantifier EN is required")
-	errSKUIsRequired                         = errors.New("sku is required")
-	errSKUAndCategoryAbbreviationMismatch    = errors.New("sku and category abbreviation mismatch")
-	errBarcodeIsRequired                     = errors.New("barcode is required")
+	errProductInvalidMaximumOrder = errors.New("maximum order is not a number")
+	errCategoryNotFound           = errors.New("category not found")
+	errChildCategoryNotFound      = errors.New("child category not found")
 )
 
+// productColumns maps product and product variant validation fields to the products file columns.
+var productColumns = map[string]string{
+	"name_en":               "product_name_ENG",
+	"name_id":               "product_name_IND",
+	"shoptree_variant_id":   "product_variant_id",
+	"images_urls":           "image_link",
+	"variant_type_id":       "variant_type",
+	"variant_value":         "option_value_1",
+	"variant_quantifier_en": "quantifier_ENG",
+	"variant_quantifier_id": "quantifier_IND",
+	"sku":                   "sku_structured",
+	"barcode":               "barcodes",
+	"brand_id":              "brand_name",
+	"category_1":            "category_name_EN",
+	"category_2":            "sub_category_name_EN",
+}
+
+// productFileColumns are the columns of the products file.
+var productFileColumns = fileColumns{
+	required: []string{
+		"product_variant_id",
+		"sku_structured",
+		"product_name_ENG",
+		"product_name_IND",
+		"option_value_1",
+		"quantifier_ENG",
+		"quantifier_IND",
+		"maximum_ordered_qty",
+		"barcodes",
+		"category_name_EN",
+		"sub_category_name_EN",
+		"product_description_IND",
+		"product_description_ENG",
+		"image_link",
+		"default_variant",
+	},
+	optional: []string{
+		"brand_name",
+		"variant_type",
+	},
+}
+
 type HeadersIndex struct {
 	shoptree_variant_id   int
 	sku                   int
@@ -66,86 +94,117 @@ type HeadersIndex struct {
 	description_ID        int
 	image_url             int
 	default_variant       int
+	brand_name            int
+	variant_type          int
 }
 
-// importProducts looks into the path given and bulk insert all the products.
-func importProducts(ctx context.Context, db *mongo.Database, path string) error {
-	// first get all categories from database.
-	categories, err := getCategories(ctx, db)
-	if err != nil {
-		return err
-	}
+// importProducts looks into the path given and bulk insert all the products,
+// on upsert mode products are matched with the existing products instead.
+// Products are written batch by batch while the file is read, so neither the
+// products of the file nor the existing products are all kept in memory.
+func importProducts(ctx context.Context, db *mongo.Database, path string, opts importOptions) error {
+	// level 1 categories indexed by their EN name, fetched on their first use.
+	parentCategories := map[string]*category.Category{}
 
-	// find or insert brand
-	// NOTE: This is only temporary, until brand lists are ready to be imported.
-	brandData, err := findOrInsertBrand(ctx, db)
+	// get all brands to link products by their brand name.
+	brands, err := getBrands(ctx, db)
 	if err != nil {
 		return err
 	}
 
-	// find or insert variant type
-	variantTypeData, err := findOrInsertVariantType(ctx, db)
+	// get all variant types to link product variants by their variant type name.
+	variantTypes, err := getVariantTypes(ctx, db)
 	if err != nil {
 		return err
 	}
 
 	// open products file from the path given.
-	productsFile, err := os.Open(filepath.Clean(path))
+	report := newImportReport(productCollection)
+	w := newProductWriter(db, opts, report)
+
+	// products is the batch of products not written yet.
+	var products []*product.Product
+	// productIndexes indexes the batch products by their EN name, to group the variants rows of the same product.
+	productIndexes := map[string]int{}
+	hi := &HeadersIndex{}
+	errReport := newErrorReport(productColumns)
+	// reads the products file row by row, on the format of the file.
+	rows, err := openRowReader(path, opts)
 	if err != nil {
-		return fmt.Errorf("failed to open products file: %w", err)
+		return fmt.Errorf("failed to read products file: %w", err)
 	}
-	defer func() {
-		err := productsFile.Close()
+	defer rows.close()
+	for {
+		line, rowNumber, err := rows.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
 		if err != nil {
-			log.Fatalf("failed to close products file: %v", err)
+			return fmt.Errorf("failed to read products file lines: %w", err)
 		}
-	}()
+		if rowNumber == 1 {
+			header, err := readHeader(line, productFileColumns, opts.headerAliases)
+			if err != nil {
+				return fmt.Errorf("failed to read products file header: %w", err)
+			}
+			errReport.setHeader(header.names)
 
-	// reads the products file.
-	productsFileData, err := csv.NewReader(productsFile).ReadAll()
-	if err != nil {
-		return fmt.Errorf("failed to read products file lines: %w", err)
-	}
+			// get all the index for the headers
+			hi = getProductsHeaderIndex(header)
 
-	logList := []string{}
+			// products files without brand name column are linked to the default brand.
+			if hi.brand_name < 0 {
+				defaultBrand, err := findOrInsertBrand(ctx, db, opts)
+				if err != nil {
+					return err
+				}
+				brands = map[string]*brand.Brand{"": defaultBrand}
+			}
 
-	var products []*product.Product
-	hi := &HeadersIndex{}
-	for i, line := range productsFileData {
-		rowNumber := i + 1
-		if rowNumber == 1 {
-			// get all the index for the headers
-			hi = getProductsHeaderIndex(line)
+			// products files without variant type column are linked to the UOM variant type.
+			if hi.variant_type < 0 {
+				defaultVariantType, err := findOrInsertVariantType(ctx, db, opts)
+				if err != nil {
+					return err
+				}
+				variantTypes = map[string]*product.VariantType{"": defaultVariantType}
+			}
 			continue
 		}
 
+		// every invalid column of the row is recorded.
 		// check barcode and image url, product without barcode/image_url will be skipped
 		// NOTE: This is just an extra measure to prevent invalid product insertion.
-		switch "" {
-		case line[hi.image_url]:
-			logList = append(logList, fmt.Errorf("image url on row: %d, is empty, skipping product", rowNumber).Error())
-			continue
-		case line[hi.barcode]:
-			logList = append(logList, fmt.Errorf("barcode on row: %d, is empty, skipping product", rowNumber).Error())
-			continue
+		if line[hi.image_url] == "" {
+			errReport.add(rowNumber, line, &validation.FieldError{
+				Field: "images_urls",
+				Err:   validation.ErrProductVariantImageURLIsRequired,
+			})
+		}
+		if line[hi.barcode] == "" {
+			errReport.add(rowNumber, line, &validation.FieldError{
+				Field: "barcode",
+				Err:   validation.ErrBarcodeIsRequired,
+			})
 		}
 
 		// convert max number to type int32
 		maxNumber, err := strconv.ParseInt(line[hi.maximum_order], 10, 32)
 		if err != nil {
-			// return fmt.Errorf("failed to convert maximum order on row: %v, err: %w", i+1, err)
-			logList = append(logList, fmt.Errorf("failed to convert maximum order on row: %d, err: %w", rowNumber, err).Error())
+			errReport.addColumn(rowNumber, line, "maximum_ordered_qty", errProductInvalidMaximumOrder)
 		}
 
 		// look for product in products
-		var productIdx int
-		found := false
-		for i, p := range products {
-			if strings.Compare(p.Name_EN, line[hi.product_name_EN]) == 0 {
-				productIdx = i
-				found = true
-				break
-			}
+		productIdx, found := productIndexes[line[hi.product_name_EN]]
+
+		// look for variant type
+		var variantTypeName string
+		if hi.variant_type >= 0 {
+			variantTypeName = line[hi.variant_type]
+		}
+		variantTypeData, err := getVariantType(variantTypes, variantTypeName, rowNumber)
+		if err != nil {
+			errReport.addColumn(rowNumber, line, "variant_type", err)
 		}
 
 		variantImageURL := line[hi.sku] + "-0.webp"
@@ -153,7 +212,6 @@ func importProducts(ctx context.Context, db *mongo.Database, path string) error
 			ID:                   primitive.NewObjectID(),
 			ShoptreeVariantID:    line[hi.shoptree_variant_id],
 			ImagesURLs:           []string{variantImageURL},
-			VariantTypeID:        variantTypeData.ID,
 			VariantValue:         line[hi.variant_value],
 			VariantQuantifier_ID: line[hi.variant_quantifier_ID],
 			VariantQuantifier_EN: line[hi.variant_quantifier_EN],
@@ -165,95 +223,406 @@ func importProducts(ctx context.Context, db *mongo.Database, path string) error
 		if strings.Compare(line[hi.default_variant], "yes") == 0 {
 			productVariant.VariantStatus = prpb.VariantStatus_VARIANT_STATUS_DEFAULT
 		}
-		if err := validateProductVariant(productVariant); err != nil {
-			return fmt.Errorf("failed to import products, on row: %d, err: %w", rowNumber, err)
+		if variantTypeData != nil {
+			productVariant.VariantTypeID = variantTypeData.ID
+			if err := validation.ValidateVariantQuantifier(variantTypeData, productVariant); err != nil {
+				errReport.add(rowNumber, line, err)
+			}
+		}
+		if err := validation.ValidateProductVariant(productVariant); err != nil {
+			errReport.add(rowNumber, line, err)
 		}
 
 		// if product has been created, append product variant to found product,
 		// else, create product and insert product variant to variants list.
 		if found {
-			products[productIdx].Variants =
-				append(products[productIdx].Variants, productVariant)
-		} else {
-			// look for level 1 category
-			categoryl1, err := getParentCategory(categories, line[hi.category_name_EN], rowNumber)
-			if err != nil {
-				// this is just a measure to make sure the program continues and keeps
-				// logging the errors found.
-				categoryl1 = &category.Category{
-					ID: primitive.NewObjectID(),
-				}
-				logList = append(logList, err.Error())
+			if errReport.len() == 0 {
+				products[productIdx].Variants =
+					append(products[productIdx].Variants, productVariant)
 			}
+			continue
+		}
+
+		p := &product.Product{
+			ID:       primitive.NewObjectID(),
+			Name_ID:  line[hi.product_name_ID],
+			Name_EN:  line[hi.product_name_EN],
+			Variants: []*product.ProductVariant{productVariant},
+		}
 
-			// look for C2 category
+		// look for level 1 category, then its C2 category
+		categoryl1, err := getParentCategory(ctx, db, parentCategories, line[hi.category_name_EN], rowNumber)
+		if err != nil && !errors.Is(err, errCategoryNotFound) {
+			return err
+		}
+		if err != nil {
+			errReport.addColumn(rowNumber, line, "category_name_EN", err)
+		} else {
+			p.Category1ID = categoryl1.ID
 			categoryl2, err := getChildCategory(categoryl1.ChildCategories, line[hi.subcategory_name_EN], rowNumber)
 			if err != nil {
-				categoryl2 = category.Category{
-					ID: primitive.NewObjectID(),
-				}
-				// skip logging if child categories is empty.
-				if len(categoryl1.ChildCategories) > 0 {
-					logList = append(logList, err.Error())
-				}
+				errReport.addColumn(rowNumber, line, "sub_category_name_EN", err)
+			} else {
+				p.Category2ID = categoryl2.ID
 			}
 
 			// check if variant SKU is designated with the correct category.
-			if !strings.Contains(line[hi.sku], categoryl1.Abbreviation) {
-				return fmt.Errorf("mismatch sku: %s and category abbreviation: %s", line[hi.sku], categoryl1.Abbreviation)
+			if err := validation.ValidateSKU(line[hi.sku], categoryl1.Abbreviation); err != nil {
+				errReport.add(rowNumber, line, err)
 			}
+		}
 
-			p := &product.Product{
-				ID:          primitive.NewObjectID(),
-				Name_ID:     line[hi.product_name_ID],
-				Name_EN:     line[hi.product_name_EN],
-				BrandID:     brandData.ID,
-				Category1ID: categoryl1.ID,
-				Category2ID: categoryl2.ID,
-				Variants:    []*product.ProductVariant{productVariant},
-			}
-			// if description is #N/A, means description is empty
-			emptyDescValue := "#N/A"
-			if strings.Compare(line[hi.description_EN], emptyDescValue) != 0 {
-				p.Description_EN = line[hi.description_EN]
-			}
-			if strings.Compare(line[hi.description_ID], emptyDescValue) != 0 {
-				p.Description_ID = line[hi.description_ID]
-			}
-			if err := validateProduct(p); err != nil {
-				return fmt.Errorf("failed to import products, on row: %d, err: %w", rowNumber, err)
+		// look for product brand
+		var brandName string
+		if hi.brand_name >= 0 {
+			brandName = line[hi.brand_name]
+		}
+		brandData, err := getBrand(brands, brandName, rowNumber)
+		if err != nil {
+			errReport.addColumn(rowNumber, line, "brand_name", err)
+		} else {
+			p.BrandID = brandData.ID
+		}
+
+		// if description is #N/A, means description is empty
+		emptyDescValue := "#N/A"
+		if strings.Compare(line[hi.description_EN], emptyDescValue) != 0 {
+			p.Description_EN = line[hi.description_EN]
+		}
+		if strings.Compare(line[hi.description_ID], emptyDescValue) != 0 {
+			p.Description_ID = line[hi.description_ID]
+		}
+		if err := validation.ValidateProduct(p); err != nil {
+			errReport.add(rowNumber, line, err)
+		}
+		// once a row is invalid the import fails, the remaining rows are only validated.
+		if errReport.len() > 0 {
+			continue
+		}
+
+		// the batch is written once it is full, before a new product is added to it.
+		if len(products) >= opts.batch() {
+			if err := w.write(ctx, products); err != nil {
+				return err
 			}
-			products = append(products, p)
+			products, productIndexes = nil, map[string]int{}
 		}
+		productIndexes[p.Name_EN] = len(products)
+		products = append(products, p)
 	}
 
-	// if log list is not empty, fails bulk insert and return errors.
-	if len(logList) > 0 {
-		log.Print(strings.Join(logList, "\n"))
-		return errors.New("found several errors while importing products")
+	// fails the import when any row is invalid, the batches already written
+	// are rolled back by the import run.
+	if err := errReport.check(opts.errorsReportPath); err != nil {
+		return err
 	}
 
-	collection := db.Collection(productCollection)
+	if err := w.write(ctx, products); err != nil {
+		return err
+	}
+	if opts.mode == modeUpsert && opts.disableMissingVariants {
+		if err := w.disableMissingVariants(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := report.finish(opts); err != nil {
+		return err
+	}
+	if !opts.dryRun {
+		log.Print("successfully imported products!")
+	}
+	return nil
+}
+
+// productWriter writes the products of a file batch by batch.
+type productWriter struct {
+	db     *mongo.Database
+	opts   importOptions
+	report *importReport
+	// written maps the EN name of the written products to their id, so the variants
+	// of a product found after its batch has been written are added to the product.
+	written map[string]primitive.ObjectID
+	// matched holds the ids of the variants found on the file by the id of their
+	// product, in order of the products, so the variants missing from the whole
+	// file can be disabled. It is only set when missing variants are disabled.
+	matched    map[primitive.ObjectID]map[primitive.ObjectID]bool
+	matchedIDs []primitive.ObjectID
+}
+
+func newProductWriter(db *mongo.Database, opts importOptions, report *importReport) *productWriter {
+	w := &productWriter{
+		db:      db,
+		opts:    opts,
+		report:  report,
+		written: map[string]primitive.ObjectID{},
+	}
+	if opts.mode == modeUpsert && opts.disableMissingVariants {
+		w.matched = map[primitive.ObjectID]map[primitive.ObjectID]bool{}
+	}
+	return w
+}
+
+// write converts a batch of products to mongo write models and bulk writes them,
+// on upsert mode the batch products are matched with the existing products of the
+// same EN name. Nothing is written on dry run or when all products are unchanged.
+func (w *productWriter) write(ctx context.Context, products []*product.Product) error {
+	if len(products) < 1 {
+		return nil
+	}
+
+	existingByName := map[string]*product.Product{}
+	if w.opts.mode == modeUpsert {
+		names := make([]string, 0, len(products))
+		for _, p := range products {
+			names = append(names, p.Name_EN)
+		}
+		existingProducts, err := findProducts(ctx, w.db, names)
+		if err != nil {
+			return err
+		}
+		for _, p := range existingProducts {
+			existingByName[p.Name_EN] = p
+		}
+	}
 
-	// converts all products to mongo write model for bulk insert.
 	models := []mongo.WriteModel{}
 	for _, p := range products {
-		writeModel := mongo.NewInsertOneModel().SetDocument(p)
-		models = append(models, writeModel)
+		if existing, ok := existingByName[p.Name_EN]; ok {
+			w.written[p.Name_EN] = existing.ID
+			if mergeProduct(existing, p, w.matchedVariants(existing.ID), w.report) {
+				models = append(models, productUpdateModel(existing))
+			}
+			continue
+		}
+
+		// the product has been written by a previous batch, which is only missing
+		// from the existing products on insert mode or on dry run.
+		if id, ok := w.written[p.Name_EN]; ok {
+			w.match(id, p.Variants)
+			for _, v := range p.Variants {
+				w.report.add(changeCreated, productKey(p, v))
+			}
+			writeModel := mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": id}).
+				SetUpdate(bson.M{
+					"$push": bson.M{"variants": bson.M{"$each": p.Variants}},
+				})
+			models = append(models, writeModel)
+			continue
+		}
+
+		w.written[p.Name_EN] = p.ID
+		w.match(p.ID, p.Variants)
+		models = append(models, mongo.NewInsertOneModel().SetDocument(p))
+		reportProduct(w.report, changeCreated, p)
 	}
 
-	// bulk insert products
-	if _, err := collection.BulkWrite(ctx, models); err != nil {
-		return fmt.Errorf("failed to execute BulkWrite, on import products: %w", err)
+	if !w.opts.dryRun && len(models) > 0 {
+		if _, err := bulkWrite(ctx, w.db.Collection(productCollection), models, w.opts); err != nil {
+			return fmt.Errorf("failed to execute BulkWrite, on import products: %w", err)
+		}
+	}
+	return nil
+}
+
+// matchedVariants returns the matched variants of the product with id, it returns
+// nil when missing variants are not disabled.
+func (w *productWriter) matchedVariants(id primitive.ObjectID) map[primitive.ObjectID]bool {
+	if w.matched == nil {
+		return nil
+	}
+	matched, ok := w.matched[id]
+	if !ok {
+		matched = map[primitive.ObjectID]bool{}
+		w.matched[id] = matched
+		w.matchedIDs = append(w.matchedIDs, id)
 	}
+	return matched
+}
+
+// match adds variants to the matched variants of the product with id.
+func (w *productWriter) match(id primitive.ObjectID, variants []*product.ProductVariant) {
+	matched := w.matchedVariants(id)
+	if matched == nil {
+		return
+	}
+	for _, v := range variants {
+		matched[v.ID] = true
+	}
+}
+
+// disableMissingVariants disables the variants of the imported products which are
+// missing from the whole file, once all the batches have been written. Products are
+// fetched batch by batch and only the status of their missing variants is updated.
+func (w *productWriter) disableMissingVariants(ctx context.Context) error {
+	collection := w.db.Collection(productCollection)
+	batchSize := w.opts.batch()
+	for start := 0; start < len(w.matchedIDs); start += batchSize {
+		end := start + batchSize
+		if end > len(w.matchedIDs) {
+			end = len(w.matchedIDs)
+		}
+
+		cur, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": w.matchedIDs[start:end]}})
+		if err != nil {
+			return fmt.Errorf("failed to execute Find products: %w", err)
+		}
+		var products []*product.Product
+		if err := cur.All(ctx, &products); err != nil {
+			return fmt.Errorf("failed to decode products: %w", err)
+		}
+
+		models := []mongo.WriteModel{}
+		for _, p := range products {
+			var missing bson.A
+			for _, v := range p.Variants {
+				if w.matched[p.ID][v.ID] || v.VariantStatus == prpb.VariantStatus_VARIANT_STATUS_DISABLED {
+					continue
+				}
+				missing = append(missing, v.ID)
+				w.report.add(changeUpdated, productKey(p, v), "variant_status")
+			}
+			if len(missing) < 1 {
+				continue
+			}
+			writeModel := mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": p.ID}).
+				SetUpdate(bson.M{
+					"$set": bson.M{"variants.$[missing].variant_status": prpb.VariantStatus_VARIANT_STATUS_DISABLED},
+				}).
+				SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+					bson.M{"missing._id": bson.M{"$in": missing}},
+				}})
+			models = append(models, writeModel)
+		}
 
-	log.Print("successfully imported products!")
+		if !w.opts.dryRun && len(models) > 0 {
+			if _, err := bulkWrite(ctx, collection, models, w.opts); err != nil {
+				return fmt.Errorf("failed to execute BulkWrite, on disable missing variants: %w", err)
+			}
+		}
+	}
 	return nil
 }
 
-// getCategories fetches all categories in our database which will be used to get
-// level 1 and 2 categories for product bulk insert.
-func getCategories(ctx context.Context, db *mongo.Database) ([]*category.Category, error) {
+// findProducts fetches the products in our database with one of the EN names
+// which have not been deleted.
+func findProducts(ctx context.Context, db *mongo.Database, names []string) ([]*product.Product, error) {
+	cur, err := db.Collection(productCollection).Find(ctx, bson.M{
+		"name_en":    bson.M{"$in": names},
+		"deleted_at": nil,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Find products: %w", err)
+	}
+
+	var products []*product.Product
+	if err := cur.All(ctx, &products); err != nil {
+		return nil, fmt.Errorf("failed to decode products: %w", err)
+	}
+	return products, nil
+}
+
+// productUpdateModel returns the model updating the fields and variants of a merged product.
+func productUpdateModel(existing *product.Product) mongo.WriteModel {
+	return mongo.NewUpdateOneModel().
+		SetFilter(bson.M{"_id": existing.ID}).
+		SetUpdate(bson.M{
+			"$set": bson.M{
+				"name_id":        existing.Name_ID,
+				"description_en": existing.Description_EN,
+				"description_id": existing.Description_ID,
+				"brand_id":       existing.BrandID,
+				"category1_id":   existing.Category1ID,
+				"category2_id":   existing.Category2ID,
+				"variants":       existing.Variants,
+			},
+		})
+}
+
+// mergeProduct matches p with existing, the product with the same EN name, and its
+// variants by shoptree variant id or sku, keeping the existing product and variant ids
+// so inventories keep pointing to the same variants. Changed fields are updated and
+// new variants are appended, the ids of the matched variants are added to matched
+// when it is not nil. It returns whether existing has been changed.
+func mergeProduct(existing, p *product.Product, matched map[primitive.ObjectID]bool, report *importReport) bool {
+	fields := changedFields(existing, p,
+		"name_id", "description_en", "description_id", "brand_id", "category1_id", "category2_id")
+	changed := len(fields) > 0
+	existing.Name_ID = p.Name_ID
+	existing.Description_EN = p.Description_EN
+	existing.Description_ID = p.Description_ID
+	existing.BrandID = p.BrandID
+	existing.Category1ID = p.Category1ID
+	existing.Category2ID = p.Category2ID
+	report.addDiff(productKey(p, nil), fields)
+
+	for _, v := range p.Variants {
+		existingVariant := findVariant(existing.Variants, v)
+		if existingVariant == nil {
+			existing.Variants = append(existing.Variants, v)
+			if matched != nil {
+				matched[v.ID] = true
+			}
+			changed = true
+			report.add(changeCreated, productKey(p, v))
+			continue
+		}
+		if matched != nil {
+			matched[existingVariant.ID] = true
+		}
+
+		// variant ids and images are kept, images are managed outside of the importer.
+		v.ID = existingVariant.ID
+		if len(existingVariant.ImagesURLs) > 0 {
+			v.ImagesURLs = existingVariant.ImagesURLs
+		}
+		variantFields := changedFields(existingVariant, v)
+		if len(variantFields) > 0 {
+			*existingVariant = *v
+			changed = true
+		}
+		report.addDiff(productKey(p, v), variantFields)
+	}
+	return changed
+}
+
+// findVariant looks for v on variants by its shoptree variant id, or by its sku
+// when the shoptree variant id is empty.
+func findVariant(variants []*product.ProductVariant, v *product.ProductVariant) *product.ProductVariant {
+	for _, existing := range variants {
+		if v.ShoptreeVariantID != "" && existing.ShoptreeVariantID == v.ShoptreeVariantID {
+			return existing
+		}
+	}
+	for _, existing := range variants {
+		if existing.SKU == v.SKU {
+			return existing
+		}
+	}
+	return nil
+}
+
+// reportProduct adds the same change for a product and all its variants.
+func reportProduct(report *importReport, kind changeKind, p *product.Product) {
+	report.add(kind, productKey(p, nil))
+	for _, v := range p.Variants {
+		report.add(kind, productKey(p, v))
+	}
+}
+
+// productKey identifies a product on import reports by its EN name,
+// followed by the variant sku for product variants.
+func productKey(p *product.Product, v *product.ProductVariant) string {
+	if v == nil {
+		return p.Name_EN
+	}
+	return p.Name_EN + " > " + v.SKU
+}
+
+// findCategories fetches all categories in our database.
+func findCategories(ctx context.Context, db *mongo.Database) ([]*category.Category, error) {
 	cursor, err := db.Collection(categoryCollection).Find(ctx, bson.M{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute Find categories: %w", err)
@@ -267,87 +636,102 @@ func getCategories(ctx context.Context, db *mongo.Database) ([]*category.Categor
 		}
 		categories = append(categories, c)
 	}
-
-	// categories must exist before in order to execute bulk insert products.
-	if len(categories) < 1 {
-		return nil, fmt.Errorf("no categories found in database")
-	}
-
 	return categories, nil
 }
 
-// findOrInsertBrand first checks whether the brand already exist in our database,
+// findOrInsertBrand first checks whether the default brand already exist in our database,
 // if it doesn't exist then insert the brand and return brand response.
-func findOrInsertBrand(ctx context.Context, db *mongo.Database) (*brand.Brand, error) {
+// Nothing is inserted on dry run.
+func findOrInsertBrand(ctx context.Context, db *mongo.Database, opts importOptions) (*brand.Brand, error) {
+	collection := db.Collection(brandCollection)
+
 	// check whether brand already exist
 	brandData := &brand.Brand{}
-	found := true
-	if err := db.Collection(brandCollection).
-		FindOne(ctx, bson.M{
-			"name": "Dropezy",
-		}).
-		Decode(brandData); err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			found = false
-		} else {
-			return nil, fmt.Errorf("failed to execute FindOne brand: %w", err)
-		}
+	err := collection.FindOne(ctx, bson.M{"name": "Dropezy"}).Decode(brandData)
+	if err == nil {
+		return brandData, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to execute FindOne brand: %w", err)
 	}
+
 	// if brand doesn't exist, seed one dummy brand data for all products.
-	if !found {
-		brandRes, err := db.Collection(brandCollection).InsertOne(ctx, &brand.Brand{
-			Name: "Dropezy",
-		})
-		if err != nil {
+	brandData = &brand.Brand{
+		ID:   primitive.NewObjectID(),
+		Name: "Dropezy",
+	}
+	if !opts.dryRun {
+		models := []mongo.WriteModel{mongo.NewInsertOneModel().SetDocument(brandData)}
+		if _, err := bulkWrite(ctx, collection, models, opts); err != nil {
 			return nil, fmt.Errorf("failed to execute InsertOne brand: %w", err)
 		}
-		brandData = &brand.Brand{
-			ID: brandRes.InsertedID.(primitive.ObjectID),
-		}
 	}
 	return brandData, nil
 }
 
 // findOrInsertVariantType first checks whether the variant type already exist in our database,
 // if it doesn't exist then insert the variant type and return variant type response.
-func findOrInsertVariantType(ctx context.Context, db *mongo.Database) (*product.VariantType, error) {
+// Nothing is inserted on dry run.
+func findOrInsertVariantType(ctx context.Context, db *mongo.Database, opts importOptions) (*product.VariantType, error) {
+	collection := db.Collection(variantTypeCollection)
+
 	// check whether variant type exist
 	variantType := &product.VariantType{}
-	found := true
-	if err := db.Collection(variantTypeCollection).
-		FindOne(ctx, bson.M{
-			"name": "UOM",
-		}).
-		Decode(variantType); err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			found = false
-		} else {
-			return nil, fmt.Errorf("failed to execute FindOne variant type: %w", err)
-		}
+	err := collection.FindOne(ctx, bson.M{"name": "UOM"},
+		options.FindOne().SetCollation(variantTypeNameCollation),
+	).Decode(variantType)
+	if err == nil {
+		return variantType, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to execute FindOne variant type: %w", err)
 	}
+
 	// if variant type doesn't exist, insert variant type
-	if !found {
-		variantTypeRes, err := db.Collection(variantTypeCollection).InsertOne(ctx, &product.VariantType{
-			Name: "UOM",
-		})
-		if err != nil {
+	// the variant type has no quantifiers, so it allows any quantifier.
+	variantType = &product.VariantType{
+		ID:       primitive.NewObjectID(),
+		Name:     "UOM",
+		Label_EN: "Unit of measure",
+		Label_ID: "Satuan",
+	}
+	if !opts.dryRun {
+		models := []mongo.WriteModel{mongo.NewInsertOneModel().SetDocument(variantType)}
+		if _, err := bulkWrite(ctx, collection, models, opts); err != nil {
 			return nil, fmt.Errorf("failed to execute InsertOne variant type: %w", err)
 		}
-		variantType = &product.VariantType{
-			ID: variantTypeRes.InsertedID.(primitive.ObjectID),
-		}
 	}
 	return variantType, nil
 }
 
-// getParentCategory looks for a category with "Name_EN" on categories list in our db.
-func getParentCategory(categories []*category.Category, ctName string, rowNumber int) (*category.Category, error) {
-	for _, c := range categories {
-		if strings.Compare(c.Name_EN, ctName) == 0 {
-			return c, nil
+// getBrand looks for a brand with name on brands indexed by their lowercase name.
+func getBrand(brands map[string]*brand.Brand, name string, rowNumber int) (*brand.Brand, error) {
+	if b, ok := brands[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("failed to find brand with name: %s, on row: %d, error: %w", name, rowNumber, errBrandNotFound)
+}
+
+// getParentCategory looks for a level 1 category with "Name_EN" on categories indexed by their
+// EN name, categories are fetched from our database on their first lookup and added to
+// categories, so only the categories of the products file are kept in memory.
+func getParentCategory(ctx context.Context, db *mongo.Database, categories map[string]*category.Category, ctName string, rowNumber int) (*category.Category, error) {
+	c, ok := categories[ctName]
+	if !ok {
+		c = &category.Category{}
+		err := db.Collection(categoryCollection).FindOne(ctx, bson.M{"name_en": ctName}).Decode(c)
+		switch {
+		case errors.Is(err, mongo.ErrNoDocuments):
+			c = nil
+		case err != nil:
+			return nil, fmt.Errorf("failed to execute FindOne category: %w", err)
 		}
+		categories[ctName] = c
+	}
+	if c == nil {
+		return nil, fmt.Errorf("failed to find level 1 category with EN name: %s, on row: %d, error: %w", ctName, rowNumber, errCategoryNotFound)
 	}
-	return nil, fmt.Errorf("failed to find level 1 category with EN name: %s, on row: %d", ctName, rowNumber)
+	return c, nil
 }
 
 // getChildCategory looks for a child category with "Name_EN" on categories list in our db.
@@ -357,94 +741,29 @@ func getChildCategory(categories []category.Category, ctName string, rowNumber i
 			return c, nil
 		}
 	}
-	return category.Category{}, fmt.Errorf("failed to find level 2 category with EN name: %s, on row: %d", ctName, rowNumber)
-}
-
-// validateProduct checks whether all required parameters are fulfilled.
-func validateProduct(p *product.Product) error {
-	switch {
-	case p.ID == primitive.NilObjectID: // just in case id is not properly generated.
-		return errProductIDIsRequired
-	case p.Name_ID == "":
-		return errProductNameIDIsRequired
-	case p.Name_EN == "":
-		return errProductNameENIsRequired
-	case p.BrandID == primitive.NilObjectID:
-		return errProductBrandIDIsRequired
-	case p.Category1ID == primitive.NilObjectID:
-		return errProductCategory1IDIsRequired
-	case p.Category2ID == primitive.NilObjectID:
-		return errProductCategory2IDIsRequired
-	case p.Variants == nil:
-		return errProductVariantsIsRequired
-	}
-	return nil
+	return category.Category{}, fmt.Errorf("failed to find level 2 category with EN name: %s, on row: %d, error: %w", ctName, rowNumber, errChildCategoryNotFound)
 }
 
-// validateProductVariant checks whether all required parameters are fulfilled.
-func validateProductVariant(pv *product.ProductVariant) error {
-	switch {
-	case pv.ID == primitive.NilObjectID:
-		return errProductVariantIDIsRequired
-	case pv.ShoptreeVariantID == "":
-		return errShoptreeVariantIDIsRequired
-	case pv.ImagesURLs[0] == "":
-		return errProductVariantImageURLIsRequired
-	case len(pv.ImagesURLs) < 1: // just in case image url is not inserted.
-		return errProductVariantImageURLIsRequired
-	case pv.VariantTypeID == primitive.NilObjectID:
-		return errProductVariantVariantTypeIDIsRequired
-	case pv.VariantValue == "":
-		return errVariantValueIsRequired
-	case pv.VariantQuantifier_ID == "":
-		return errVariantQuantifierIDIsRequired
-	case pv.VariantQuantifier_EN == "":
-		return errVariantQuantifierENIsRequired
-	case pv.SKU == "":
-		return errSKUIsRequired
-	case pv.Barcode == "":
-		return errBarcodeIsRequired
+// get the index of products file headers,
+// brand name and variant type columns are optional, -1 means the column doesn't exist.
+func getProductsHeaderIndex(header *fileHeader) *HeadersIndex {
+	return &HeadersIndex{
+		shoptree_variant_id:   header.column("product_variant_id"),
+		sku:                   header.column("sku_structured"),
+		product_name_EN:       header.column("product_name_ENG"),
+		product_name_ID:       header.column("product_name_IND"),
+		variant_value:         header.column("option_value_1"),
+		variant_quantifier_EN: header.column("quantifier_ENG"),
+		variant_quantifier_ID: header.column("quantifier_IND"),
+		maximum_order:         header.column("maximum_ordered_qty"),
+		barcode:               header.column("barcodes"),
+		category_name_EN:      header.column("category_name_EN"),
+		subcategory_name_EN:   header.column("sub_category_name_EN"),
+		description_ID:        header.column("product_description_IND"),
+		description_EN:        header.column("product_description_ENG"),
+		image_url:             header.column("image_link"),
+		default_variant:       header.column("default_variant"),
+		brand_name:            header.column("brand_name"),
+		variant_type:          header.column("variant_type"),
 	}
-
-	return nil
-}
-
-// get the index of products file headers.
-func getProductsHeaderIndex(line []string) *HeadersIndex {
-	hi := &HeadersIndex{}
-	for idx, header := range line {
-		switch header {
-		case "product_variant_id":
-			hi.shoptree_variant_id = idx
-		case "sku_structured":
-			hi.sku = idx
-		case "product_name_ENG":
-			hi.product_name_EN = idx
-		case "product_name_IND":
-			hi.product_name_ID = idx
-		case "option_value_1":
-			hi.variant_value = idx
-		case "quantifier_ENG":
-			hi.variant_quantifier_EN = idx
-		case "quantifier_IND":
-			hi.variant_quantifier_ID = idx
-		case "maximum_ordered_qty":
-			hi.maximum_order = idx
-		case "barcodes":
-			hi.barcode = idx
-		case "category_name_EN":
-			hi.category_name_EN = idx
-		case "sub_category_name_EN":
-			hi.subcategory_name_EN = idx
-		case "product_description_IND":
-			hi.description_ID = idx
-		case "product_description_ENG":
-			hi.description_EN = idx
-		case "image_link":
-			hi.image_url = idx
-		case "default_variant":
-			hi.default_variant = idx
-		}
-	}
-	return hi
 }
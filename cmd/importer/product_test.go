@@ -8,10 +8,14 @@ import (
 	"github.com/dropezy/storefront-backend/internal/storage/model/brand"
 	"github.com/dropezy/storefront-backend/internal/storage/model/category"
 	"github.com/dropezy/storefront-backend/internal/storage/model/product"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"github.com/dropezy/storefront-backend/ems-api/validation"
+
 	// protobuf
 	ctpb "github.com/dropezy/proto/v1/category"
+	prpb "github.com/dropezy/proto/v1/product"
 )
 
 func TestImportProducts(t *testing.T) {
@@ -61,14 +65,103 @@ func TestImportProducts(t *testing.T) {
 	if _, err := testDb.Collection(variantTypeCollection).InsertOne(ctx, vt); err != nil {
 		t.Fatalf("unexpected error, got = %v", err)
 	}
+	sizeVariantType := &product.VariantType{
+		ID:       primitive.NewObjectID(),
+		Name:     "product-test-size",
+		Label_EN: "Size",
+		Label_ID: "Ukuran",
+		Quantifiers: []*product.Quantifier{
+			{Name_EN: "ml", Name_ID: "ml"},
+		},
+	}
+	if _, err := testDb.Collection(variantTypeCollection).InsertOne(ctx, sizeVariantType); err != nil {
+		t.Fatalf("unexpected error, got = %v", err)
+	}
 
 	t.Run("Success", func(t *testing.T) {
 		path := "testdata/product/success.csv"
-		if err := importProducts(ctx, testDb, path); err != nil {
+		if err := importProducts(ctx, testDb, path, importOptions{mode: modeInsert}); err != nil {
 			t.Fatalf("expected nil error, got = %v", err)
 		}
 	})
 
+	t.Run("Batches", func(t *testing.T) {
+		// every product is written on its own batch, the last row adds a
+		// variant to a product written by a previous batch.
+		opts := importOptions{mode: modeInsert, batchSize: 1}
+		if err := importProducts(ctx, testDb, "testdata/product/split_rows.csv", opts); err != nil {
+			t.Fatalf("expected nil error, got = %v", err)
+		}
+
+		var products []*product.Product
+		cur, err := testDb.Collection(productCollection).Find(ctx, bson.M{"name_en": "Split Chicken Eggs"})
+		if err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if err := cur.All(ctx, &products); err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if len(products) != 1 || len(products[0].Variants) != 2 {
+			t.Fatalf("expected 1 product with 2 variants, got = %+v", products)
+		}
+	})
+
+	t.Run("Upsert", func(t *testing.T) {
+		// products are looked up and missing variants disabled batch by batch.
+		opts := importOptions{mode: modeUpsert, disableMissingVariants: true, batchSize: 1}
+		getProduct := func() *product.Product {
+			t.Helper()
+
+			var products []*product.Product
+			cur, err := testDb.Collection(productCollection).Find(ctx, bson.M{"name_en": "Upsert Chicken Eggs"})
+			if err != nil {
+				t.Fatalf("unexpected error, got = %v", err)
+			}
+			if err := cur.All(ctx, &products); err != nil {
+				t.Fatalf("unexpected error, got = %v", err)
+			}
+			if len(products) != 1 {
+				t.Fatalf("expected 1 product, got = %d", len(products))
+			}
+			return products[0]
+		}
+
+		if err := importProducts(ctx, testDb, "testdata/product/upsert.csv", opts); err != nil {
+			t.Fatalf("expected nil error, got = %v", err)
+		}
+		// importing the same file again must not duplicate products.
+		if err := importProducts(ctx, testDb, "testdata/product/upsert.csv", opts); err != nil {
+			t.Fatalf("expected nil error, got = %v", err)
+		}
+		before := getProduct()
+
+		if err := importProducts(ctx, testDb, "testdata/product/upsert_update.csv", opts); err != nil {
+			t.Fatalf("expected nil error, got = %v", err)
+		}
+		after := getProduct()
+
+		if after.ID != before.ID {
+			t.Fatalf("expected product id to be kept, got = %s, want = %s", after.ID.Hex(), before.ID.Hex())
+		}
+		if after.Description_ID != "deskripsi baru" {
+			t.Fatalf("expected product description ID to be updated, got = %s", after.Description_ID)
+		}
+		if len(after.Variants) != 3 {
+			t.Fatalf("expected 3 variants, got = %d", len(after.Variants))
+		}
+		for i, v := range before.Variants {
+			if after.Variants[i].ID != v.ID {
+				t.Fatalf("expected variant id to be kept, got = %s, want = %s", after.Variants[i].ID.Hex(), v.ID.Hex())
+			}
+		}
+		if after.Variants[0].Barcode != "222211" || after.Variants[0].MaximumOrder != 10 {
+			t.Fatalf("expected variant to be updated, got = %+v", after.Variants[0])
+		}
+		if after.Variants[1].VariantStatus != prpb.VariantStatus_VARIANT_STATUS_DISABLED {
+			t.Fatalf("expected missing variant to be disabled, got = %v", after.Variants[1].VariantStatus)
+		}
+	})
+
 	// failed scenarios
 	tests := []struct {
 		name    string
@@ -78,37 +171,47 @@ func TestImportProducts(t *testing.T) {
 		{
 			name:    "EmptyProductNameEN",
 			path:    "testdata/product/empty_product_name_en.csv",
-			wantErr: errProductNameENIsRequired,
+			wantErr: validation.ErrProductNameENIsRequired,
 		},
 		{
 			name:    "EmptyProductNameID",
 			path:    "testdata/product/empty_product_name_id.csv",
-			wantErr: errProductNameIDIsRequired,
+			wantErr: validation.ErrProductNameIDIsRequired,
 		},
 		{
 			name:    "EmptyShoptreeVariantID",
 			path:    "testdata/product/empty_shoptree_variant_id.csv",
-			wantErr: errShoptreeVariantIDIsRequired,
+			wantErr: validation.ErrShoptreeVariantIDIsRequired,
 		},
 		{
 			name:    "EmptyVariantValue",
 			path:    "testdata/product/empty_variant_value.csv",
-			wantErr: errVariantValueIsRequired,
+			wantErr: validation.ErrVariantValueIsRequired,
 		},
 		{
 			name:    "EmptyVariantQuantifierEN",
 			path:    "testdata/product/empty_variant_quantifier_en.csv",
-			wantErr: errVariantQuantifierENIsRequired,
+			wantErr: validation.ErrVariantQuantifierENIsRequired,
 		},
 		{
 			name:    "EmptyVariantQuantifierID",
 			path:    "testdata/product/empty_variant_quantifier_id.csv",
-			wantErr: errVariantQuantifierIDIsRequired,
+			wantErr: validation.ErrVariantQuantifierIDIsRequired,
 		},
 		{
 			name:    "EmptySKU",
 			path:    "testdata/product/empty_sku.csv",
-			wantErr: errSKUIsRequired,
+			wantErr: validation.ErrSKUIsRequired,
+		},
+		{
+			name:    "VariantQuantifierNotAllowed",
+			path:    "testdata/product/variant_quantifier_not_allowed.csv",
+			wantErr: validation.ErrVariantQuantifierNotAllowed,
+		},
+		{
+			name:    "MissingHeaders",
+			path:    "testdata/product/missing_headers.csv",
+			wantErr: errMissingHeaders,
 		},
 	}
 
@@ -117,10 +220,43 @@ func TestImportProducts(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 
-			err := importProducts(ctx, testDb, test.path)
+			err := importProducts(ctx, testDb, test.path, importOptions{mode: modeInsert})
 			if !errors.Is(errors.Unwrap(err), test.wantErr) {
 				t.Fatalf("importProducts(_, _) error, got = %v, want = %v", err, test.wantErr)
 			}
 		})
 	}
 }
+
+func TestImportProductsDryRunDefaults(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		defaultTestTimeout,
+	)
+	t.Cleanup(cancel)
+
+	// an empty database has neither the default brand nor the UOM variant type,
+	// a dry run must report them without inserting them.
+	db := testDb.Client().Database(testDb.Name() + "-dry-run")
+	t.Cleanup(func() {
+		if err := db.Drop(context.Background()); err != nil {
+			t.Errorf("unexpected error, got = %v", err)
+		}
+	})
+
+	// the import fails since the database has no categories, the default
+	// brand and variant type are looked up when the header is read.
+	_ = importProducts(ctx, db, "testdata/product/success.csv", importOptions{mode: modeInsert, dryRun: true})
+
+	for _, collection := range []string{brandCollection, variantTypeCollection} {
+		n, err := db.Collection(collection).CountDocuments(ctx, bson.M{})
+		if err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if n != 0 {
+			t.Errorf("expected nothing to be written to %s on dry run, got = %d", collection, n)
+		}
+	}
+}
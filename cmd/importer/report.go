@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// importMode defines how imported rows are written to the database.
+type importMode string
+
+const (
+	// modeInsert inserts every row as a new document.
+	modeInsert importMode = "insert"
+	// modeUpsert matches rows with existing documents, keeping their ids.
+	modeUpsert importMode = "upsert"
+)
+
+// parseImportMode validates the mode flag value.
+func parseImportMode(mode string) (importMode, error) {
+	switch m := importMode(mode); m {
+	case modeInsert, modeUpsert:
+		return m, nil
+	}
+	return "", fmt.Errorf("invalid mode: %s, valid modes: %s, %s", mode, modeInsert, modeUpsert)
+}
+
+// importOptions holds the flags shared by import operations.
+type importOptions struct {
+	mode importMode
+	// disableMissingVariants disables existing product variants missing from
+	// the imported file, only used on upsert mode.
+	disableMissingVariants bool
+	// dryRun performs all the parsing, lookups and validation without
+	// writing to the database.
+	dryRun bool
+	// reportPath is the optional path of the JSON change report.
+	reportPath string
+	// errorsReportPath is the optional path of the CSV or JSON row errors report.
+	errorsReportPath string
+	// batchSize is the number of write models sent on each BulkWrite.
+	batchSize int
+	// headerAliases maps alternative header names to the header names known by the importers.
+	headerAliases headerAliases
+	// format is the format of the imported file, detected from its extension when empty.
+	format fileFormat
+	// sheet is the worksheet read from XLSX files, defaults to the first sheet.
+	sheet string
+	// transaction runs the import inside a transaction when mongo supports them.
+	transaction bool
+	// actor is the person running the import, recorded on the run and the audit log.
+	actor string
+	// run journals the written documents, nil when the import is not run by runImport.
+	run *importRun
+}
+
+// changeKind describes what an import did to a document.
+type changeKind string
+
+const (
+	changeCreated   changeKind = "created"
+	changeUpdated   changeKind = "updated"
+	changeUnchanged changeKind = "unchanged"
+)
+
+// change is a single document change made by an import.
+type change struct {
+	Kind changeKind `json:"kind"`
+	Key  string     `json:"key"`
+	// Fields are the bson names of the updated fields.
+	Fields []string `json:"fields,omitempty"`
+}
+
+// importReport collects the changes made by an import operation.
+type importReport struct {
+	entity  string
+	changes []change
+}
+
+func newImportReport(entity string) *importReport {
+	return &importReport{entity: entity}
+}
+
+// add records a change on the document identified by key.
+func (r *importReport) add(kind changeKind, key string, fields ...string) {
+	r.changes = append(r.changes, change{Kind: kind, Key: key, Fields: fields})
+}
+
+// addDiff records an update with the changed fields, or an unchanged
+// document when there are no changed fields.
+func (r *importReport) addDiff(key string, fields []string) {
+	if len(fields) < 1 {
+		r.add(changeUnchanged, key)
+		return
+	}
+	r.add(changeUpdated, key, fields...)
+}
+
+// count returns the number of changes of kind.
+func (r *importReport) count(kind changeKind) int {
+	var n int
+	for _, c := range r.changes {
+		if c.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// print logs the created and updated documents followed by the summary of all changes.
+func (r *importReport) print(dryRun bool) {
+	prefix := ""
+	if dryRun {
+		prefix = "[dry run] "
+	}
+	for _, c := range r.changes {
+		switch c.Kind {
+		case changeCreated:
+			log.Printf("%s%s %s: %s", prefix, c.Kind, r.entity, c.Key)
+		case changeUpdated:
+			log.Printf("%s%s %s: %s (%s)", prefix, c.Kind, r.entity, c.Key, strings.Join(c.Fields, ", "))
+		}
+	}
+	log.Printf("%s%s: %d created, %d updated, %d unchanged", prefix,
+		r.entity, r.count(changeCreated), r.count(changeUpdated), r.count(changeUnchanged))
+}
+
+// finish prints the report and writes it as JSON when a report path is given.
+func (r *importReport) finish(opts importOptions) error {
+	r.print(opts.dryRun)
+	if opts.reportPath == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(struct {
+		Entity    string   `json:"entity"`
+		DryRun    bool     `json:"dry_run"`
+		Created   int      `json:"created"`
+		Updated   int      `json:"updated"`
+		Unchanged int      `json:"unchanged"`
+		Changes   []change `json:"changes"`
+	}{
+		Entity:    r.entity,
+		DryRun:    opts.dryRun,
+		Created:   r.count(changeCreated),
+		Updated:   r.count(changeUpdated),
+		Unchanged: r.count(changeUnchanged),
+		Changes:   r.changes,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s report: %w", r.entity, err)
+	}
+	if err := os.WriteFile(filepath.Clean(opts.reportPath), b, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s report: %w", r.entity, err)
+	}
+	return nil
+}
+
+// changedFields compares the struct fields of old and new, which must be of the
+// same type, and returns the bson names of the fields with different values.
+// When fields are given, only those fields are compared.
+func changedFields(old, new interface{}, fields ...string) []string {
+	include := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		include[f] = true
+	}
+
+	oldValue := reflect.Indirect(reflect.ValueOf(old))
+	newValue := reflect.Indirect(reflect.ValueOf(new))
+
+	var changed []string
+	for i := 0; i < oldValue.NumField(); i++ {
+		name, _, _ := strings.Cut(oldValue.Type().Field(i).Tag.Get("bson"), ",")
+		if name == "" || name == "-" || (len(include) > 0 && !include[name]) {
+			continue
+		}
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,562 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/dropezy/storefront-backend/ems-api/services/audit"
+)
+
+const (
+	importRunCollection       = "import_run"
+	importRunChangeCollection = "import_run_change"
+
+	// finishTimeout bounds the rollback and the final status update of a run, which
+	// don't use the import context so they still happen once the import is cancelled.
+	finishTimeout = 30 * time.Minute
+)
+
+var (
+	errInvalidRunID          = errors.New("invalid import run id")
+	errRunNotFound           = errors.New("import run not found")
+	errRunNotApplied         = errors.New("import run is not applied, failed or still running")
+	errRunNotLatest          = errors.New("import run has been followed by other runs on the same collections")
+	errUnsupportedWriteModel = errors.New("unsupported write model")
+	errDocumentChanged       = errors.New("document has been changed since the import run")
+)
+
+// runStatus is the status of an import run.
+type runStatus string
+
+const (
+	runRunning    runStatus = "running"
+	runApplied    runStatus = "applied"
+	runFailed     runStatus = "failed"
+	runRolledBack runStatus = "rolled_back"
+	runUndone     runStatus = "undone"
+)
+
+// importFunc imports the file on path into db.
+type importFunc func(ctx context.Context, db *mongo.Database, path string, opts importOptions) error
+
+// importRun is a single run of the importer, every document it writes is journaled
+// as a runChange so the run can be rolled back or undone.
+type importRun struct {
+	ID            primitive.ObjectID `bson:"_id"`
+	Operation     string             `bson:"operation"`
+	Path          string             `bson:"path"`
+	Transactional bool               `bson:"transactional"`
+	Status        runStatus          `bson:"status"`
+	// Actor is the person who ran the import.
+	Actor string `bson:"actor,omitempty"`
+	// Collections are the collections written by the run.
+	Collections []string   `bson:"collections,omitempty"`
+	StartedAt   time.Time  `bson:"started_at"`
+	FinishedAt  *time.Time `bson:"finished_at,omitempty"`
+}
+
+// runChange is the state of a document before and after it was written by an import run.
+type runChange struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	RunID      primitive.ObjectID `bson:"run_id"`
+	Collection string             `bson:"collection"`
+	// Filter identifies the written document.
+	Filter bson.M `bson:"filter"`
+	// Before is the document before the run, empty when it was created by the run.
+	Before bson.Raw `bson:"before,omitempty"`
+	// After is the document once its batch was written, empty when the write failed.
+	// Documents which no longer match After have been changed since the run and
+	// are left untouched when the run is reverted.
+	After bson.Raw `bson:"after,omitempty"`
+	// Undone tells whether the document has been reverted, so an interrupted
+	// revert can be resumed.
+	Undone bool `bson:"undone,omitempty"`
+}
+
+// runImport runs fn journaling every written document. When mongo supports transactions
+// the whole run is executed inside a single transaction, so it either fully applies or
+// leaves the database unchanged. Otherwise the written documents are rolled back from
+// the run journal when fn fails. Once applied the run can still be reverted with undoRun.
+func runImport(ctx context.Context, db *mongo.Database, operation, path string, opts importOptions, fn importFunc) error {
+	// nothing is written on dry run.
+	if opts.dryRun {
+		return fn(ctx, db, path, opts)
+	}
+
+	transactional := false
+	if opts.transaction {
+		supported, err := supportsTransactions(ctx, db)
+		if err != nil {
+			return err
+		}
+		if !supported {
+			log.Print("transactions are not supported by the mongo deployment, failed imports are rolled back from the run journal")
+		}
+		transactional = supported
+	}
+
+	run := &importRun{
+		ID:            primitive.NewObjectID(),
+		Operation:     operation,
+		Path:          path,
+		Transactional: transactional,
+		Status:        runRunning,
+		Actor:         opts.actor,
+		StartedAt:     time.Now().UTC(),
+	}
+	if _, err := db.Collection(importRunCollection).InsertOne(ctx, run); err != nil {
+		return fmt.Errorf("failed to execute InsertOne import run: %w", err)
+	}
+	opts.run = run
+
+	// the journal is written inside the transaction as well, so it only holds
+	// the documents of committed runs.
+	err := inTransaction(ctx, db, transactional, func(ctx context.Context) error {
+		return fn(ctx, db, path, opts)
+	})
+
+	// the run is finished on a context of its own, so it's rolled back and
+	// recorded even when the import failed because ctx is done.
+	finishCtx, cancel := context.WithTimeout(context.Background(), finishTimeout)
+	defer cancel()
+
+	if err != nil {
+		status := runFailed
+		// aborted transactions left nothing to roll back.
+		if _, rbErr := undoChanges(finishCtx, db, run.ID, transactional); rbErr != nil {
+			log.Printf("failed to roll back import run: %s, undo it with -undo=%s, error: %v", run.ID.Hex(), run.ID.Hex(), rbErr)
+		} else {
+			status = runRolledBack
+		}
+		if err := finishRun(finishCtx, db, run.ID, status); err != nil {
+			log.Print(err)
+		}
+		auditRun(finishCtx, db, "importer/"+operation, opts.actor, run.ID, nil)
+		return err
+	}
+
+	if err := finishRun(finishCtx, db, run.ID, runApplied); err != nil {
+		return err
+	}
+	auditRun(finishCtx, db, "importer/"+operation, opts.actor, run.ID, nil)
+	log.Printf("import run: %s applied, it can be reverted with -undo=%s", run.ID.Hex(), run.ID.Hex())
+	return nil
+}
+
+// undoRun reverts the documents written by the run with runID, the run must be either
+// applied, failed without being rolled back or interrupted while running. It must also
+// be the latest run on the collections it wrote, so later runs are not clobbered.
+// Documents changed since the run are left untouched and logged.
+// actor is the person undoing the run.
+func undoRun(ctx context.Context, db *mongo.Database, runID, actor string) error {
+	id, err := primitive.ObjectIDFromHex(runID)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errInvalidRunID, runID)
+	}
+
+	run := &importRun{}
+	if err := db.Collection(importRunCollection).FindOne(ctx, bson.M{"_id": id}).Decode(run); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("%w: %s", errRunNotFound, runID)
+		}
+		return fmt.Errorf("failed to execute FindOne import run: %w", err)
+	}
+	if !undoable(run.Status) {
+		return fmt.Errorf("%w: %s, status: %s", errRunNotApplied, runID, run.Status)
+	}
+
+	if len(run.Collections) > 0 {
+		later, err := db.Collection(importRunCollection).CountDocuments(ctx, bson.M{
+			"_id":         bson.M{"$gt": id},
+			"status":      bson.M{"$in": bson.A{runApplied, runFailed, runRunning}},
+			"collections": bson.M{"$in": run.Collections},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute CountDocuments import run: %w", err)
+		}
+		if later > 0 {
+			return fmt.Errorf("%w: %s", errRunNotLatest, runID)
+		}
+	}
+
+	supported, err := supportsTransactions(ctx, db)
+	if err != nil {
+		return err
+	}
+	skipped, err := undoChanges(ctx, db, id, supported)
+	if err != nil {
+		return err
+	}
+
+	if err := finishRun(ctx, db, id, runUndone); err != nil {
+		return err
+	}
+	auditRun(ctx, db, "importer/undo", actor, id, run)
+	if skipped > 0 {
+		log.Printf("import run: %s undone, %d documents changed since the run were left untouched", runID, skipped)
+		return nil
+	}
+	log.Printf("import run: %s undone", runID)
+	return nil
+}
+
+// undoable tells whether a run with status may still have written documents to revert.
+func undoable(status runStatus) bool {
+	switch status {
+	case runApplied, runFailed, runRunning:
+		return true
+	}
+	return false
+}
+
+// journal records the current state of the documents about to be written by models,
+// it must be called before the models are written and returns the recorded changes,
+// a document written by several models is only recorded once. Nothing is recorded
+// without a run.
+func (r *importRun) journal(ctx context.Context, collection *mongo.Collection, models []mongo.WriteModel) ([]*runChange, error) {
+	if r == nil || len(models) < 1 {
+		return nil, nil
+	}
+
+	changes := make([]*runChange, 0, len(models))
+	// upserts tells whether the update model of a change is an upsert, by change id.
+	upserts := map[primitive.ObjectID]bool{}
+	// values of the filters of update models grouped by the filter key,
+	// so their current documents are fetched with one query per key.
+	updated := map[string][]interface{}{}
+	// seen are the filter keys of the documents already recorded.
+	seen := map[string]bool{}
+	for _, m := range models {
+		c := &runChange{
+			ID:         primitive.NewObjectID(),
+			RunID:      r.ID,
+			Collection: collection.Name(),
+		}
+		switch m := m.(type) {
+		case *mongo.InsertOneModel:
+			raw, err := bson.Marshal(m.Document)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal inserted document: %w", err)
+			}
+			c.Filter = bson.M{"_id": bson.Raw(raw).Lookup("_id")}
+		case *mongo.UpdateOneModel:
+			filter, ok := m.Filter.(bson.M)
+			if !ok || len(filter) != 1 {
+				return nil, fmt.Errorf("%w: update filter must have a single field, got: %v", errUnsupportedWriteModel, m.Filter)
+			}
+			c.Filter = filter
+			upserts[c.ID] = m.Upsert != nil && *m.Upsert
+		default:
+			return nil, fmt.Errorf("%w: %T", errUnsupportedWriteModel, m)
+		}
+
+		key, err := changeKey(c)
+		if err != nil {
+			return nil, err
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if _, ok := upserts[c.ID]; ok {
+			for key, value := range c.Filter {
+				updated[key] = append(updated[key], value)
+			}
+		}
+		changes = append(changes, c)
+	}
+
+	// the current documents indexed by the filter key and value.
+	before, err := findByFilters(ctx, collection, updated)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]interface{}, 0, len(changes))
+	journaled := make([]*runChange, 0, len(changes))
+	for _, c := range changes {
+		if upsert, ok := upserts[c.ID]; ok {
+			key, err := changeKey(c)
+			if err != nil {
+				return nil, err
+			}
+			c.Before = before[key]
+			// updates without a current document and upsert don't write anything.
+			if len(c.Before) < 1 && !upsert {
+				continue
+			}
+		}
+		docs = append(docs, c)
+		journaled = append(journaled, c)
+	}
+	if len(docs) < 1 {
+		return nil, nil
+	}
+	if _, err := collection.Database().Collection(importRunChangeCollection).InsertMany(ctx, docs); err != nil {
+		return nil, fmt.Errorf("failed to execute InsertMany import run changes: %w", err)
+	}
+
+	_, err = collection.Database().Collection(importRunCollection).UpdateOne(ctx,
+		bson.M{"_id": r.ID},
+		bson.M{"$addToSet": bson.M{"collections": collection.Name()}},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute UpdateOne import run: %w", err)
+	}
+	return journaled, nil
+}
+
+// snapshot records the state of the documents of changes once their batch has been
+// written, it must be called after the models journaled as changes are written.
+func (r *importRun) snapshot(ctx context.Context, collection *mongo.Collection, changes []*runChange) error {
+	if r == nil || len(changes) < 1 {
+		return nil
+	}
+
+	values := map[string][]interface{}{}
+	for _, c := range changes {
+		for key, value := range c.Filter {
+			values[key] = append(values[key], value)
+		}
+	}
+	after, err := findByFilters(ctx, collection, values)
+	if err != nil {
+		return err
+	}
+
+	models := make([]mongo.WriteModel, 0, len(changes))
+	for _, c := range changes {
+		key, err := changeKey(c)
+		if err != nil {
+			return err
+		}
+		c.After = after[key]
+		if len(c.After) < 1 {
+			continue
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": c.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"after": c.After}}))
+	}
+	if len(models) < 1 {
+		return nil
+	}
+	if _, err := collection.Database().Collection(importRunChangeCollection).BulkWrite(ctx, models); err != nil {
+		return fmt.Errorf("failed to execute BulkWrite import run changes: %w", err)
+	}
+	return nil
+}
+
+// findByFilters fetches the documents of collection matching the values of the filter
+// keys, with one query per key. Documents are indexed by their filterKey.
+func findByFilters(ctx context.Context, collection *mongo.Collection, values map[string][]interface{}) (map[string]bson.Raw, error) {
+	docs := map[string]bson.Raw{}
+	for key, keyValues := range values {
+		cur, err := collection.Find(ctx, bson.M{key: bson.M{"$in": keyValues}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute Find %s, on journal import run: %w", collection.Name(), err)
+		}
+		for cur.Next(ctx) {
+			doc := make(bson.Raw, len(cur.Current))
+			copy(doc, cur.Current)
+			value := doc.Lookup(key)
+			docs[filterKey(key, value.Type, value.Value)] = doc
+		}
+		if err := cur.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read %s, on journal import run: %w", collection.Name(), err)
+		}
+		if err := cur.Close(ctx); err != nil {
+			return nil, fmt.Errorf("failed to close %s cursor: %w", collection.Name(), err)
+		}
+	}
+	return docs, nil
+}
+
+// changeKey is the filterKey of the single field filter of c.
+func changeKey(c *runChange) (string, error) {
+	for key, value := range c.Filter {
+		t, data, err := bson.MarshalValue(value)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal filter value: %w", err)
+		}
+		return filterKey(key, t, data), nil
+	}
+	return "", nil
+}
+
+// filterKey is the key of a filter field and its marshaled value.
+func filterKey(key string, t bsontype.Type, data []byte) string {
+	return key + "\x00" + string(t) + string(data)
+}
+
+// undoChanges reverts the documents journaled by the run with runID on reverse order,
+// documents created by the run are deleted and the others are restored. Each document
+// is reverted inside a transaction of its own when transactional is set. Documents
+// changed since the run wrote them are left untouched and logged, it returns their count.
+func undoChanges(ctx context.Context, db *mongo.Database, runID primitive.ObjectID, transactional bool) (int, error) {
+	cur, err := db.Collection(importRunChangeCollection).Find(ctx,
+		bson.M{"run_id": runID, "undone": bson.M{"$ne": true}},
+		options.Find().SetSort(bson.M{"_id": -1}),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute Find import run changes: %w", err)
+	}
+	defer cur.Close(ctx)
+
+	skipped := 0
+	for cur.Next(ctx) {
+		c := &runChange{}
+		if err := cur.Decode(c); err != nil {
+			return skipped, fmt.Errorf("failed to decode import run change: %w", err)
+		}
+
+		err := inTransaction(ctx, db, transactional, func(ctx context.Context) error {
+			return undoChange(ctx, db, c)
+		})
+		if errors.Is(err, errDocumentChanged) {
+			skipped++
+			log.Printf("%s document: %v has been changed since import run: %s, it is left untouched", c.Collection, c.Filter, runID.Hex())
+			continue
+		}
+		if err != nil {
+			return skipped, err
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return skipped, fmt.Errorf("failed to read import run changes: %w", err)
+	}
+	return skipped, nil
+}
+
+// undoChange reverts the document of c and marks c as undone, it returns errDocumentChanged
+// when the document no longer matches the document written by the run. Changes journaled
+// without the document after the write are reverted without being checked.
+func undoChange(ctx context.Context, db *mongo.Database, c *runChange) error {
+	collection := db.Collection(c.Collection)
+	if len(c.After) > 0 {
+		current, err := collection.FindOne(ctx, c.Filter).DecodeBytes()
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("failed to execute FindOne %s, on undo import run: %w", c.Collection, err)
+		}
+		if !bytes.Equal(current, c.After) {
+			return errDocumentChanged
+		}
+	}
+
+	if len(c.Before) < 1 {
+		if _, err := collection.DeleteOne(ctx, c.Filter); err != nil {
+			return fmt.Errorf("failed to execute DeleteOne %s, on undo import run: %w", c.Collection, err)
+		}
+	} else {
+		_, err := collection.ReplaceOne(ctx,
+			bson.M{"_id": c.Before.Lookup("_id")},
+			c.Before,
+			options.Replace().SetUpsert(true),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to execute ReplaceOne %s, on undo import run: %w", c.Collection, err)
+		}
+	}
+
+	_, err := db.Collection(importRunChangeCollection).UpdateOne(ctx,
+		bson.M{"_id": c.ID},
+		bson.M{"$set": bson.M{"undone": true}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute UpdateOne import run change: %w", err)
+	}
+	return nil
+}
+
+// finishRun sets the final status of the run with id.
+func finishRun(ctx context.Context, db *mongo.Database, id primitive.ObjectID, status runStatus) error {
+	_, err := db.Collection(importRunCollection).UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"status": status, "finished_at": time.Now().UTC()}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute UpdateOne import run: %w", err)
+	}
+	return nil
+}
+
+// auditRun writes the audit entry of the import run with id, or of its undo when before
+// is the run before it was undone. The entry references the run, whose journal holds
+// the documents written by the run. Failures are logged since the run is already over.
+func auditRun(ctx context.Context, db *mongo.Database, operation, actor string, id primitive.ObjectID, before *importRun) {
+	after := &importRun{}
+	if err := db.Collection(importRunCollection).FindOne(ctx, bson.M{"_id": id}).Decode(after); err != nil {
+		log.Printf("failed to fetch import run: %s for the audit log, error: %v", id.Hex(), err)
+		return
+	}
+
+	var beforeSnapshot interface{}
+	if before != nil {
+		beforeSnapshot = before
+	}
+	c, err := audit.NewChange(audit.EntityImportRun, id.Hex(), beforeSnapshot, after)
+	if err != nil {
+		log.Printf("failed to snapshot import run: %s for the audit log, error: %v", id.Hex(), err)
+		return
+	}
+
+	e := &audit.Entry{
+		ID:        primitive.NewObjectID(),
+		Principal: &audit.Principal{Name: actor},
+		Operation: operation,
+		Code:      string(after.Status),
+		Changes:   []*audit.Change{c},
+		Timestamp: time.Now().UTC(),
+	}
+	if err := audit.NewMongoStore(db).CreateEntry(ctx, e); err != nil {
+		log.Printf("failed to write audit entry of import run: %s, error: %v", id.Hex(), err)
+	}
+}
+
+// supportsTransactions reports whether the mongo deployment is a replica set or a sharded
+// cluster, standalone servers don't support multi-document transactions.
+func supportsTransactions(ctx context.Context, db *mongo.Database) (bool, error) {
+	var res struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := db.RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&res); err != nil {
+		return false, fmt.Errorf("failed to execute isMaster command: %w", err)
+	}
+	return res.SetName != "" || res.Msg == "isdbgrid", nil
+}
+
+// withTransaction runs fn inside a transaction, which is aborted when fn fails.
+func withTransaction(ctx context.Context, db *mongo.Database, fn func(sc mongo.SessionContext) error) error {
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start mongo session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// inTransaction runs fn inside a transaction when transactional is set, otherwise fn
+// is run on ctx.
+func inTransaction(ctx context.Context, db *mongo.Database, transactional bool, fn func(ctx context.Context) error) error {
+	if !transactional {
+		return fn(ctx)
+	}
+	return withTransaction(ctx, db, func(sc mongo.SessionContext) error {
+		return fn(sc)
+	})
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/brand"
+
+	"github.com/dropezy/storefront-backend/ems-api/services/audit"
+)
+
+func TestImportRunUndo(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		defaultTestTimeout,
+	)
+	t.Cleanup(cancel)
+
+	// runs are not parallel, since a run can only be undone while it's the latest run on brands.
+	tests := []struct {
+		name        string
+		path        string
+		transaction bool
+		updatedSlug string
+		createdSlug string
+	}{
+		{
+			name:        "Journal",
+			path:        "testdata/run/brands.csv",
+			updatedSlug: "run-updated",
+			createdSlug: "run-created",
+		},
+		{
+			name:        "Transaction",
+			path:        "testdata/run/brands_transaction.csv",
+			transaction: true,
+			updatedSlug: "run-transaction-updated",
+			createdSlug: "run-transaction-created",
+		},
+	}
+
+	collection := testDb.Collection(brandCollection)
+	for _, test := range tests {
+		existing := &brand.Brand{
+			ID:      primitive.NewObjectID(),
+			Name:    "Run Existing",
+			LogoURL: "https://i.imgur.com/run-existing.png",
+			Slug:    test.updatedSlug,
+		}
+		if _, err := collection.InsertOne(ctx, existing); err != nil {
+			t.Fatalf("%s: unexpected error, got = %v", test.name, err)
+		}
+
+		opts := importOptions{transaction: test.transaction, actor: "ops"}
+		if err := runImport(ctx, testDb, operationBrand, test.path, opts, importBrands); err != nil {
+			t.Fatalf("%s: runImport(_, _) error, got = %v", test.name, err)
+		}
+
+		run := &importRun{}
+		if err := testDb.Collection(importRunCollection).
+			FindOne(ctx, bson.M{"path": test.path}).
+			Decode(run); err != nil {
+			t.Fatalf("%s: unexpected error, got = %v", test.name, err)
+		}
+		if run.Status != runApplied {
+			t.Fatalf("%s: run status, got = %s, want = %s", test.name, run.Status, runApplied)
+		}
+
+		if err := undoRun(ctx, testDb, run.ID.Hex(), "ops"); err != nil {
+			t.Fatalf("%s: undoRun(_, _) error, got = %v", test.name, err)
+		}
+
+		restored := &brand.Brand{}
+		if err := collection.FindOne(ctx, bson.M{"_id": existing.ID}).Decode(restored); err != nil {
+			t.Fatalf("%s: unexpected error, got = %v", test.name, err)
+		}
+		if restored.Name != existing.Name || restored.LogoURL != existing.LogoURL {
+			t.Fatalf("%s: restored brand, got = %+v, want = %+v", test.name, restored, existing)
+		}
+		err := collection.FindOne(ctx, bson.M{"slug": test.createdSlug}).Err()
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			t.Fatalf("%s: created brand, got = %v, want = %v", test.name, err, mongo.ErrNoDocuments)
+		}
+
+		if err := undoRun(ctx, testDb, run.ID.Hex(), "ops"); !errors.Is(err, errRunNotApplied) {
+			t.Fatalf("%s: undoRun(_, _) error, got = %v, want = %v", test.name, err, errRunNotApplied)
+		}
+
+		// the run and its undo are audited, from the most recent.
+		entries, err := audit.NewMongoStore(testDb).ListEntries(ctx, &audit.ListFilter{
+			Entity:   audit.EntityImportRun,
+			EntityID: run.ID.Hex(),
+			Limit:    10,
+		})
+		if err != nil {
+			t.Fatalf("%s: unexpected error, got = %v", test.name, err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("%s: audit entries, got = %d, want = 2", test.name, len(entries))
+		}
+		wantOperations := []string{"importer/undo", "importer/" + operationBrand}
+		wantCodes := []string{string(runUndone), string(runApplied)}
+		for idx, e := range entries {
+			if e.Operation != wantOperations[idx] || e.Code != wantCodes[idx] || e.Principal.Name != "ops" {
+				t.Fatalf("%s: audit entry, got = %s %s by %s, want = %s %s by ops",
+					test.name, e.Operation, e.Code, e.Principal.Name, wantOperations[idx], wantCodes[idx])
+			}
+		}
+	}
+
+	// failed runs can be undone, documents changed since the run are left untouched.
+	t.Run("FailedRunChangedDocument", func(t *testing.T) {
+		existing := &brand.Brand{
+			ID:      primitive.NewObjectID(),
+			Name:    "Run Changed Existing",
+			LogoURL: "https://i.imgur.com/run-changed-existing.png",
+			Slug:    "run-changed-updated",
+		}
+		if _, err := collection.InsertOne(ctx, existing); err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+
+		path := "testdata/run/brands_changed.csv"
+		if err := runImport(ctx, testDb, operationBrand, path, importOptions{actor: "ops"}, importBrands); err != nil {
+			t.Fatalf("runImport(_, _) error, got = %v", err)
+		}
+		run := &importRun{}
+		if err := testDb.Collection(importRunCollection).FindOne(ctx, bson.M{"path": path}).Decode(run); err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if err := finishRun(ctx, testDb, run.ID, runFailed); err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+
+		// the created brand is edited through the API after the run.
+		const editedLogo = "https://i.imgur.com/run-changed-api.png"
+		if _, err := collection.UpdateOne(ctx,
+			bson.M{"slug": "run-changed-edited"},
+			bson.M{"$set": bson.M{"logo_url": editedLogo}},
+		); err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+
+		if err := undoRun(ctx, testDb, run.ID.Hex(), "ops"); err != nil {
+			t.Fatalf("undoRun(_, _) error, got = %v", err)
+		}
+
+		restored := &brand.Brand{}
+		if err := collection.FindOne(ctx, bson.M{"_id": existing.ID}).Decode(restored); err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if restored.Name != existing.Name {
+			t.Fatalf("restored brand, got = %+v, want = %+v", restored, existing)
+		}
+		if err := collection.FindOne(ctx, bson.M{"slug": "run-changed-created"}).Err(); !errors.Is(err, mongo.ErrNoDocuments) {
+			t.Fatalf("created brand, got = %v, want = %v", err, mongo.ErrNoDocuments)
+		}
+		edited := &brand.Brand{}
+		if err := collection.FindOne(ctx, bson.M{"slug": "run-changed-edited"}).Decode(edited); err != nil {
+			t.Fatalf("expected edited brand to be kept, got = %v", err)
+		}
+		if edited.LogoURL != editedLogo {
+			t.Fatalf("edited brand logo, got = %s, want = %s", edited.LogoURL, editedLogo)
+		}
+	})
+
+	// the whole run is written inside a single transaction on replica sets,
+	// along with its journal.
+	t.Run("TransactionBatches", func(t *testing.T) {
+		supported, err := supportsTransactions(ctx, testDb)
+		if err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if !supported {
+			t.Skip("transactions are not supported by the mongo deployment, a replica set is required")
+		}
+
+		path := "testdata/run/brands_batches.csv"
+		opts := importOptions{transaction: true, batchSize: 1, actor: "ops"}
+		if err := runImport(ctx, testDb, operationBrand, path, opts, importBrands); err != nil {
+			t.Fatalf("runImport(_, _) error, got = %v", err)
+		}
+		run := &importRun{}
+		if err := testDb.Collection(importRunCollection).FindOne(ctx, bson.M{"path": path}).Decode(run); err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if !run.Transactional || run.Status != runApplied {
+			t.Fatalf("run, got = %+v, want transactional and %s", run, runApplied)
+		}
+
+		var changes []*runChange
+		cur, err := testDb.Collection(importRunChangeCollection).Find(ctx, bson.M{"run_id": run.ID})
+		if err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if err := cur.All(ctx, &changes); err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if len(changes) != 3 {
+			t.Fatalf("expected 3 journaled changes, got = %d", len(changes))
+		}
+		for _, c := range changes {
+			if len(c.After) < 1 {
+				t.Fatalf("expected change %s to have the written document", c.ID.Hex())
+			}
+		}
+
+		if err := undoRun(ctx, testDb, run.ID.Hex(), "ops"); err != nil {
+			t.Fatalf("undoRun(_, _) error, got = %v", err)
+		}
+		n, err := collection.CountDocuments(ctx, bson.M{"slug": bson.M{"$regex": "^run-batch-"}})
+		if err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if n != 0 {
+			t.Fatalf("expected created brands to be deleted, got = %d", n)
+		}
+	})
+
+	// the run is rolled back and finished even when the import context is done.
+	t.Run("CancelledImport", func(t *testing.T) {
+		runCtx, runCancel := context.WithCancel(ctx)
+		defer runCancel()
+
+		path := "testdata/run/brands_cancelled.csv"
+		fn := func(ctx context.Context, db *mongo.Database, path string, opts importOptions) error {
+			if err := importBrands(ctx, db, path, opts); err != nil {
+				return err
+			}
+			runCancel()
+			return ctx.Err()
+		}
+		if err := runImport(runCtx, testDb, operationBrand, path, importOptions{actor: "ops"}, fn); !errors.Is(err, context.Canceled) {
+			t.Fatalf("runImport(_, _) error, got = %v, want = %v", err, context.Canceled)
+		}
+
+		run := &importRun{}
+		if err := testDb.Collection(importRunCollection).FindOne(ctx, bson.M{"path": path}).Decode(run); err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if run.Status != runRolledBack {
+			t.Fatalf("run status, got = %s, want = %s", run.Status, runRolledBack)
+		}
+		err := collection.FindOne(ctx, bson.M{"slug": "run-cancelled-created"}).Err()
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			t.Fatalf("created brand, got = %v, want = %v", err, mongo.ErrNoDocuments)
+		}
+	})
+}
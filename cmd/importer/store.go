@@ -4,50 +4,277 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/dropezy/storefront-backend/internal/storage/model/darkstore"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/darkstore"
+
+	"github.com/dropezy/storefront-backend/ems-api/validation"
 )
 
 const darkstoreCollection = "store"
 
 var (
 	errStoreNotFound = errors.New("no stores found in our database")
+
+	errStoreInvalidLatitude       = errors.New("store latitude is not a number")
+	errStoreInvalidLongitude      = errors.New("store longitude is not a number")
+	errStoreInvalidOpeningHours   = errors.New("store opening hours format is invalid")
+	errStoreDuplicateLocationCode = errors.New("store location code is shown on more than one row")
 )
 
-func importStore(ctx context.Context, db *mongo.Database, path string) error {
-	// check if stores exist in our database
-	darkstores, err := getStores(ctx, db)
-	if err != nil && !errors.Is(err, errStoreNotFound) {
+// storeColumns maps store validation fields to the stores file columns.
+var storeColumns = map[string]string{
+	"name":                 "store_name",
+	"location_code":        "location_code",
+	"shoptree_location_id": "shoptree_location_id",
+	"latitude":             "latitude",
+	"longitude":            "longitude",
+	"opening_hours":        "opening_hours",
+}
+
+// storeFileColumns are the columns of the stores file.
+var storeFileColumns = fileColumns{
+	required: []string{
+		"store_name",
+		"location_code",
+		"shoptree_location_id",
+		"address",
+		"latitude",
+		"longitude",
+		"opening_hours",
+		"active",
+	},
+}
+
+// weekdays maps the day abbreviations used in the stores file opening hours.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// importStore looks into the path given and upserts all the stores,
+// stores are matched by their location code so existing store ids are kept.
+func importStore(ctx context.Context, db *mongo.Database, path string, opts importOptions) error {
+	var (
+		store_name           int
+		location_code        int
+		shoptree_location_id int
+		address              int
+		latitude             int
+		longitude            int
+		opening_hours        int
+		active               int
+	)
+
+	var darkstores []*darkstore.Store
+	locationCodes := map[string]bool{}
+	errReport := newErrorReport(storeColumns)
+	// reads the stores file row by row, on the format of the file.
+	rows, err := openRowReader(path, opts)
+	if err != nil {
+		return fmt.Errorf("failed to read stores file: %w", err)
+	}
+	defer rows.close()
+	for {
+		line, rowNumber, err := rows.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read stores file lines: %w", err)
+		}
+		if rowNumber == 1 {
+			header, err := readHeader(line, storeFileColumns, opts.headerAliases)
+			if err != nil {
+				return fmt.Errorf("failed to read stores file header: %w", err)
+			}
+			errReport.setHeader(header.names)
+
+			// get all the index for the headers
+			store_name = header.column("store_name")
+			location_code = header.column("location_code")
+			shoptree_location_id = header.column("shoptree_location_id")
+			address = header.column("address")
+			latitude = header.column("latitude")
+			longitude = header.column("longitude")
+			opening_hours = header.column("opening_hours")
+			active = header.column("active")
+			continue
+		}
+
+		// every invalid column of the row is recorded, the row is skipped when any is invalid.
+		rowErrors := errReport.len()
+
+		ds := &darkstore.Store{
+			Name:               line[store_name],
+			LocationCode:       line[location_code],
+			ShoptreeLocationID: line[shoptree_location_id],
+			Address:            line[address],
+			Active:             strings.EqualFold(line[active], "yes"),
+		}
+		if ds.Latitude, err = strconv.ParseFloat(line[latitude], 64); err != nil {
+			errReport.addColumn(rowNumber, line, "latitude", errStoreInvalidLatitude)
+		}
+		if ds.Longitude, err = strconv.ParseFloat(line[longitude], 64); err != nil {
+			errReport.addColumn(rowNumber, line, "longitude", errStoreInvalidLongitude)
+		}
+		if ds.OpeningHours, err = parseOpeningHours(line[opening_hours]); err != nil {
+			errReport.addColumn(rowNumber, line, "opening_hours", err)
+		}
+
+		// check store parameter values
+		if err := validation.ValidateStore(ds); err != nil {
+			errReport.add(rowNumber, line, err)
+		}
+		if locationCodes[ds.LocationCode] {
+			errReport.addColumn(rowNumber, line, "location_code", errStoreDuplicateLocationCode)
+		}
+		if errReport.len() > rowErrors {
+			continue
+		}
+		locationCodes[ds.LocationCode] = true
+
+		darkstores = append(darkstores, ds)
+	}
+
+	// fails the import when any row is invalid, so nothing is partially imported.
+	if err := errReport.check(opts.errorsReportPath); err != nil {
+		return err
+	}
+
+	existingStores, err := findStores(ctx, db)
+	if err != nil {
 		return err
 	}
-	if len(darkstores) > 0 {
-		return errors.New("darkstore already exist in our database")
+	existingByLocationCode := make(map[string]*darkstore.Store, len(existingStores))
+	for _, ds := range existingStores {
+		existingByLocationCode[ds.LocationCode] = ds
 	}
 
 	collection := db.Collection(darkstoreCollection)
+	report := newImportReport(darkstoreCollection)
+
+	// converts all created and changed stores to mongo write model for bulk upsert.
+	models := []mongo.WriteModel{}
+	for _, ds := range darkstores {
+		if existing, ok := existingByLocationCode[ds.LocationCode]; ok {
+			ds.ID = existing.ID
+			fields := changedFields(existing, ds)
+			report.addDiff(ds.LocationCode, fields)
+			if len(fields) < 1 {
+				continue
+			}
+		} else {
+			report.add(changeCreated, ds.LocationCode)
+		}
 
-	// if no darkstores found, insert darkstore
-	// NOTE: This is just a dummy darkstore, need to be insert from path
-	ds := &darkstore.Store{
-		ID:                 primitive.NewObjectID(),
-		Name:               "Dropezy Store",
-		ShoptreeLocationID: "962553ec420c45388a6bfb26308bdc23",
-		LocationCode:       "WHT",
+		writeModel := mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"location_code": ds.LocationCode}).
+			SetUpdate(bson.M{
+				"$set": bson.M{
+					"name":                 ds.Name,
+					"shoptree_location_id": ds.ShoptreeLocationID,
+					"address":              ds.Address,
+					"latitude":             ds.Latitude,
+					"longitude":            ds.Longitude,
+					"opening_hours":        ds.OpeningHours,
+					"active":               ds.Active,
+				},
+				"$setOnInsert": bson.M{"_id": primitive.NewObjectID()},
+			}).
+			SetUpsert(true)
+		models = append(models, writeModel)
 	}
-	if _, err := collection.InsertOne(ctx, ds); err != nil {
-		return fmt.Errorf("failed to execute InsertOne store: %w", err)
+
+	// bulk upsert stores, nothing is written on dry run or when all stores are unchanged.
+	if !opts.dryRun && len(models) > 0 {
+		if _, err := bulkWrite(ctx, collection, models, opts); err != nil {
+			return fmt.Errorf("failed to execute BulkWrite, on import stores: %w", err)
+		}
 	}
 
-	log.Print("successfully imported stores!")
+	if err := report.finish(opts); err != nil {
+		return err
+	}
+	if !opts.dryRun {
+		log.Print("successfully imported stores!")
+	}
 	return nil
 }
 
+// parseOpeningHours parses the opening hours of a store, the value is a list of
+// days or day ranges with their opening time separated by semicolon,
+// e.g. "mon-fri 07:00-22:00;sat-sun 08:00-21:00" or "daily 08:00-22:00".
+func parseOpeningHours(value string) ([]*darkstore.OpeningHours, error) {
+	var openingHours []*darkstore.OpeningHours
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		days, hours, ok := strings.Cut(entry, " ")
+		if !ok {
+			return nil, errStoreInvalidOpeningHours
+		}
+		open, closing, ok := strings.Cut(strings.TrimSpace(hours), "-")
+		if !ok {
+			return nil, errStoreInvalidOpeningHours
+		}
+
+		from, to := time.Sunday, time.Saturday
+		if days = strings.ToLower(days); days != "daily" {
+			firstDay, lastDay, isRange := strings.Cut(days, "-")
+			if !isRange {
+				lastDay = firstDay
+			}
+			if from, ok = weekdays[firstDay]; !ok {
+				return nil, errStoreInvalidOpeningHours
+			}
+			if to, ok = weekdays[lastDay]; !ok || to < from {
+				return nil, errStoreInvalidOpeningHours
+			}
+		}
+
+		for day := from; day <= to; day++ {
+			openingHours = append(openingHours, &darkstore.OpeningHours{
+				Day:   day,
+				Open:  open,
+				Close: closing,
+			})
+		}
+	}
+	return openingHours, nil
+}
+
 // getStores get list of darkstores saved in our database.
 func getStores(ctx context.Context, db *mongo.Database) ([]*darkstore.Store, error) {
+	darkstores, err := findStores(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if len(darkstores) < 1 {
+		return nil, errStoreNotFound
+	}
+
+	return darkstores, nil
+}
+
+// findStores fetches all darkstores in our database.
+func findStores(ctx context.Context, db *mongo.Database) ([]*darkstore.Store, error) {
 	cur, err := db.Collection(darkstoreCollection).Find(ctx, bson.M{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute Find stores: %w", err)
@@ -57,9 +284,5 @@ func getStores(ctx context.Context, db *mongo.Database) ([]*darkstore.Store, err
 	if err := cur.All(ctx, &darkstores); err != nil {
 		return nil, fmt.Errorf("failed to decode darkstores: %w", err)
 	}
-	if len(darkstores) < 1 {
-		return nil, errStoreNotFound
-	}
-
 	return darkstores, nil
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dropezy/storefront-backend/ems-api/validation"
+)
+
+func TestImportStore(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		defaultTestTimeout,
+	)
+	t.Cleanup(cancel)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+
+		path := "testdata/store/success.csv"
+		if err := importStore(ctx, testDb, path, importOptions{}); err != nil {
+			t.Fatalf("expected nil error, got = %v", err)
+		}
+	})
+
+	t.Run("SuccessJSON", func(t *testing.T) {
+		t.Parallel()
+
+		path := "testdata/store/success.json"
+		if err := importStore(ctx, testDb, path, importOptions{}); err != nil {
+			t.Fatalf("expected nil error, got = %v", err)
+		}
+	})
+
+	// failed scenarios
+	tests := []struct {
+		name    string
+		path    string
+		wantErr error
+	}{
+		{
+			name:    "EmptyStoreName",
+			path:    "testdata/store/empty_store_name.csv",
+			wantErr: validation.ErrStoreNameIsRequired,
+		},
+		{
+			name:    "EmptyLocationCode",
+			path:    "testdata/store/empty_location_code.csv",
+			wantErr: validation.ErrStoreLocationCodeIsRequired,
+		},
+		{
+			name:    "EmptyShoptreeLocationID",
+			path:    "testdata/store/empty_shoptree_location_id.csv",
+			wantErr: validation.ErrStoreShoptreeLocationIDIsRequired,
+		},
+		{
+			name:    "InvalidLatitude",
+			path:    "testdata/store/invalid_latitude.csv",
+			wantErr: errStoreInvalidLatitude,
+		},
+		{
+			name:    "InvalidOpeningHours",
+			path:    "testdata/store/invalid_opening_hours.csv",
+			wantErr: errStoreInvalidOpeningHours,
+		},
+		{
+			name:    "DuplicateLocationCode",
+			path:    "testdata/store/duplicate_location_code.csv",
+			wantErr: errStoreDuplicateLocationCode,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := importStore(ctx, testDb, test.path, importOptions{})
+			if !errors.Is(errors.Unwrap(err), test.wantErr) {
+				t.Fatalf("importStore(_, _) error, got = %v, want = %v", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"log"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// defaultBatchSize is the default number of write models sent on each BulkWrite.
+	defaultBatchSize = 1000
+	// progressInterval is the number of rows read between progress logs.
+	progressInterval = 10000
+)
+
+// csvRows reads the rows of a CSV file one by one,
+// so large files are never fully loaded in memory.
+type csvRows struct {
+	file      io.Reader
+	r         *csv.Reader
+	rowNumber int
+}
+
+func newCSVRows(r io.Reader) *csvRows {
+	return &csvRows{file: r, r: csv.NewReader(r)}
+}
+
+// next returns the next row along with its row number starting from 1 for the header,
+// io.EOF is returned when there are no more rows.
+func (r *csvRows) next() ([]string, int, error) {
+	line, err := r.r.Read()
+	if err != nil {
+		return nil, r.rowNumber, err
+	}
+	r.rowNumber++
+	if r.rowNumber%progressInterval == 0 {
+		log.Printf("read %d rows", r.rowNumber)
+	}
+	return line, r.rowNumber, nil
+}
+
+func (r *csvRows) close() {
+	closeFile(r.file)
+}
+
+// batch returns the number of write models sent on each BulkWrite,
+// the default batch size is used when the options batch size is not set.
+func (o importOptions) batch() int {
+	if o.batchSize < 1 {
+		return defaultBatchSize
+	}
+	return o.batchSize
+}
+
+// bulkWrite executes models on collection in batches of the options batch size,
+// logging the progress after each batch. Each batch is journaled on the options run,
+// so the run can be rolled back. It returns the counts of all the batches.
+func bulkWrite(ctx context.Context, collection *mongo.Collection, models []mongo.WriteModel, opts importOptions) (*mongo.BulkWriteResult, error) {
+	batchSize := opts.batch()
+	result := &mongo.BulkWriteResult{}
+	for start := 0; start < len(models); start += batchSize {
+		end := start + batchSize
+		if end > len(models) {
+			end = len(models)
+		}
+
+		res, err := writeBatch(ctx, collection, models[start:end], opts.run)
+		if err != nil {
+			return nil, err
+		}
+		result.InsertedCount += res.InsertedCount
+		result.MatchedCount += res.MatchedCount
+		result.ModifiedCount += res.ModifiedCount
+		result.DeletedCount += res.DeletedCount
+		result.UpsertedCount += res.UpsertedCount
+		log.Printf("wrote %d/%d %s", end, len(models), collection.Name())
+	}
+	return result, nil
+}
+
+// writeBatch journals a batch of models on run, writes them and records
+// the written documents on run.
+func writeBatch(ctx context.Context, collection *mongo.Collection, models []mongo.WriteModel, run *importRun) (*mongo.BulkWriteResult, error) {
+	changes, err := run.journal(ctx, collection, models)
+	if err != nil {
+		return nil, err
+	}
+	res, err := collection.BulkWrite(ctx, models)
+	if err != nil {
+		return nil, err
+	}
+	if err := run.snapshot(ctx, collection, changes); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
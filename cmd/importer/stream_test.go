@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestCSVRows(t *testing.T) {
+	t.Parallel()
+
+	rows := newCSVRows(strings.NewReader("name,sku\nfoo,DNE0001\nbar,DNE0002\n"))
+	want := [][]string{{"name", "sku"}, {"foo", "DNE0001"}, {"bar", "DNE0002"}}
+	for i := range want {
+		line, rowNumber, err := rows.next()
+		if err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if rowNumber != i+1 {
+			t.Fatalf("row number, got = %d, want = %d", rowNumber, i+1)
+		}
+		if strings.Join(line, ",") != strings.Join(want[i], ",") {
+			t.Fatalf("row %d, got = %v, want = %v", rowNumber, line, want[i])
+		}
+	}
+	if _, _, err := rows.next(); !errors.Is(err, io.EOF) {
+		t.Fatalf("next() error, got = %v, want = %v", err, io.EOF)
+	}
+}
+
+func TestBulkWrite(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTestTimeout)
+	defer cancel()
+
+	collection := testDb.Collection("bulk_write_test")
+	models := []mongo.WriteModel{}
+	for i := 0; i < 5; i++ {
+		models = append(models, mongo.NewInsertOneModel().SetDocument(bson.M{"index": i}))
+	}
+	res, err := bulkWrite(ctx, collection, models, importOptions{batchSize: 2})
+	if err != nil {
+		t.Fatalf("unexpected error, got = %v", err)
+	}
+	if res.InsertedCount != int64(len(models)) {
+		t.Fatalf("inserted count, got = %d, want = %d", res.InsertedCount, len(models))
+	}
+
+	count, err := collection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		t.Fatalf("unexpected error, got = %v", err)
+	}
+	if count != int64(len(models)) {
+		t.Fatalf("documents count, got = %d, want = %d", count, len(models))
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/product"
+
+	"github.com/dropezy/storefront-backend/ems-api/validation"
+)
+
+// quantifierSeparator separates the quantifiers listed on a variant types file column.
+const quantifierSeparator = ";"
+
+// variantTypeNameCollation matches variant type names regardless of their case,
+// the same way product variants are linked to their variant type.
+var variantTypeNameCollation = &options.Collation{Locale: "en", Strength: 2}
+
+var (
+	errVariantTypeNotFound         = errors.New("variant type not found")
+	errVariantTypeDuplicateName    = errors.New("variant type name is shown on more than one row")
+	errVariantTypeQuantifierLength = errors.New("variant type quantifiers EN and ID must have the same length")
+)
+
+// variantTypeColumns maps variant type validation fields to the variant types file columns.
+var variantTypeColumns = map[string]string{
+	"name":        "variant_type_name",
+	"label_en":    "label_EN",
+	"label_id":    "label_ID",
+	"quantifiers": "quantifiers_EN",
+}
+
+// variantTypeFileColumns are the columns of the variant types file.
+var variantTypeFileColumns = fileColumns{
+	required: []string{
+		"variant_type_name",
+		"label_EN",
+		"label_ID",
+		"quantifiers_EN",
+		"quantifiers_ID",
+	},
+}
+
+// importVariantTypes looks into the path given and upserts all the variant types,
+// variant types are matched by their name regardless of its case so existing
+// variant type ids and names are kept.
+func importVariantTypes(ctx context.Context, db *mongo.Database, path string, opts importOptions) error {
+	var (
+		variant_type_name int
+		label_EN          int
+		label_ID          int
+		quantifiers_EN    int
+		quantifiers_ID    int
+	)
+
+	var variantTypes []*product.VariantType
+	names := map[string]bool{}
+	errReport := newErrorReport(variantTypeColumns)
+	// reads the variant types file row by row, on the format of the file.
+	rows, err := openRowReader(path, opts)
+	if err != nil {
+		return fmt.Errorf("failed to read variant types file: %w", err)
+	}
+	defer rows.close()
+	for {
+		line, rowNumber, err := rows.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read variant types file lines: %w", err)
+		}
+		if rowNumber == 1 {
+			header, err := readHeader(line, variantTypeFileColumns, opts.headerAliases)
+			if err != nil {
+				return fmt.Errorf("failed to read variant types file header: %w", err)
+			}
+			errReport.setHeader(header.names)
+
+			// get all the index for the headers
+			variant_type_name = header.column("variant_type_name")
+			label_EN = header.column("label_EN")
+			label_ID = header.column("label_ID")
+			quantifiers_EN = header.column("quantifiers_EN")
+			quantifiers_ID = header.column("quantifiers_ID")
+			continue
+		}
+
+		// every invalid column of the row is recorded, the row is skipped when any is invalid.
+		rowErrors := errReport.len()
+
+		quantifiers, err := parseQuantifiers(line[quantifiers_EN], line[quantifiers_ID])
+		if err != nil {
+			errReport.addColumn(rowNumber, line, "quantifiers_ID", err)
+		}
+
+		vt := &product.VariantType{
+			Name:        strings.TrimSpace(line[variant_type_name]),
+			Label_EN:    strings.TrimSpace(line[label_EN]),
+			Label_ID:    strings.TrimSpace(line[label_ID]),
+			Quantifiers: quantifiers,
+		}
+		// check variant type parameter values
+		if err := validation.ValidateVariantType(vt); err != nil {
+			errReport.add(rowNumber, line, err)
+		}
+
+		// products are linked to variant types by name, so names must be unique.
+		if names[strings.ToLower(vt.Name)] {
+			errReport.addColumn(rowNumber, line, "variant_type_name", errVariantTypeDuplicateName)
+		}
+		if errReport.len() > rowErrors {
+			continue
+		}
+		names[strings.ToLower(vt.Name)] = true
+
+		variantTypes = append(variantTypes, vt)
+	}
+
+	// fails the import when any row is invalid, so nothing is partially imported.
+	if err := errReport.check(opts.errorsReportPath); err != nil {
+		return err
+	}
+
+	collection := db.Collection(variantTypeCollection)
+
+	// existing variant types are matched by their lowercase name and updated by id.
+	existing, err := getVariantTypes(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	// converts all variant types to mongo write model for bulk upsert.
+	models := []mongo.WriteModel{}
+	for _, vt := range variantTypes {
+		e, ok := existing[strings.ToLower(vt.Name)]
+		if !ok {
+			vt.ID = primitive.NewObjectID()
+			models = append(models, mongo.NewInsertOneModel().SetDocument(vt))
+			continue
+		}
+		writeModel := mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": e.ID}).
+			SetUpdate(bson.M{
+				"$set": bson.M{
+					"label_en":    vt.Label_EN,
+					"label_id":    vt.Label_ID,
+					"quantifiers": vt.Quantifiers,
+				},
+			})
+		models = append(models, writeModel)
+	}
+
+	// bulk upsert variant types.
+	if _, err := bulkWrite(ctx, collection, models, opts); err != nil {
+		return fmt.Errorf("failed to execute BulkWrite, on import variant types: %w", err)
+	}
+
+	log.Print("successfully imported variant types!")
+	return nil
+}
+
+// parseQuantifiers pairs the EN and ID quantifiers separated by quantifierSeparator,
+// e.g. "pcs;pack" and "pcs;pak".
+func parseQuantifiers(namesEN, namesID string) ([]*product.Quantifier, error) {
+	if strings.TrimSpace(namesEN) == "" && strings.TrimSpace(namesID) == "" {
+		return nil, nil
+	}
+
+	en := strings.Split(namesEN, quantifierSeparator)
+	id := strings.Split(namesID, quantifierSeparator)
+	if len(en) != len(id) {
+		return nil, errVariantTypeQuantifierLength
+	}
+
+	quantifiers := make([]*product.Quantifier, 0, len(en))
+	for i := range en {
+		quantifiers = append(quantifiers, &product.Quantifier{
+			Name_EN: strings.TrimSpace(en[i]),
+			Name_ID: strings.TrimSpace(id[i]),
+		})
+	}
+	return quantifiers, nil
+}
+
+// getVariantTypes fetches all variant types in our database indexed by their lowercase name,
+// which will be used to link product variants to their variant type.
+func getVariantTypes(ctx context.Context, db *mongo.Database) (map[string]*product.VariantType, error) {
+	cur, err := db.Collection(variantTypeCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Find variant types: %w", err)
+	}
+
+	var variantTypes []*product.VariantType
+	if err := cur.All(ctx, &variantTypes); err != nil {
+		return nil, fmt.Errorf("failed to decode variant types: %w", err)
+	}
+
+	variantTypesByName := make(map[string]*product.VariantType, len(variantTypes))
+	for _, vt := range variantTypes {
+		variantTypesByName[strings.ToLower(vt.Name)] = vt
+	}
+	return variantTypesByName, nil
+}
+
+// getVariantType looks for a variant type with name on variant types indexed by their lowercase name.
+func getVariantType(variantTypes map[string]*product.VariantType, name string, rowNumber int) (*product.VariantType, error) {
+	if vt, ok := variantTypes[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return vt, nil
+	}
+	return nil, fmt.Errorf("failed to find variant type with name: %s, on row: %d, error: %w", name, rowNumber, errVariantTypeNotFound)
+}
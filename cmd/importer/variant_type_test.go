@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/dropezy/storefront-backend/ems-api/validation"
+)
+
+func TestImportVariantTypes(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		defaultTestTimeout,
+	)
+	t.Cleanup(cancel)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+
+		path := "testdata/variant_type/success.csv"
+		if err := importVariantTypes(ctx, testDb, path, importOptions{}); err != nil {
+			t.Fatalf("expected nil error, got = %v", err)
+		}
+	})
+
+	t.Run("CaseInsensitiveName", func(t *testing.T) {
+		t.Parallel()
+
+		// the second file updates the variant type of the first one instead of adding another.
+		for _, path := range []string{
+			"testdata/variant_type/weight.csv",
+			"testdata/variant_type/weight_uppercase.csv",
+		} {
+			if err := importVariantTypes(ctx, testDb, path, importOptions{}); err != nil {
+				t.Fatalf("expected nil error, got = %v", err)
+			}
+		}
+
+		n, err := testDb.Collection(variantTypeCollection).CountDocuments(ctx,
+			bson.M{"name": "Weight"},
+			options.Count().SetCollation(variantTypeNameCollation),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error, got = %v", err)
+		}
+		if n != 1 {
+			t.Errorf("weight variant types, got = %d, want = 1", n)
+		}
+	})
+
+	// failed scenarios
+	tests := []struct {
+		name    string
+		path    string
+		wantErr error
+	}{
+		{
+			name:    "EmptyVariantTypeName",
+			path:    "testdata/variant_type/empty_variant_type_name.csv",
+			wantErr: validation.ErrVariantTypeNameIsRequired,
+		},
+		{
+			name:    "MismatchQuantifiers",
+			path:    "testdata/variant_type/mismatch_quantifiers.csv",
+			wantErr: errVariantTypeQuantifierLength,
+		},
+		{
+			name:    "DuplicateName",
+			path:    "testdata/variant_type/duplicate_name.csv",
+			wantErr: errVariantTypeDuplicateName,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := importVariantTypes(ctx, testDb, test.path, importOptions{})
+			if !errors.Is(errors.Unwrap(err), test.wantErr) {
+				t.Fatalf("importVariantTypes(_, _) error, got = %v, want = %v", err, test.wantErr)
+			}
+		})
+	}
+}
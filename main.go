@@ -15,6 +15,7 @@ import (
 	"github.com/kenshaw/envcfg"
 	"github.com/rs/cors"
 	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 	"google.golang.org/grpc"
@@ -29,11 +30,20 @@ import (
 	"github.com/dropezy/internal/grpc/interceptors"
 	"github.com/dropezy/internal/logging"
 
-	"github.com/dropezy/storefront-backend/internal/storage/mongo"
+	storage "github.com/dropezy/storefront-backend/internal/storage/mongo"
 
+	"github.com/dropezy/storefront-backend/ems-api/metrics"
 	"github.com/dropezy/storefront-backend/ems-api/services"
+	"github.com/dropezy/storefront-backend/ems-api/services/apikey"
+	"github.com/dropezy/storefront-backend/ems-api/services/audit"
+	"github.com/dropezy/storefront-backend/ems-api/services/auth"
+	"github.com/dropezy/storefront-backend/ems-api/services/brand"
 	"github.com/dropezy/storefront-backend/ems-api/services/category"
+	"github.com/dropezy/storefront-backend/ems-api/services/darkstore"
+	"github.com/dropezy/storefront-backend/ems-api/services/inventory"
 	"github.com/dropezy/storefront-backend/ems-api/services/product"
+	"github.com/dropezy/storefront-backend/ems-api/services/user"
+	"github.com/dropezy/storefront-backend/ems-api/services/varianttype"
 )
 
 const service = "ems-api"
@@ -108,21 +118,49 @@ func main() {
 
 	logger.Info().Msgf("starting %s server", service)
 
+	m := metrics.New(version, environment)
+
+	// initialize mongo storage, the service stores share its database.
+	mongoStorage, err := storage.NewStorage(config, logger, storage.WithCommandMonitor(m.MongoMonitor()))
+	if err != nil {
+		logger.Fatal().Err(err).Msg("error initializing mongo storage")
+	}
+	db := mongoStorage.Database()
+	if err := setupIndexes(db); err != nil {
+		logger.Err(err).Msg("failed to create mongo indexes")
+	}
+	userStore := user.NewMongoStore(db)
+	apiKeyStore := apikey.NewMongoStore(db)
+	auditStore := audit.NewMongoStore(db)
+
 	// grpc server init
-	grpcServer, err := setupGRPCServer()
+	grpcServer, err := setupGRPCServer(m, db, userStore, apiKeyStore, auditStore)
 	if err != nil {
 		logger.Err(err).Msg("failed to setup gRPC server")
 	}
 
 	gw, err := setupGrpcGateway(
+		m,
 		category.RegisterGateway,
 		product.RegisterGateway,
+		inventory.RegisterGateway,
+		darkstore.RegisterGateway,
+		brand.RegisterGateway,
+		varianttype.RegisterGateway,
+		user.RegisterGateway,
+		apikey.RegisterGateway,
+		audit.RegisterGateway,
 	)
 	if err != nil {
 		logger.Err(err).Msg("failed to setup gRPC gateway")
 	}
 
-	server := setupServer(grpcServer, gw)
+	authMiddleware, err := setupAuthMiddleware(userStore, apiKeyStore)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to setup authentication")
+	}
+
+	server := setupServer(grpcServer, gw, authMiddleware)
 
 	// empty host because our service will be forwarded to
 	// outside via port forwarding.
@@ -137,6 +175,19 @@ func main() {
 		logger.Fatal().Err(server.Serve(l)).Msg("server shutdown")
 	}()
 
+	adminServer := setupAdminServer(m)
+	adminL, err := net.Listen("tcp", net.JoinHostPort("", config.GetString("admin.port")))
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+	}
+
+	go func() {
+		logger.Info().Msgf("admin server listening at %v", adminL.Addr())
+		if err := adminServer.Serve(adminL); err != http.ErrServerClosed {
+			logger.Fatal().Err(err).Msg("admin server shutdown")
+		}
+	}()
+
 	// catch interrupt signals
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt)
@@ -150,27 +201,35 @@ func main() {
 		logger.Fatal().Err(err).Msg("failed to shutdown server")
 	}
 	grpcServer.Stop()
+	if err := adminServer.Shutdown(shutdownCtx); err != nil {
+		logger.Fatal().Err(err).Msg("failed to shutdown admin server")
+	}
 
 	logger.Info().Msg("server exited gracefully")
 }
 
-func setupGRPCServer() (*grpc.Server, error) {
-	// initialize mongo client
-	mongoStore, err := mongo.NewStorage(config, logger)
-	if err != nil {
-		logger.Fatal().Err(err).Msg("error initializing mongo storage")
-	}
-
+func setupGRPCServer(m *metrics.Metrics, db *mongo.Database, userStore user.Store, apiKeyStore apikey.Store, auditStore audit.Store) (*grpc.Server, error) {
 	srv := grpc.NewServer(
 		interceptors.New(
 			logger,
+			m.UnaryServerInterceptor(),
 			grpctrace.UnaryServerInterceptor(),
+			audit.UnaryServerInterceptor(logger, auditStore),
+			auth.UnaryServerInterceptor(),
 		),
+		grpc.ChainStreamInterceptor(m.StreamServerInterceptor(), auth.StreamServerInterceptor()),
 	)
 
 	if err := services.Register(srv,
-		category.RegisterService(logger, mongoStore),
-		product.RegisterService(logger, mongoStore),
+		category.RegisterService(logger, category.NewMongoStore(db)),
+		product.RegisterService(logger, product.NewMongoStore(db)),
+		inventory.RegisterService(logger, inventory.NewMongoStore(db)),
+		darkstore.RegisterService(logger, darkstore.NewMongoStore(db)),
+		brand.RegisterService(logger, brand.NewMongoStore(db)),
+		varianttype.RegisterService(logger, varianttype.NewMongoStore(db)),
+		user.RegisterService(logger, userStore),
+		apikey.RegisterService(logger, apiKeyStore),
+		audit.RegisterService(logger, auditStore),
 	); err != nil {
 		return nil, err
 	}
@@ -184,9 +243,32 @@ func setupGRPCServer() (*grpc.Server, error) {
 	return srv, nil
 }
 
+// setupIndexes creates the indexes of the collections the services rely on.
+func setupIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, s := range []interface {
+		CreateIndexes(ctx context.Context) error
+	}{
+		brand.NewMongoStore(db),
+		category.NewMongoStore(db),
+		product.NewMongoStore(db),
+		varianttype.NewMongoStore(db),
+		user.NewMongoStore(db),
+		audit.NewMongoStore(db),
+	} {
+		if err := s.CreateIndexes(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type RegisterGatewayFunc func(ctx context.Context, mux *runtime.ServeMux, addr string, opts []grpc.DialOption) error
 
-func setupGrpcGateway(registerGatewayFuncs ...RegisterGatewayFunc) (http.Handler, error) {
+// setupGrpcGateway returns the gateway of the registered services, its requests are measured by m.
+func setupGrpcGateway(m *metrics.Metrics, registerGatewayFuncs ...RegisterGatewayFunc) (http.Handler, error) {
 	options := []runtime.ServeMuxOption{
 		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
 			MarshalOptions: protojson.MarshalOptions{
@@ -197,6 +279,14 @@ func setupGrpcGateway(registerGatewayFuncs ...RegisterGatewayFunc) (http.Handler
 				DiscardUnknown: true,
 			},
 		}),
+		// forward the API key to the gRPC server, which authenticates the gateway calls.
+		runtime.WithIncomingHeaderMatcher(func(key string) (string, bool) {
+			if http.CanonicalHeaderKey(key) == apiKeyHeader {
+				return strings.ToLower(key), true
+			}
+			return runtime.DefaultHeaderMatcher(key)
+		}),
+		m.GatewayOption(),
 	}
 
 	mux := runtime.NewServeMux(options...)
@@ -211,15 +301,55 @@ func setupGrpcGateway(registerGatewayFuncs ...RegisterGatewayFunc) (http.Handler
 			return nil, fmt.Errorf("unable to register gateway: %w", err)
 		}
 	}
-	return mux, nil
+	return m.WrapGateway(mux), nil
+}
+
+// setupAuthMiddleware returns the authenticator chain of the requests: API keys,
+// then bearer tokens when JWT keys are configured, then basic auth.
+func setupAuthMiddleware(users userFinder, keys apiKeyFinder) (*authMiddleware, error) {
+	authenticators := []authenticator{
+		&apiKeyAuthenticator{keys: keys},
+	}
+
+	jwtConfig := auth.JWTConfig{
+		JWKSFile:  config.GetString("jwt.jwks"),
+		Issuer:    config.GetString("jwt.issuer"),
+		Audience:  config.GetString("jwt.audience"),
+		RoleClaim: config.GetString("jwt.roleClaim"),
+	}
+	for _, path := range strings.Split(config.GetString("jwt.keys"), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			jwtConfig.KeyFiles = append(jwtConfig.KeyFiles, path)
+		}
+	}
+	if jwtConfig.JWKSFile != "" || len(jwtConfig.KeyFiles) > 0 {
+		roleMap, err := auth.ParseRoleMap(config.GetString("jwt.roleMap"))
+		if err != nil {
+			return nil, err
+		}
+		jwtConfig.RoleMap = roleMap
+
+		verifier, err := auth.NewJWTVerifier(jwtConfig)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, &bearerAuthenticator{verifier: verifier})
+	}
+
+	authenticators = append(authenticators, &basicAuthenticator{
+		username: config.GetString("server.username"),
+		password: config.GetString("server.password"),
+		users:    users,
+	})
+	return &authMiddleware{authenticators: authenticators}, nil
 }
 
 // setupServer return http server with h2c handler, it also provide root http route
-// to print our server version.
-func setupServer(grpcServer *grpc.Server, gw http.Handler) *http.Server {
+// to print our server version. Requests are authenticated by authMiddleware.
+func setupServer(grpcServer *grpc.Server, gw http.Handler, authMiddleware *authMiddleware) *http.Server {
 	return &http.Server{
 		Handler: h2c.NewHandler(
-			mixedHandler(grpcServer, gw),
+			mixedHandler(grpcServer, gw, authMiddleware),
 			&http2.Server{IdleTimeout: 120 * time.Second},
 		),
 		ReadTimeout:  5 * time.Second,
@@ -228,7 +358,19 @@ func setupServer(grpcServer *grpc.Server, gw http.Handler) *http.Server {
 	}
 }
 
-func mixedHandler(grpcServer *grpc.Server, gw http.Handler) http.Handler {
+// setupAdminServer returns the admin http server, serving the metrics at /metrics.
+func setupAdminServer(m *metrics.Metrics) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	return &http.Server{
+		Handler:      mux,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+}
+
+func mixedHandler(grpcServer *grpc.Server, gw http.Handler, authMiddleware *authMiddleware) http.Handler {
 	corsHandler := cors.New(cors.Options{
 		AllowCredentials: true,
 		AllowedOrigins:   strings.Split(config.GetString("cors.origins"), ","),
@@ -238,10 +380,7 @@ func mixedHandler(grpcServer *grpc.Server, gw http.Handler) http.Handler {
 	apiMiddleware := &apiMiddleware{
 		name:    service,
 		version: version,
-		auth: &basicAuthMiddleware{
-			username: config.GetString("server.username"),
-			password: config.GetString("server.password"),
-		},
+		auth:    authMiddleware,
 	}
 	return apiMiddleware.WrapHandler(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,259 @@
+// Package metrics exports the Prometheus metrics of ems-api: gRPC and
+// gateway requests, mongo commands and build info.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	gwruntime "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/event"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "ems"
+
+// unknownMethod labels the gateway requests which don't match any route.
+const unknownMethod = "unknown"
+
+// Metrics holds the collectors of ems-api, registered on their own registry.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	grpcRequests        *prometheus.CounterVec
+	grpcRequestDuration *prometheus.HistogramVec
+	grpcInFlight        prometheus.Gauge
+
+	gatewayRequests        *prometheus.CounterVec
+	gatewayRequestDuration *prometheus.HistogramVec
+	gatewayInFlight        prometheus.Gauge
+
+	mongoCommandDuration *prometheus.HistogramVec
+
+	// mongoCommands holds the collections of the started mongo commands by request id,
+	// the finished command events don't carry the collection.
+	mu            sync.Mutex
+	mongoCommands map[int64]string
+}
+
+// New returns the ems-api metrics, along with the Go runtime and process
+// metrics and the build info of version.
+func New(version, environment string) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		grpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "requests_total",
+			Help:      "Number of gRPC requests by full method name and status code, gateway requests included.",
+		}, []string{"method", "code"}),
+		grpcRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of gRPC requests by full method name and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		grpcInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "requests_in_flight",
+			Help:      "Number of gRPC requests being served.",
+		}),
+		gatewayRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "gateway",
+			Name:      "requests_total",
+			Help:      "Number of gateway HTTP requests by gRPC method and HTTP status code.",
+		}, []string{"method", "code"}),
+		gatewayRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "gateway",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of gateway HTTP requests by gRPC method and HTTP status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		gatewayInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "gateway",
+			Name:      "requests_in_flight",
+			Help:      "Number of gateway HTTP requests being served.",
+		}),
+		mongoCommandDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "mongo",
+			Name:      "command_duration_seconds",
+			Help:      "Latency of mongo commands by command name, collection and outcome.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"command", "collection", "status"}),
+		mongoCommands: map[int64]string{},
+	}
+
+	buildInfo := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "build_info",
+		Help:      "Build info of ems-api, always 1.",
+		ConstLabels: prometheus.Labels{
+			"version":     version,
+			"environment": environment,
+			"goversion":   runtime.Version(),
+		},
+	})
+	buildInfo.Set(1)
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		buildInfo,
+		m.grpcRequests,
+		m.grpcRequestDuration,
+		m.grpcInFlight,
+		m.gatewayRequests,
+		m.gatewayRequestDuration,
+		m.gatewayInFlight,
+		m.mongoCommandDuration,
+	)
+	return m
+}
+
+// Handler returns the HTTP handler exposing the metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// UnaryServerInterceptor measures unary gRPC requests.
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		done := m.observeGRPC(info.FullMethod)
+		resp, err := handler(ctx, req)
+		done(err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor measures streaming gRPC requests, e.g. server reflection.
+func (m *Metrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		done := m.observeGRPC(info.FullMethod)
+		err := handler(srv, ss)
+		done(err)
+		return err
+	}
+}
+
+// observeGRPC starts measuring a gRPC request, the returned func ends
+// the measure with the request error.
+func (m *Metrics) observeGRPC(method string) func(err error) {
+	start := time.Now()
+	m.grpcInFlight.Inc()
+	return func(err error) {
+		m.grpcInFlight.Dec()
+		code := status.Code(err).String()
+		m.grpcRequests.WithLabelValues(method, code).Inc()
+		m.grpcRequestDuration.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+	}
+}
+
+// gatewayCall holds the gRPC method of a gateway request, set once the request is routed.
+type gatewayCall struct {
+	method string
+}
+
+type gatewayCallKey struct{}
+
+// GatewayOption returns the gateway option reporting the gRPC method of
+// the gateway requests measured by WrapGateway.
+func (m *Metrics) GatewayOption() gwruntime.ServeMuxOption {
+	return gwruntime.WithMetadata(func(ctx context.Context, r *http.Request) metadata.MD {
+		if call, ok := r.Context().Value(gatewayCallKey{}).(*gatewayCall); ok {
+			if method, ok := gwruntime.RPCMethod(ctx); ok {
+				call.method = method
+			}
+		}
+		return nil
+	})
+}
+
+// WrapGateway measures the gateway requests served by gw, the gateway
+// must be created with GatewayOption to label them by gRPC method.
+func (m *Metrics) WrapGateway(gw http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		m.gatewayInFlight.Inc()
+		defer m.gatewayInFlight.Dec()
+
+		call := &gatewayCall{method: unknownMethod}
+		sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+		gw.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), gatewayCallKey{}, call)))
+
+		code := strconv.Itoa(sw.code)
+		m.gatewayRequests.WithLabelValues(call.method, code).Inc()
+		m.gatewayRequestDuration.WithLabelValues(call.method, code).Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusWriter records the status code written by a handler.
+type statusWriter struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.code = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush lets the gateway stream responses through the writer.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// MongoMonitor returns the mongo client monitor measuring the commands.
+func (m *Metrics) MongoMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			// the collection is the value of the command name element, e.g. {"find": "product"}.
+			collection, _ := e.Command.Lookup(e.CommandName).StringValueOK()
+			m.mu.Lock()
+			m.mongoCommands[e.RequestID] = collection
+			m.mu.Unlock()
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			m.observeMongo(e.CommandFinishedEvent, "succeeded")
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			m.observeMongo(e.CommandFinishedEvent, "failed")
+		},
+	}
+}
+
+func (m *Metrics) observeMongo(e event.CommandFinishedEvent, status string) {
+	m.mu.Lock()
+	collection := m.mongoCommands[e.RequestID]
+	delete(m.mongoCommands, e.RequestID)
+	m.mu.Unlock()
+
+	m.mongoCommandDuration.
+		WithLabelValues(e.CommandName, collection, status).
+		Observe(time.Duration(e.DurationNanos).Seconds())
+}
@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gwruntime "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	t.Parallel()
+
+	m := New("v1.2.3", "test")
+	interceptor := m.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/ems.v1.brand.BrandService/GetBrand"}
+
+	for _, err := range []error{nil, nil, status.Error(codes.NotFound, "brand not found")} {
+		_, _ = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			if got := testutil.ToFloat64(m.grpcInFlight); got != 1 {
+				t.Errorf("requests in flight = %v, want 1", got)
+			}
+			return nil, err
+		})
+	}
+
+	if got := testutil.ToFloat64(m.grpcRequests.WithLabelValues(info.FullMethod, "OK")); got != 2 {
+		t.Errorf("OK requests = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.grpcRequests.WithLabelValues(info.FullMethod, "NotFound")); got != 1 {
+		t.Errorf("NotFound requests = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.grpcInFlight); got != 0 {
+		t.Errorf("requests in flight = %v, want 0", got)
+	}
+}
+
+func TestWrapGateway(t *testing.T) {
+	t.Parallel()
+
+	m := New("v1.2.3", "test")
+	mux := gwruntime.NewServeMux(m.GatewayOption())
+	if err := mux.HandlePath(http.MethodGet, "/v1/brands/{id}", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		// the generated gateway handlers annotate the context before calling the service.
+		if _, err := gwruntime.AnnotateContext(r.Context(), mux, r, "/ems.v1.brand.BrandService/GetBrand"); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	h := m.WrapGateway(mux)
+
+	for _, path := range []string{"/v1/brands/1", "/v1/unknown"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(m.gatewayRequests.WithLabelValues("/ems.v1.brand.BrandService/GetBrand", "404")); got != 1 {
+		t.Errorf("GetBrand requests = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.gatewayRequests.WithLabelValues(unknownMethod, "404")); got != 1 {
+		t.Errorf("unknown requests = %v, want 1", got)
+	}
+}
+
+func TestMongoMonitor(t *testing.T) {
+	t.Parallel()
+
+	m := New("v1.2.3", "test")
+	monitor := m.MongoMonitor()
+
+	cmd, err := bson.Marshal(bson.D{{Key: "find", Value: "product"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	monitor.Started(context.Background(), &event.CommandStartedEvent{Command: cmd, CommandName: "find", RequestID: 1})
+	monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 1, DurationNanos: 2e6},
+	})
+	monitor.Failed(context.Background(), &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "ping", RequestID: 2, DurationNanos: 1e6},
+	})
+
+	if got := testutil.CollectAndCount(m.mongoCommandDuration); got != 2 {
+		t.Errorf("mongo command series = %v, want 2", got)
+	}
+	if len(m.mongoCommands) != 0 {
+		t.Errorf("pending mongo commands = %v, want none", m.mongoCommands)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	m := New("v1.2.3", "test")
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %v, want %v", rec.Code, http.StatusOK)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `ems_build_info{environment="test",goversion=`) || !strings.Contains(body, `version="v1.2.3"} 1`) {
+		t.Errorf("build info missing from metrics:\n%s", body)
+	}
+}
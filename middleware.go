@@ -1,10 +1,23 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/dropezy/storefront-backend/ems-api/services/apikey"
+	"github.com/dropezy/storefront-backend/ems-api/services/auth"
+	"github.com/dropezy/storefront-backend/ems-api/services/user"
 )
 
 type Middleware interface {
@@ -15,7 +28,7 @@ type apiMiddleware struct {
 	name    string
 	version string
 
-	auth *basicAuthMiddleware
+	auth *authMiddleware
 }
 
 func (am *apiMiddleware) WrapHandler(next http.Handler) http.Handler {
@@ -35,32 +48,276 @@ func (am *apiMiddleware) WrapHandler(next http.Handler) http.Handler {
 	})
 }
 
-type basicAuthMiddleware struct {
-	username string
-	password string
+// errInvalidCredentials is returned by authenticators when the request
+// carries their credentials but the credentials are invalid.
+var errInvalidCredentials = errors.New("invalid credentials")
+
+// authenticator authenticates requests with one kind of credentials.
+type authenticator interface {
+	// authenticate returns the principal of the request, or nil when the request
+	// doesn't carry the credentials of the authenticator.
+	authenticate(r *http.Request) (*auth.Principal, error)
+	// challenge returns the WWW-Authenticate challenge of the authenticator,
+	// or an empty string.
+	challenge() string
 }
 
-func (am *basicAuthMiddleware) WrapHandler(next http.Handler) http.Handler {
+// authMiddleware authenticates requests with the first authenticator of the chain
+// whose credentials are found on the request, and exposes the principal to the
+// handlers through the request context, see auth.FromContext.
+type authMiddleware struct {
+	authenticators []authenticator
+}
+
+func (am *authMiddleware) WrapHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		username, password, ok := r.BasicAuth()
-		if ok {
-			usernameHash := sha256.Sum256([]byte(username))
-			passwordHash := sha256.Sum256([]byte(password))
-
-			wantUsernameHash := sha256.Sum256([]byte(am.username))
-			wantPasswordHash := sha256.Sum256([]byte(am.password))
-
-			// validate the given credentials against the expected credentials
-			if subtle.ConstantTimeCompare(usernameHash[:], wantUsernameHash[:]) == 1 &&
-				subtle.ConstantTimeCompare(passwordHash[:], wantPasswordHash[:]) == 1 {
-				next.ServeHTTP(w, r)
+		for _, a := range am.authenticators {
+			p, err := a.authenticate(r)
+			if errors.Is(err, errInvalidCredentials) {
+				break
+			}
+			if err != nil {
+				logger.Err(err).Msg("failed to authenticate request")
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if p != nil {
+				// the principal is authorized per RPC by the gRPC server interceptors,
+				// gateway requests reach them through the gateway gRPC calls.
+				next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), p)))
 				return
 			}
 		}
 
-		// fail the request if the authorization header is not present or
+		// fail the request if no credentials are present or
 		// the supplied credentials are invalid.
-		w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
+		for _, a := range am.authenticators {
+			if c := a.challenge(); c != "" {
+				w.Header().Add("WWW-Authenticate", c)
+			}
+		}
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 	})
 }
+
+// userFinder looks up the users authenticated by their username and password.
+type userFinder interface {
+	GetUserByUsername(ctx context.Context, username string) (*auth.User, error)
+	UsersExist(ctx context.Context) (bool, error)
+}
+
+// credentialsCacheTTL is how long verified credentials are trusted without
+// checking the password hash again, disabled users, changed passwords and
+// revoked API keys are rejected after at most this duration. The cache is kept
+// per server instance, so it is not invalidated when a key is revoked.
+const credentialsCacheTTL = time.Minute
+
+// dummyPasswordHash is checked against the password of unknown users,
+// so they take as long to reject as users with a wrong password.
+var dummyPasswordHash, _ = auth.HashPassword("dummy-password")
+
+// basicAuthenticator authenticates users with basic auth.
+type basicAuthenticator struct {
+	// username and password are the bootstrap admin credentials of the
+	// server config, used to create the first users. They are only accepted
+	// while no user exists, so they can't be used once the first admin is created.
+	username string
+	password string
+
+	// usersExist is set once a user is found, users are never deleted so
+	// the bootstrap credentials stay rejected without checking the store again.
+	usersExist int32
+
+	users userFinder
+	cache principalCache
+}
+
+func (a *basicAuthenticator) challenge() string {
+	return `Basic realm="restricted", charset="UTF-8"`
+}
+
+func (a *basicAuthenticator) authenticate(r *http.Request) (*auth.Principal, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+
+	if a.username != "" && a.password != "" {
+		usernameHash := sha256.Sum256([]byte(username))
+		passwordHash := sha256.Sum256([]byte(password))
+
+		wantUsernameHash := sha256.Sum256([]byte(a.username))
+		wantPasswordHash := sha256.Sum256([]byte(a.password))
+
+		// validate the given credentials against the bootstrap credentials
+		if subtle.ConstantTimeCompare(usernameHash[:], wantUsernameHash[:]) == 1 &&
+			subtle.ConstantTimeCompare(passwordHash[:], wantPasswordHash[:]) == 1 {
+			bootstrap, err := a.bootstrapOpen(r.Context())
+			if err != nil {
+				return nil, err
+			}
+			if bootstrap {
+				return &auth.Principal{Name: username, Role: auth.RoleAdmin}, nil
+			}
+		}
+	}
+
+	sum := sha256.Sum256([]byte(username + ":" + password))
+	key := hex.EncodeToString(sum[:])
+	if p := a.cache.get(key); p != nil {
+		return p, nil
+	}
+
+	u, err := a.users.GetUserByUsername(r.Context(), username)
+	if errors.Is(err, user.ErrUserNotFound) {
+		auth.CheckPassword(dummyPasswordHash, password)
+		return nil, errInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !auth.CheckPassword(u.PasswordHash, password) || u.Disabled {
+		return nil, errInvalidCredentials
+	}
+
+	p := &auth.Principal{ID: u.ID.Hex(), Name: u.Username, Role: u.Role}
+	a.cache.put(key, p, time.Now().Add(credentialsCacheTTL))
+	return p, nil
+}
+
+// bootstrapOpen reports whether the bootstrap credentials are accepted,
+// which is only while the user collection is empty.
+func (a *basicAuthenticator) bootstrapOpen(ctx context.Context) (bool, error) {
+	if atomic.LoadInt32(&a.usersExist) == 1 {
+		return false, nil
+	}
+	exists, err := a.users.UsersExist(ctx)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		atomic.StoreInt32(&a.usersExist, 1)
+	}
+	return !exists, nil
+}
+
+// apiKeyHeader is the header of API keys, forwarded by the gateway
+// and sent as metadata by gRPC clients.
+const apiKeyHeader = "X-Api-Key"
+
+// apiKeyFinder looks up the API keys by the hash of the key.
+type apiKeyFinder interface {
+	GetAPIKeyByHash(ctx context.Context, hash string) (*auth.APIKey, error)
+	TouchAPIKey(ctx context.Context, id primitive.ObjectID, at time.Time) error
+}
+
+// apiKeyAuthenticator authenticates integrations with API keys.
+type apiKeyAuthenticator struct {
+	keys apiKeyFinder
+
+	// cache also throttles the last use updates of the keys to one per credentialsCacheTTL.
+	cache principalCache
+}
+
+func (a *apiKeyAuthenticator) challenge() string {
+	return ""
+}
+
+func (a *apiKeyAuthenticator) authenticate(r *http.Request) (*auth.Principal, error) {
+	key := r.Header.Get(apiKeyHeader)
+	if key == "" {
+		return nil, nil
+	}
+
+	hash := auth.HashAPIKey(key)
+	if p := a.cache.get(hash); p != nil {
+		return p, nil
+	}
+
+	k, err := a.keys.GetAPIKeyByHash(r.Context(), hash)
+	if errors.Is(err, apikey.ErrAPIKeyNotFound) {
+		return nil, errInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !k.Active(now) {
+		return nil, errInvalidCredentials
+	}
+
+	if err := a.keys.TouchAPIKey(r.Context(), k.ID, now.UTC()); err != nil {
+		logger.Err(err).Str("api_key_id", k.ID.Hex()).Msg("failed to record api key use")
+	}
+
+	p := k.Principal()
+	expiresAt := now.Add(credentialsCacheTTL)
+	if k.ExpiresAt != nil && k.ExpiresAt.Before(expiresAt) {
+		expiresAt = *k.ExpiresAt
+	}
+	a.cache.put(hash, p, expiresAt)
+	return p, nil
+}
+
+// bearerAuthenticator authenticates the callers of other internal tools
+// with the JWTs the tools issue.
+type bearerAuthenticator struct {
+	verifier *auth.JWTVerifier
+}
+
+func (a *bearerAuthenticator) challenge() string {
+	return `Bearer realm="restricted"`
+}
+
+func (a *bearerAuthenticator) authenticate(r *http.Request) (*auth.Principal, error) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, nil
+	}
+
+	p, err := a.verifier.Verify(strings.TrimSpace(token))
+	if err != nil {
+		logger.Debug().Err(err).Msg("rejected bearer token")
+		return nil, errInvalidCredentials
+	}
+	return p, nil
+}
+
+// principalCache holds the principals of recently verified credentials,
+// keyed by a hash of the credentials.
+type principalCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedPrincipal
+}
+
+type cachedPrincipal struct {
+	principal *auth.Principal
+	expiresAt time.Time
+}
+
+// get returns the principal of the credentials key, expired entries are removed.
+func (c *principalCache) get(key string) *auth.Principal {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(e.expiresAt) {
+		delete(c.entries, key)
+		return nil
+	}
+	return e.principal
+}
+
+// put caches the principal of the credentials key until expiresAt.
+func (c *principalCache) put(key string, p *auth.Principal, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[string]cachedPrincipal{}
+	}
+	c.entries[key] = cachedPrincipal{principal: p, expiresAt: expiresAt}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/dropezy/storefront-backend/ems-api/services/apikey"
+	"github.com/dropezy/storefront-backend/ems-api/services/auth"
+	"github.com/dropezy/storefront-backend/ems-api/services/user"
+)
+
+// testUsers is a userFinder holding the users of the tests.
+type testUsers []*auth.User
+
+func (us testUsers) GetUserByUsername(ctx context.Context, username string) (*auth.User, error) {
+	for _, u := range us {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+	return nil, user.ErrUserNotFound
+}
+
+func (us testUsers) UsersExist(ctx context.Context) (bool, error) {
+	return len(us) > 0, nil
+}
+
+func TestBasicAuthenticatorBootstrap(t *testing.T) {
+	t.Parallel()
+
+	const (
+		bootstrapUsername = "bootstrap"
+		bootstrapPassword = "bootstrap-password"
+	)
+	hash, err := auth.HashPassword("ops-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := &auth.User{ID: primitive.NewObjectID(), Username: "ops", PasswordHash: hash, Role: auth.RoleViewer}
+
+	tests := []struct {
+		name     string
+		users    testUsers
+		username string
+		password string
+		wantRole auth.Role
+		wantErr  error
+	}{
+		{
+			name:     "NoUsers",
+			username: bootstrapUsername,
+			password: bootstrapPassword,
+			wantRole: auth.RoleAdmin,
+		},
+		{
+			name:     "NoUsersWrongPassword",
+			username: bootstrapUsername,
+			password: "wrong-password",
+			wantErr:  errInvalidCredentials,
+		},
+		{
+			name:     "UsersExist",
+			users:    testUsers{ops},
+			username: bootstrapUsername,
+			password: bootstrapPassword,
+			wantErr:  errInvalidCredentials,
+		},
+		{
+			name:     "User",
+			users:    testUsers{ops},
+			username: "ops",
+			password: "ops-password",
+			wantRole: auth.RoleViewer,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := &basicAuthenticator{username: bootstrapUsername, password: bootstrapPassword, users: test.users}
+			r := httptest.NewRequest("GET", "/v1/products", nil)
+			r.SetBasicAuth(test.username, test.password)
+
+			p, err := a.authenticate(r)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("authenticate(_) error, got = %v, want = %v", err, test.wantErr)
+			}
+			if test.wantErr != nil {
+				return
+			}
+			if p == nil || p.Role != test.wantRole {
+				t.Fatalf("authenticate(_) principal, got = %+v, want role = %s", p, test.wantRole)
+			}
+		})
+	}
+}
+
+// testAPIKeys is an apiKeyFinder holding the API keys of the tests by their hash.
+type testAPIKeys struct {
+	mu   sync.Mutex
+	keys map[string]*auth.APIKey
+}
+
+func (ks *testAPIKeys) GetAPIKeyByHash(ctx context.Context, hash string) (*auth.APIKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	k, ok := ks.keys[hash]
+	if !ok {
+		return nil, apikey.ErrAPIKeyNotFound
+	}
+	copied := *k
+	return &copied, nil
+}
+
+func (ks *testAPIKeys) TouchAPIKey(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	return nil
+}
+
+func (ks *testAPIKeys) revoke(hash string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	now := time.Now()
+	ks.keys[hash].RevokedAt = &now
+}
+
+func TestAPIKeyAuthenticatorRevoked(t *testing.T) {
+	t.Parallel()
+
+	const key = "ems_test-key"
+	hash := auth.HashAPIKey(key)
+	keys := &testAPIKeys{keys: map[string]*auth.APIKey{
+		hash: {ID: primitive.NewObjectID(), Name: "sync", KeyHash: hash},
+	}}
+	a := &apiKeyAuthenticator{keys: keys}
+
+	authenticate := func() (*auth.Principal, error) {
+		r := httptest.NewRequest("GET", "/v1/inventories", nil)
+		r.Header.Set(apiKeyHeader, key)
+		return a.authenticate(r)
+	}
+
+	if p, err := authenticate(); err != nil || p == nil {
+		t.Fatalf("authenticate(_) of an active key, got = %+v, %v", p, err)
+	}
+
+	// the verified key is still trusted until its cache entry expires.
+	keys.revoke(hash)
+	if p, err := authenticate(); err != nil || p == nil {
+		t.Fatalf("authenticate(_) of a cached revoked key, got = %+v, %v, want it accepted for up to %s", p, err, credentialsCacheTTL)
+	}
+
+	a.cache.mu.Lock()
+	e := a.cache.entries[hash]
+	if ttl := time.Until(e.expiresAt); ttl > credentialsCacheTTL {
+		t.Errorf("cached key expires in %s, want at most %s", ttl, credentialsCacheTTL)
+	}
+	e.expiresAt = time.Now().Add(-time.Second)
+	a.cache.entries[hash] = e
+	a.cache.mu.Unlock()
+
+	if _, err := authenticate(); !errors.Is(err, errInvalidCredentials) {
+		t.Fatalf("authenticate(_) of an expired cached revoked key error, got = %v, want = %v", err, errInvalidCredentials)
+	}
+}
+
+// testAuthenticator authenticates the requests carrying its header.
+type testAuthenticator struct {
+	header    string
+	principal *auth.Principal
+	err       error
+	scheme    string
+	calls     int
+}
+
+func (a *testAuthenticator) challenge() string {
+	return a.scheme
+}
+
+func (a *testAuthenticator) authenticate(r *http.Request) (*auth.Principal, error) {
+	a.calls++
+	if r.Header.Get(a.header) == "" {
+		return nil, nil
+	}
+	return a.principal, a.err
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	t.Parallel()
+
+	var (
+		bearerChallenge = (&bearerAuthenticator{}).challenge()
+		basicChallenge  = (&basicAuthenticator{}).challenge()
+	)
+
+	tests := []struct {
+		name           string
+		headers        []string
+		keyErr         error
+		wantStatus     int
+		wantPrincipal  string
+		wantCalls      []int
+		wantChallenges []string
+	}{
+		{
+			name:          "FirstAuthenticatorWins",
+			headers:       []string{"X-Key", "X-Bearer", "X-Basic"},
+			wantStatus:    http.StatusOK,
+			wantPrincipal: "key",
+			wantCalls:     []int{1, 0, 0},
+		},
+		{
+			name:          "NextAuthenticatorWithoutCredentials",
+			headers:       []string{"X-Basic"},
+			wantStatus:    http.StatusOK,
+			wantPrincipal: "basic",
+			wantCalls:     []int{1, 1, 1},
+		},
+		{
+			name:           "InvalidCredentialsStopTheChain",
+			headers:        []string{"X-Key", "X-Basic"},
+			keyErr:         errInvalidCredentials,
+			wantStatus:     http.StatusUnauthorized,
+			wantCalls:      []int{1, 0, 0},
+			wantChallenges: []string{bearerChallenge, basicChallenge},
+		},
+		{
+			name:           "NoCredentials",
+			wantStatus:     http.StatusUnauthorized,
+			wantCalls:      []int{1, 1, 1},
+			wantChallenges: []string{bearerChallenge, basicChallenge},
+		},
+		{
+			name:       "AuthenticatorError",
+			headers:    []string{"X-Key", "X-Basic"},
+			keyErr:     errors.New("mongo is down"),
+			wantStatus: http.StatusInternalServerError,
+			wantCalls:  []int{1, 0, 0},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			authenticators := []*testAuthenticator{
+				{header: "X-Key", principal: &auth.Principal{Name: "key", APIKey: true}, err: test.keyErr},
+				{header: "X-Bearer", principal: &auth.Principal{Name: "bearer"}, scheme: bearerChallenge},
+				{header: "X-Basic", principal: &auth.Principal{Name: "basic"}, scheme: basicChallenge},
+			}
+			am := &authMiddleware{}
+			for _, a := range authenticators {
+				am.authenticators = append(am.authenticators, a)
+			}
+
+			var principal string
+			h := am.WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal = auth.FromContext(r.Context()).Name
+			}))
+			r := httptest.NewRequest("GET", "/v1/products", nil)
+			for _, header := range test.headers {
+				r.Header.Set(header, "credentials")
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != test.wantStatus {
+				t.Fatalf("status, got = %d, want = %d", w.Code, test.wantStatus)
+			}
+			if principal != test.wantPrincipal {
+				t.Errorf("principal, got = %q, want = %q", principal, test.wantPrincipal)
+			}
+			var calls []int
+			for _, a := range authenticators {
+				calls = append(calls, a.calls)
+			}
+			if !reflect.DeepEqual(calls, test.wantCalls) {
+				t.Errorf("authenticator calls, got = %v, want = %v", calls, test.wantCalls)
+			}
+			if got := w.Header().Values("WWW-Authenticate"); !reflect.DeepEqual(got, test.wantChallenges) {
+				t.Errorf("WWW-Authenticate, got = %q, want = %q", got, test.wantChallenges)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Package money parses prices as written by merchandisers on spreadsheets,
+// e.g. "Rp 12.500" or "12500.50", to storage amounts.
+package money
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model"
+
+	// protobuf
+	mpb "github.com/dropezy/proto/meta"
+)
+
+// decimals is the number of decimal digits stored on amount nums,
+// e.g. Rp 12.500 is stored as 1250000.
+const decimals = 2
+
+var (
+	ErrInvalidAmount       = errors.New("amount is not a valid number")
+	ErrTooManyDecimals     = errors.New("amount has more than 2 decimal digits")
+	ErrUnsupportedCurrency = errors.New("currency is not supported")
+	ErrCurrencyMismatch    = errors.New("amount currency doesn't match the currency")
+)
+
+// currencySymbols maps the currency symbols used as amount prefix to their ISO 4217 code.
+var currencySymbols = map[string]string{
+	"RP": "IDR",
+}
+
+// ParseCurrency parses an ISO 4217 currency code or symbol, e.g. IDR or Rp.
+func ParseCurrency(code string) (mpb.Currency, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if iso, ok := currencySymbols[code]; ok {
+		code = iso
+	}
+	cur, ok := mpb.Currency_value["CURRENCY_"+code]
+	if !ok || cur == int32(mpb.Currency_CURRENCY_UNSPECIFIED) {
+		return mpb.Currency_CURRENCY_UNSPECIFIED, ErrUnsupportedCurrency
+	}
+	return mpb.Currency(cur), nil
+}
+
+// Parse parses value to an amount of cur. The value may be prefixed by a currency
+// code or symbol matching cur, and may use either dot or comma as thousands and
+// decimal separators, e.g. "12.500", "12,500.50", "Rp 12.500,50" or "IDR 12500".
+// A single separator followed by exactly 3 digits is a thousands separator.
+func Parse(value string, cur mpb.Currency) (*model.Amount, error) {
+	s := strings.TrimSpace(value)
+
+	// check currency prefix, e.g. Rp, Rp. or IDR.
+	idx := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsLetter(r) })
+	if idx < 0 {
+		return nil, ErrInvalidAmount
+	}
+	if idx > 0 {
+		prefixCur, err := ParseCurrency(s[:idx])
+		if err != nil {
+			return nil, err
+		}
+		if prefixCur != cur {
+			return nil, ErrCurrencyMismatch
+		}
+		s = strings.TrimSpace(strings.TrimPrefix(s[idx:], "."))
+	}
+
+	num, err := parseNum(s)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Amount{
+		Num: num,
+		Cur: cur,
+	}, nil
+}
+
+// parseNum converts a positive number with thousands and decimal separators
+// to its value in hundredths.
+func parseNum(s string) (string, error) {
+	if s == "" || strings.Trim(s, "0123456789.,") != "" {
+		return "", ErrInvalidAmount
+	}
+
+	// the decimal separator is the last separator when both are used, or a single
+	// separator which is not followed by exactly 3 digits.
+	decimalIdx := -1
+	thousandsSep := "."
+	lastDot, lastComma := strings.LastIndex(s, "."), strings.LastIndex(s, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		decimalIdx = lastDot
+		if lastComma > lastDot {
+			decimalIdx = lastComma
+		}
+	case lastDot >= 0 || lastComma >= 0:
+		sepIdx := lastDot
+		if lastComma >= 0 {
+			sepIdx = lastComma
+		}
+		if strings.Count(s, s[sepIdx:sepIdx+1]) == 1 && len(s)-sepIdx-1 != 3 {
+			decimalIdx = sepIdx
+		}
+	}
+
+	intPart, decPart := s, ""
+	if decimalIdx >= 0 {
+		intPart, decPart = s[:decimalIdx], s[decimalIdx+1:]
+		if s[decimalIdx] == '.' {
+			thousandsSep = ","
+		}
+	} else if lastComma >= 0 {
+		thousandsSep = ","
+	}
+
+	// thousands groups must have exactly 3 digits except the first group.
+	groups := strings.Split(intPart, thousandsSep)
+	for i, g := range groups {
+		if g == "" || strings.Trim(g, "0123456789") != "" || (i > 0 && len(g) != 3) || (i == 0 && len(groups) > 1 && len(g) > 3) {
+			return "", ErrInvalidAmount
+		}
+	}
+	if decimalIdx >= 0 && (decPart == "" || strings.Trim(decPart, "0123456789") != "") {
+		return "", ErrInvalidAmount
+	}
+	if len(decPart) > decimals {
+		return "", ErrTooManyDecimals
+	}
+
+	num := strings.TrimLeft(strings.Join(groups, "")+decPart+strings.Repeat("0", decimals-len(decPart)), "0")
+	if num == "" {
+		num = "0"
+	}
+	return num, nil
+}
@@ -0,0 +1,68 @@
+package money
+
+import (
+	"errors"
+	"testing"
+
+	// protobuf
+	mpb "github.com/dropezy/proto/meta"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value   string
+		wantNum string
+		wantErr error
+	}{
+		{value: "12500", wantNum: "1250000"},
+		{value: "12.500", wantNum: "1250000"},
+		{value: "12,500", wantNum: "1250000"},
+		{value: "1.250.000", wantNum: "125000000"},
+		{value: "12500.50", wantNum: "1250050"},
+		{value: "12500,5", wantNum: "1250050"},
+		{value: "12.500,50", wantNum: "1250050"},
+		{value: "12,500.50", wantNum: "1250050"},
+		{value: "Rp 12.500", wantNum: "1250000"},
+		{value: "Rp. 12.500", wantNum: "1250000"},
+		{value: "IDR12500", wantNum: "1250000"},
+		{value: "0", wantNum: "0"},
+		{value: "", wantErr: ErrInvalidAmount},
+		{value: "abc", wantErr: ErrInvalidAmount},
+		{value: "-12500", wantErr: ErrInvalidAmount},
+		{value: "12.50.0", wantErr: ErrInvalidAmount},
+		{value: "1,2.500", wantErr: ErrInvalidAmount},
+		{value: "12500.", wantErr: ErrInvalidAmount},
+		{value: "12500.505", wantErr: ErrInvalidAmount},
+		{value: "12,500.505", wantErr: ErrTooManyDecimals},
+		{value: "XYZ 12", wantErr: ErrUnsupportedCurrency},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.value, func(t *testing.T) {
+			t.Parallel()
+
+			amount, err := Parse(test.value, mpb.Currency_CURRENCY_IDR)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Parse(%q, _) error, got = %v, want = %v", test.value, err, test.wantErr)
+			}
+			if err == nil && (amount.Num != test.wantNum || amount.Cur != mpb.Currency_CURRENCY_IDR) {
+				t.Fatalf("Parse(%q, _), got = %+v, want = %s", test.value, amount, test.wantNum)
+			}
+		})
+	}
+}
+
+func TestParseCurrency(t *testing.T) {
+	t.Parallel()
+
+	for _, code := range []string{"IDR", "idr", "Rp"} {
+		if cur, err := ParseCurrency(code); err != nil || cur != mpb.Currency_CURRENCY_IDR {
+			t.Fatalf("ParseCurrency(%q), got = %v, %v, want = %v", code, cur, err, mpb.Currency_CURRENCY_IDR)
+		}
+	}
+	if _, err := ParseCurrency("UNSPECIFIED"); !errors.Is(err, ErrUnsupportedCurrency) {
+		t.Fatalf("ParseCurrency(_) error, got = %v, want = %v", err, ErrUnsupportedCurrency)
+	}
+}
@@ -0,0 +1,187 @@
+// Package apikey implements API key gRPC service methods
+// to manage the API keys of integrations.
+package apikey
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/dropezy/storefront-backend/ems-api/services"
+	"github.com/dropezy/storefront-backend/ems-api/services/audit"
+	"github.com/dropezy/storefront-backend/ems-api/services/auth"
+	"github.com/dropezy/storefront-backend/ems-api/validation"
+
+	// protobuf
+	akpb "github.com/dropezy/proto/ems/v1/apikey"
+)
+
+const serviceName = "apikey"
+
+// Handler holds API key gRPC service implementation.
+type Handler struct {
+	// utilities
+	logger zerolog.Logger
+
+	// service dependencies
+	store Store
+}
+
+// NewHandler returns a new API key service handler.
+func NewHandler(logger zerolog.Logger, store Store) *Handler {
+	return &Handler{
+		logger: logger.With().Str("service", serviceName).Logger(),
+		store:  store,
+	}
+}
+
+// RegisterService registers the API key service to the gRPC server.
+func RegisterService(logger zerolog.Logger, store Store) func(srv *grpc.Server) error {
+	return func(srv *grpc.Server) error {
+		h := NewHandler(logger, store)
+		akpb.RegisterApiKeyServiceServer(srv, h)
+		return nil
+	}
+}
+
+func RegisterGateway(ctx context.Context, mux *runtime.ServeMux, addr string, opts []grpc.DialOption) error {
+	return akpb.RegisterApiKeyServiceHandlerFromEndpoint(ctx, mux, addr, opts)
+}
+
+// List will fetch API keys from storage, neither the keys nor their hashes are returned.
+func (h *Handler) List(ctx context.Context, req *akpb.ListRequest) (*akpb.ListResponse, error) {
+	keys, err := h.store.ListAPIKeys(ctx, req.GetIncludeRevoked())
+	if err != nil {
+		h.logger.Err(err).Msg("failed to fetch api keys from store")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	var keysPb []*akpb.ApiKey
+	for _, k := range keys {
+		keysPb = append(keysPb, toAPIKeyPb(k))
+	}
+
+	return &akpb.ListResponse{
+		ApiKeys: keysPb,
+	}, nil
+}
+
+// Create will validate and insert a new API key, the key is only
+// returned in the response and stored hashed.
+func (h *Handler) Create(ctx context.Context, req *akpb.CreateRequest) (*akpb.CreateResponse, error) {
+	if req.GetApiKey() == nil {
+		return nil, status.Error(codes.InvalidArgument, ErrAPIKeyIsRequired.Error())
+	}
+
+	k := fromAPIKeyPb(req.GetApiKey())
+	if err := validation.ValidateAPIKey(k); err != nil {
+		return nil, services.InvalidArgumentError(err)
+	}
+
+	key, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		h.logger.Err(err).Msg("failed to generate api key")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	k.ID = primitive.NewObjectID()
+	k.Prefix = prefix
+	k.KeyHash = auth.HashAPIKey(key)
+	k.CreatedAt = time.Now().UTC()
+	if p := auth.FromContext(ctx); p != nil {
+		k.CreatedBy = p.Name
+	}
+
+	if err := h.store.CreateAPIKey(ctx, k); err != nil {
+		h.logger.Err(err).Msg("failed to create api key on store")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	audit.Track(ctx, audit.EntityAPIKey, k.ID.Hex(), nil, k)
+
+	return &akpb.CreateResponse{
+		ApiKey: toAPIKeyPb(k),
+		Key:    key,
+	}, nil
+}
+
+// Revoke will revoke an API key, revoked keys are kept for the records.
+// Servers keep trusting the keys they verified for up to a minute,
+// so a revoked key may still be accepted during that time.
+func (h *Handler) Revoke(ctx context.Context, req *akpb.RevokeRequest) (*akpb.RevokeResponse, error) {
+	id, err := primitive.ObjectIDFromHex(req.GetApiKeyId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidAPIKeyID.Error())
+	}
+
+	k, err := h.store.GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch api key from store")
+	}
+	if k.RevokedAt != nil {
+		return nil, status.Error(codes.FailedPrecondition, ErrAPIKeyAlreadyRevoked.Error())
+	}
+
+	now := time.Now().UTC()
+	if err := h.store.RevokeAPIKey(ctx, id, now); err != nil {
+		return nil, h.toStatusError(err, "failed to revoke api key on store")
+	}
+	before := *k
+	k.RevokedAt = &now
+	audit.Track(ctx, audit.EntityAPIKey, k.ID.Hex(), &before, k)
+
+	return &akpb.RevokeResponse{
+		ApiKey: toAPIKeyPb(k),
+	}, nil
+}
+
+// toStatusError converts store errors to gRPC status errors,
+// unexpected errors are logged with msg.
+func (h *Handler) toStatusError(err error, msg string) error {
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	h.logger.Err(err).Msg(msg)
+	return status.Error(codes.Internal, err.Error())
+}
+
+func toAPIKeyPb(k *auth.APIKey) *akpb.ApiKey {
+	return &akpb.ApiKey{
+		ApiKeyId:   k.ID.Hex(),
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		StoreIds:   k.StoreIDs,
+		ExpiresAt:  toTimestampPb(k.ExpiresAt),
+		LastUsedAt: toTimestampPb(k.LastUsedAt),
+		RevokedAt:  toTimestampPb(k.RevokedAt),
+		CreatedBy:  k.CreatedBy,
+		CreatedAt:  timestamppb.New(k.CreatedAt),
+	}
+}
+
+func fromAPIKeyPb(pb *akpb.ApiKey) *auth.APIKey {
+	k := &auth.APIKey{
+		Name:     pb.GetName(),
+		Scopes:   pb.GetScopes(),
+		StoreIDs: pb.GetStoreIds(),
+	}
+	if pb.GetExpiresAt() != nil {
+		expiresAt := pb.GetExpiresAt().AsTime()
+		k.ExpiresAt = &expiresAt
+	}
+	return k
+}
+
+func toTimestampPb(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
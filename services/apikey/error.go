@@ -0,0 +1,10 @@
+package apikey
+
+import "errors"
+
+var (
+	ErrInvalidAPIKeyID      = errors.New("invalid api key id")
+	ErrAPIKeyIsRequired     = errors.New("api key is required")
+	ErrAPIKeyNotFound       = errors.New("api key not found")
+	ErrAPIKeyAlreadyRevoked = errors.New("api key is already revoked")
+)
@@ -0,0 +1,114 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/dropezy/storefront-backend/ems-api/services/auth"
+)
+
+const apiKeyCollection = "api_key"
+
+// Store is the storage contract required by the API key service.
+type Store interface {
+	ListAPIKeys(ctx context.Context, includeRevoked bool) ([]*auth.APIKey, error)
+	GetAPIKey(ctx context.Context, id primitive.ObjectID) (*auth.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*auth.APIKey, error)
+	CreateAPIKey(ctx context.Context, k *auth.APIKey) error
+	RevokeAPIKey(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	TouchAPIKey(ctx context.Context, id primitive.ObjectID, at time.Time) error
+}
+
+// MongoStore implements Store backed by mongo database.
+type MongoStore struct {
+	db *mongo.Database
+}
+
+// NewMongoStore returns a new API key store backed by db.
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{db: db}
+}
+
+// ListAPIKeys fetches the API keys ordered by their creation,
+// revoked keys are only fetched when includeRevoked is set.
+func (s *MongoStore) ListAPIKeys(ctx context.Context, includeRevoked bool) ([]*auth.APIKey, error) {
+	filter := bson.M{}
+	if !includeRevoked {
+		filter["revoked_at"] = bson.M{"$exists": false}
+	}
+	cur, err := s.db.Collection(apiKeyCollection).Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Find api keys: %w", err)
+	}
+
+	var keys []*auth.APIKey
+	if err := cur.All(ctx, &keys); err != nil {
+		return nil, fmt.Errorf("failed to decode api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// GetAPIKey fetches an API key by its id.
+func (s *MongoStore) GetAPIKey(ctx context.Context, id primitive.ObjectID) (*auth.APIKey, error) {
+	return s.findAPIKey(ctx, bson.M{"_id": id})
+}
+
+// GetAPIKeyByHash fetches an API key by the hash of the key.
+func (s *MongoStore) GetAPIKeyByHash(ctx context.Context, hash string) (*auth.APIKey, error) {
+	return s.findAPIKey(ctx, bson.M{"key_hash": hash})
+}
+
+func (s *MongoStore) findAPIKey(ctx context.Context, filter bson.M) (*auth.APIKey, error) {
+	k := &auth.APIKey{}
+	if err := s.db.Collection(apiKeyCollection).FindOne(ctx, filter).Decode(k); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to execute FindOne api key: %w", err)
+	}
+	return k, nil
+}
+
+// CreateAPIKey inserts a new API key.
+func (s *MongoStore) CreateAPIKey(ctx context.Context, k *auth.APIKey) error {
+	if _, err := s.db.Collection(apiKeyCollection).InsertOne(ctx, k); err != nil {
+		return fmt.Errorf("failed to execute InsertOne api key: %w", err)
+	}
+	return nil
+}
+
+// RevokeAPIKey marks an API key as revoked at the given time.
+func (s *MongoStore) RevokeAPIKey(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	res, err := s.db.Collection(apiKeyCollection).UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"revoked_at": at}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute UpdateOne api key: %w", err)
+	}
+	if res.MatchedCount < 1 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// TouchAPIKey records the last use of an API key.
+func (s *MongoStore) TouchAPIKey(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := s.db.Collection(apiKeyCollection).UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$max": bson.M{"last_used_at": at}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute UpdateOne api key: %w", err)
+	}
+	return nil
+}
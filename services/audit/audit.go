@@ -0,0 +1,150 @@
+// Package audit records who changed what through the mutating
+// ems gRPC calls and the importer runs, and implements the audit
+// gRPC service methods to query the records.
+package audit
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/dropezy/storefront-backend/ems-api/services/pagination"
+
+	// protobuf
+	adpb "github.com/dropezy/proto/ems/v1/audit"
+)
+
+const serviceName = "audit"
+
+// Handler holds audit gRPC service implementation.
+type Handler struct {
+	// utilities
+	logger zerolog.Logger
+
+	// service dependencies
+	store Store
+}
+
+// NewHandler returns a new audit service handler.
+func NewHandler(logger zerolog.Logger, store Store) *Handler {
+	return &Handler{
+		logger: logger.With().Str("service", serviceName).Logger(),
+		store:  store,
+	}
+}
+
+// RegisterService registers the audit service to the gRPC server.
+func RegisterService(logger zerolog.Logger, store Store) func(srv *grpc.Server) error {
+	return func(srv *grpc.Server) error {
+		h := NewHandler(logger, store)
+		adpb.RegisterAuditServiceServer(srv, h)
+		return nil
+	}
+}
+
+func RegisterGateway(ctx context.Context, mux *runtime.ServeMux, addr string, opts []grpc.DialOption) error {
+	return adpb.RegisterAuditServiceHandlerFromEndpoint(ctx, mux, addr, opts)
+}
+
+// List will fetch a page of audit entries matching the request filters
+// from storage, from the most recent.
+func (h *Handler) List(ctx context.Context, req *adpb.ListRequest) (*adpb.ListResponse, error) {
+	before, err := pagination.DecodeToken(req.GetPageToken())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	filter := &ListFilter{
+		Entity:    req.GetEntity(),
+		EntityID:  req.GetEntityId(),
+		Principal: req.GetPrincipal(),
+		Before:    before,
+		// fetch one extra entry to know whether there is a next page.
+		Limit: pagination.PageSize(req.GetPageSize()) + 1,
+	}
+	if req.GetStartTime() != nil {
+		filter.StartTime = req.GetStartTime().AsTime()
+	}
+	if req.GetEndTime() != nil {
+		filter.EndTime = req.GetEndTime().AsTime()
+	}
+	if !filter.StartTime.IsZero() && !filter.EndTime.IsZero() && !filter.StartTime.Before(filter.EndTime) {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidTimeRange.Error())
+	}
+
+	entries, err := h.store.ListEntries(ctx, filter)
+	if err != nil {
+		h.logger.Err(err).Msg("failed to fetch audit entries from store")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	var nextPageToken string
+	if pageSize := filter.Limit - 1; int64(len(entries)) > pageSize {
+		entries = entries[:pageSize]
+		nextPageToken = pagination.EncodeToken(entries[pageSize-1].ID)
+	}
+
+	var entriesPb []*adpb.Entry
+	for _, e := range entries {
+		entriesPb = append(entriesPb, h.toEntryPb(e))
+	}
+
+	return &adpb.ListResponse{
+		Entries:       entriesPb,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+func (h *Handler) toEntryPb(e *Entry) *adpb.Entry {
+	pb := &adpb.Entry{
+		EntryId:   e.ID.Hex(),
+		Operation: e.Operation,
+		Code:      e.Code,
+		ClientIp:  e.ClientIP,
+		Timestamp: timestamppb.New(e.Timestamp),
+	}
+	if e.Principal != nil {
+		pb.Principal = &adpb.Principal{
+			Id:     e.Principal.ID,
+			Name:   e.Principal.Name,
+			Role:   e.Principal.Role,
+			ApiKey: e.Principal.APIKey,
+		}
+	}
+	for _, c := range e.Changes {
+		pb.Changes = append(pb.Changes, &adpb.Change{
+			Entity:   c.Entity,
+			EntityId: c.EntityID,
+			Before:   h.toStructPb(c.Before),
+			After:    h.toStructPb(c.After),
+			Fields:   c.Fields,
+		})
+	}
+	return pb
+}
+
+// toStructPb converts a snapshot to a struct through its relaxed extended JSON,
+// e.g. ObjectIDs are converted to {"$oid": "..."}.
+func (h *Handler) toStructPb(doc bson.Raw) *structpb.Struct {
+	if len(doc) == 0 {
+		return nil
+	}
+	b, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		h.logger.Err(err).Msg("failed to convert audit snapshot to json")
+		return nil
+	}
+	s := &structpb.Struct{}
+	if err := protojson.Unmarshal(b, s); err != nil {
+		h.logger.Err(err).Msg("failed to convert audit snapshot to struct")
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestNewChange(t *testing.T) {
+	t.Parallel()
+
+	type user struct {
+		Username     string `bson:"username"`
+		PasswordHash string `bson:"password_hash"`
+		Role         string `bson:"role"`
+		Disabled     bool   `bson:"disabled"`
+	}
+	before := &user{Username: "jane", PasswordHash: "hash", Role: "viewer"}
+	after := &user{Username: "jane", PasswordHash: "new-hash", Role: "merchandiser", Disabled: true}
+
+	tests := []struct {
+		name       string
+		before     interface{}
+		after      interface{}
+		wantFields []string
+	}{
+		{name: "Created", after: after, wantFields: []string{"disabled", "role", "username"}},
+		{name: "Updated", before: before, after: after, wantFields: []string{"disabled", "role"}},
+		{name: "Deleted", before: before, wantFields: []string{"disabled", "role", "username"}},
+		{name: "Unchanged", before: before, after: before},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := NewChange(EntityUser, "42", test.before, test.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.Fields, test.wantFields) {
+				t.Errorf("NewChange() fields = %v, want %v", c.Fields, test.wantFields)
+			}
+			for _, doc := range []bson.Raw{c.Before, c.After} {
+				if _, err := doc.LookupErr("password_hash"); len(doc) > 0 && err == nil {
+					t.Errorf("NewChange() snapshot %s holds the password hash", doc)
+				}
+			}
+			if (test.before == nil) != (c.Before == nil) || (test.after == nil) != (c.After == nil) {
+				t.Errorf("NewChange() before = %s, after = %s", c.Before, c.After)
+			}
+		})
+	}
+}
+
+func TestTrack(t *testing.T) {
+	t.Parallel()
+
+	// calls which aren't audited are ignored.
+	Track(context.Background(), EntityBrand, "1", nil, bson.M{"name": "Dropezy"})
+
+	r := &recorder{}
+	ctx := newContext(context.Background(), r)
+	Track(ctx, EntityBrand, "1", nil, bson.M{"name": "Dropezy"})
+	Track(ctx, EntityBrand, "2", bson.M{"name": "Old"}, bson.M{"name": "New"})
+	if len(r.changes) != 2 || r.changes[1].EntityID != "2" || !reflect.DeepEqual(r.changes[1].Fields, []string{"name"}) {
+		t.Errorf("Track() changes = %+v", r.changes)
+	}
+}
+
+func TestMutating(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]bool{
+		"/ems.v1.brand.BrandService/Create":       true,
+		"/ems.v1.category.CategoryService/Move":   true,
+		"/ems.v1.apikey.ApiKeyService/Revoke":     true,
+		"/ems.v1.inventory.InventoryService/List": false,
+		"/ems.v1.product.ProductService/GetBySKU": false,
+		"/grpc.health.v1.Health/Check":            false,
+	}
+	for method, want := range tests {
+		if got := mutating(method); got != want {
+			t.Errorf("mutating(%s) = %t, want %t", method, got, want)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	t.Parallel()
+
+	var (
+		remote   = &net.TCPAddr{IP: net.ParseIP("10.0.0.7"), Port: 52311}
+		loopback = &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 52311}
+		// the gateway appends the address it was called from to the caller header.
+		forwarded = metadata.Pairs("x-forwarded-for", "198.51.100.1, 203.0.113.9")
+	)
+
+	tests := []struct {
+		name string
+		addr net.Addr
+		md   metadata.MD
+		want string
+	}{
+		{name: "Peer", addr: remote, want: "10.0.0.7"},
+		{name: "Gateway", addr: loopback, md: forwarded, want: "203.0.113.9"},
+		{name: "GatewayWithoutHeader", addr: loopback, want: "127.0.0.1"},
+		{name: "SpoofedHeader", addr: remote, md: forwarded, want: "10.0.0.7"},
+		{name: "NoPeer", md: forwarded},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			if test.addr != nil {
+				ctx = peer.NewContext(ctx, &peer.Peer{Addr: test.addr})
+			}
+			if test.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, test.md)
+			}
+			if got := clientIP(ctx); got != test.want {
+				t.Errorf("clientIP() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
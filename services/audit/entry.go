@@ -0,0 +1,171 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/dropezy/storefront-backend/ems-api/services/auth"
+)
+
+// Entity names of the changes.
+const (
+	EntityAPIKey      = "api_key"
+	EntityBrand       = "brand"
+	EntityCategory    = "category"
+	EntityImportRun   = "import_run"
+	EntityInventory   = "inventory"
+	EntityProduct     = "product"
+	EntityStore       = "store"
+	EntityUser        = "user"
+	EntityVariantType = "variant_type"
+)
+
+// redactedFields are removed from the snapshots, so secrets never reach the audit log.
+var redactedFields = map[string]bool{
+	"password_hash": true,
+	"key_hash":      true,
+}
+
+// Entry records a mutating call or an import run.
+type Entry struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	Principal *Principal         `bson:"principal,omitempty"`
+	// Operation is the full RPC method name, or the importer operation,
+	// e.g. "/ems.v1.brand.BrandService/Update" or "importer/product".
+	Operation string `bson:"operation"`
+	// Code is the gRPC status code of the call, or the outcome of an import run.
+	Code      string    `bson:"code"`
+	ClientIP  string    `bson:"client_ip,omitempty"`
+	Changes   []*Change `bson:"changes"`
+	Timestamp time.Time `bson:"timestamp"`
+}
+
+// Principal is the caller who made the change.
+type Principal struct {
+	ID     string `bson:"id,omitempty"`
+	Name   string `bson:"name"`
+	Role   string `bson:"role,omitempty"`
+	APIKey bool   `bson:"api_key,omitempty"`
+}
+
+// Change is the change of an entity, Before is empty for created
+// entities and After for deleted ones.
+type Change struct {
+	Entity   string   `bson:"entity"`
+	EntityID string   `bson:"entity_id"`
+	Before   bson.Raw `bson:"before,omitempty"`
+	After    bson.Raw `bson:"after,omitempty"`
+	// Fields are the top level fields which differ between Before and After.
+	Fields []string `bson:"fields,omitempty"`
+}
+
+// NewPrincipal returns the audit principal of p, nil for unauthenticated calls.
+func NewPrincipal(p *auth.Principal) *Principal {
+	if p == nil {
+		return nil
+	}
+	return &Principal{ID: p.ID, Name: p.Name, Role: string(p.Role), APIKey: p.APIKey}
+}
+
+// NewChange returns the change of the entity from before to after,
+// before or after being nil when the entity is created or deleted.
+func NewChange(entity, id string, before, after interface{}) (*Change, error) {
+	c := &Change{Entity: entity, EntityID: id}
+	var err error
+	if c.Before, err = snapshot(before); err != nil {
+		return nil, err
+	}
+	if c.After, err = snapshot(after); err != nil {
+		return nil, err
+	}
+	c.Fields = diff(c.Before, c.After)
+	return c, nil
+}
+
+// snapshot marshals v to a BSON document without the redacted fields.
+func snapshot(v interface{}) (bson.Raw, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	elems, err := bson.Raw(b).Elements()
+	if err != nil {
+		return nil, err
+	}
+
+	doc := bson.D{}
+	for _, e := range elems {
+		if !redactedFields[e.Key()] {
+			doc = append(doc, bson.E{Key: e.Key(), Value: e.Value()})
+		}
+	}
+	return bson.Marshal(doc)
+}
+
+// diff returns the sorted top level fields which differ between the documents.
+func diff(before, after bson.Raw) []string {
+	values := func(doc bson.Raw) map[string]bson.RawValue {
+		m := map[string]bson.RawValue{}
+		elems, _ := doc.Elements()
+		for _, e := range elems {
+			m[e.Key()] = e.Value()
+		}
+		return m
+	}
+	b, a := values(before), values(after)
+
+	var fields []string
+	for key, bv := range b {
+		if av, ok := a[key]; !ok || av.Type != bv.Type || !bytes.Equal(av.Value, bv.Value) {
+			fields = append(fields, key)
+		}
+	}
+	for key := range a {
+		if _, ok := b[key]; !ok {
+			fields = append(fields, key)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// recorder collects the changes made by a call.
+type recorder struct {
+	mu      sync.Mutex
+	changes []*Change
+}
+
+type recorderKey struct{}
+
+// newContext returns a copy of ctx collecting the changes of the call in r.
+func newContext(ctx context.Context, r *recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, r)
+}
+
+// Track records the change of an entity on the audit entry of the call of ctx,
+// before is nil for created entities and after for deleted ones. Calls which
+// aren't audited, e.g. read calls, are ignored.
+func Track(ctx context.Context, entity, id string, before, after interface{}) {
+	r, ok := ctx.Value(recorderKey{}).(*recorder)
+	if !ok {
+		return
+	}
+
+	c, err := NewChange(entity, id, before, after)
+	if err != nil {
+		// the entity is still recorded, without its snapshots.
+		c = &Change{Entity: entity, EntityID: id}
+	}
+	r.mu.Lock()
+	r.changes = append(r.changes, c)
+	r.mu.Unlock()
+}
@@ -0,0 +1,7 @@
+package audit
+
+import "errors"
+
+var (
+	ErrInvalidTimeRange = errors.New("start time must be before end time")
+)
@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/dropezy/storefront-backend/ems-api/services/auth"
+)
+
+// writeTimeout bounds the write of an entry, which outlives the call context.
+const writeTimeout = 5 * time.Second
+
+// mutatingPrefixes are the prefixes of the mutating method names.
+var mutatingPrefixes = []string{"Create", "Update", "Delete", "Move", "Revoke"}
+
+// mutating reports whether the full RPC method name changes data.
+func mutating(method string) bool {
+	name := method[strings.LastIndex(method, "/")+1:]
+	for _, prefix := range mutatingPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnaryServerInterceptor writes an audit entry for every mutating call, along with
+// the changes tracked by the handlers. It must run before the authorization
+// interceptor so denied calls are audited as well.
+func UnaryServerInterceptor(logger zerolog.Logger, store Store) grpc.UnaryServerInterceptor {
+	logger = logger.With().Str("service", serviceName).Logger()
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !mutating(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		r := &recorder{}
+		resp, err := handler(newContext(ctx, r), req)
+
+		r.mu.Lock()
+		changes := r.changes
+		r.mu.Unlock()
+		if changes == nil {
+			changes = []*Change{}
+		}
+		e := &Entry{
+			ID:        primitive.NewObjectID(),
+			Principal: NewPrincipal(auth.FromContext(ctx)),
+			Operation: info.FullMethod,
+			Code:      status.Code(err).String(),
+			ClientIP:  clientIP(ctx),
+			Changes:   changes,
+			Timestamp: time.Now().UTC(),
+		}
+
+		writeCtx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+		defer cancel()
+		if werr := store.CreateEntry(writeCtx, e); werr != nil {
+			logger.Err(werr).Str("operation", e.Operation).Msg("failed to write audit entry")
+		}
+		return resp, err
+	}
+}
+
+// clientIP returns the IP of the caller. Gateway calls are made by the server itself
+// over loopback, so their client IP is the last x-forwarded-for hop, which the gateway
+// appends from the address it was called from. Earlier hops are set by the callers and
+// the header of other callers is ignored, so they can't be spoofed.
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return host
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("x-forwarded-for"); len(values) > 0 {
+			hops := strings.Split(values[len(values)-1], ",")
+			if ip := strings.TrimSpace(hops[len(hops)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	return host
+}
@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const auditLogCollection = "audit_log"
+
+// Store is the storage contract required by the audit service.
+type Store interface {
+	ListEntries(ctx context.Context, filter *ListFilter) ([]*Entry, error)
+	CreateEntry(ctx context.Context, e *Entry) error
+}
+
+// ListFilter holds the parameters to filter and paginate audit entries.
+type ListFilter struct {
+	Entity   string
+	EntityID string
+	// Principal matches the id or the name of the principal.
+	Principal string
+	// StartTime and EndTime bound the entry timestamps, when not zero.
+	StartTime time.Time
+	EndTime   time.Time
+
+	// Before is the id of the last entry on the previous page,
+	// entries are listed from the most recent.
+	Before primitive.ObjectID
+	Limit  int64
+}
+
+// MongoStore implements Store backed by mongo database.
+type MongoStore struct {
+	db *mongo.Database
+}
+
+// NewMongoStore returns a new audit store backed by db.
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{db: db}
+}
+
+// CreateIndexes creates the indexes of the audit log collection, entries are
+// listed by the changed entity, by the principal and by their timestamp.
+func (s *MongoStore) CreateIndexes(ctx context.Context) error {
+	if _, err := s.db.Collection(auditLogCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "changes.entity", Value: 1}, {Key: "changes.entity_id", Value: 1}}},
+		{Keys: bson.D{{Key: "principal.id", Value: 1}}},
+		{Keys: bson.D{{Key: "principal.name", Value: 1}}},
+		{Keys: bson.D{{Key: "timestamp", Value: -1}}},
+	}); err != nil {
+		return fmt.Errorf("failed to create audit log indexes: %w", err)
+	}
+	return nil
+}
+
+// ListEntries fetches the entries matching the filter from the most recent.
+func (s *MongoStore) ListEntries(ctx context.Context, filter *ListFilter) ([]*Entry, error) {
+	query := bson.M{}
+	if filter.Before != primitive.NilObjectID {
+		query["_id"] = bson.M{"$lt": filter.Before}
+	}
+	if filter.Entity != "" || filter.EntityID != "" {
+		change := bson.M{}
+		if filter.Entity != "" {
+			change["entity"] = filter.Entity
+		}
+		if filter.EntityID != "" {
+			change["entity_id"] = filter.EntityID
+		}
+		query["changes"] = bson.M{"$elemMatch": change}
+	}
+	if filter.Principal != "" {
+		query["$or"] = bson.A{
+			bson.M{"principal.id": filter.Principal},
+			bson.M{"principal.name": filter.Principal},
+		}
+	}
+	timestamp := bson.M{}
+	if !filter.StartTime.IsZero() {
+		timestamp["$gte"] = filter.StartTime
+	}
+	if !filter.EndTime.IsZero() {
+		timestamp["$lt"] = filter.EndTime
+	}
+	if len(timestamp) > 0 {
+		query["timestamp"] = timestamp
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(filter.Limit)
+
+	cur, err := s.db.Collection(auditLogCollection).Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Find audit entries: %w", err)
+	}
+
+	var entries []*Entry
+	if err := cur.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode audit entries: %w", err)
+	}
+	return entries, nil
+}
+
+// CreateEntry inserts a new audit entry.
+func (s *MongoStore) CreateEntry(ctx context.Context, e *Entry) error {
+	if _, err := s.db.Collection(auditLogCollection).InsertOne(ctx, e); err != nil {
+		return fmt.Errorf("failed to execute InsertOne audit entry: %w", err)
+	}
+	return nil
+}
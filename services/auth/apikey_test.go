@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	// protobuf
+	akpb "github.com/dropezy/proto/ems/v1/apikey"
+	brpb "github.com/dropezy/proto/ems/v1/brand"
+	invpb "github.com/dropezy/proto/ems/v1/inventory"
+	stpb "github.com/dropezy/proto/ems/v1/store"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	t.Parallel()
+
+	key, prefix, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, prefix) || !strings.HasPrefix(prefix, apiKeyPrefix) {
+		t.Errorf("GenerateAPIKey() = %q, %q, want a key starting with its prefix", key, prefix)
+	}
+
+	other, _, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key == other || HashAPIKey(key) == HashAPIKey(other) {
+		t.Error("GenerateAPIKey() returned the same key twice")
+	}
+}
+
+func TestAPIKeyActive(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	tests := []struct {
+		name string
+		key  *APIKey
+		want bool
+	}{
+		{name: "NoExpiry", key: &APIKey{}, want: true},
+		{name: "NotExpired", key: &APIKey{ExpiresAt: &future}, want: true},
+		{name: "Expired", key: &APIKey{ExpiresAt: &past}},
+		{name: "Revoked", key: &APIKey{RevokedAt: &past}},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := test.key.Active(now); got != test.want {
+				t.Errorf("Active() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizeAPIKey(t *testing.T) {
+	t.Parallel()
+
+	var (
+		brandService = "/" + brpb.BrandService_ServiceDesc.ServiceName + "/"
+		apiKeyCreate = "/" + akpb.ApiKeyService_ServiceDesc.ServiceName + "/Create"
+	)
+	tests := []struct {
+		name     string
+		scopes   []string
+		method   string
+		wantCode codes.Code
+	}{
+		{name: "Method", scopes: []string{brandService + "List"}, method: brandService + "List", wantCode: codes.OK},
+		{name: "OtherMethod", scopes: []string{brandService + "List"}, method: brandService + "Create", wantCode: codes.PermissionDenied},
+		{name: "Service", scopes: []string{brandService + "*"}, method: brandService + "Create", wantCode: codes.OK},
+		{name: "ServicePrefix", scopes: []string{"/ems.v1.brand.Brand*"}, method: brandService + "Create", wantCode: codes.PermissionDenied},
+		{name: "DeniedService", scopes: []string{apiKeyCreate}, method: apiKeyCreate, wantCode: codes.PermissionDenied},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := NewContext(context.Background(), &Principal{Name: "sync", APIKey: true, Scopes: test.scopes})
+			if got := status.Code(Authorize(ctx, test.method)); got != test.wantCode {
+				t.Errorf("Authorize() code = %s, want %s", got, test.wantCode)
+			}
+		})
+	}
+}
+
+func TestAuthorizeStore(t *testing.T) {
+	t.Parallel()
+
+	var (
+		inventoryList = "/" + invpb.InventoryService_ServiceDesc.ServiceName + "/List"
+		storeList     = "/" + stpb.StoreService_ServiceDesc.ServiceName + "/List"
+		storeCreate   = "/" + stpb.StoreService_ServiceDesc.ServiceName + "/Create"
+		storeUpdate   = "/" + stpb.StoreService_ServiceDesc.ServiceName + "/Update"
+		brandList     = "/" + brpb.BrandService_ServiceDesc.ServiceName + "/List"
+	)
+
+	tests := []struct {
+		name     string
+		storeIDs []string
+		method   string
+		req      interface{}
+		wantCode codes.Code
+	}{
+		{name: "AnyStore", method: inventoryList, req: &invpb.ListRequest{StoreId: "b"}, wantCode: codes.OK},
+		{name: "AllowedStore", storeIDs: []string{"a"}, method: inventoryList, req: &invpb.ListRequest{StoreId: "a"}, wantCode: codes.OK},
+		{name: "OtherStore", storeIDs: []string{"a"}, method: inventoryList, req: &invpb.ListRequest{StoreId: "b"}, wantCode: codes.PermissionDenied},
+		{name: "EmptyStore", storeIDs: []string{"a"}, method: inventoryList, req: &invpb.ListRequest{}, wantCode: codes.PermissionDenied},
+		{name: "NestedAllowedStore", storeIDs: []string{"a"}, method: storeUpdate, req: &stpb.UpdateRequest{Store: &stpb.Store{StoreId: "a"}}, wantCode: codes.OK},
+		{name: "NestedOtherStore", storeIDs: []string{"a"}, method: storeUpdate, req: &stpb.UpdateRequest{Store: &stpb.Store{StoreId: "b"}}, wantCode: codes.PermissionDenied},
+		{name: "NestedMissingStore", storeIDs: []string{"a"}, method: storeUpdate, req: &stpb.UpdateRequest{}, wantCode: codes.PermissionDenied},
+		{name: "NewStore", storeIDs: []string{"a"}, method: storeCreate, req: &stpb.CreateRequest{Store: &stpb.Store{}}, wantCode: codes.PermissionDenied},
+		{name: "UnknownStoreRequest", storeIDs: []string{"a"}, method: inventoryList, req: &brpb.ListRequest{}, wantCode: codes.PermissionDenied},
+		{name: "FilteredList", storeIDs: []string{"a"}, method: storeList, req: &stpb.ListRequest{}, wantCode: codes.OK},
+		{name: "NotStoreService", storeIDs: []string{"a"}, method: brandList, req: &brpb.ListRequest{}, wantCode: codes.OK},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := NewContext(context.Background(), &Principal{Name: "sync", APIKey: true, StoreIDs: test.storeIDs})
+			if got := status.Code(AuthorizeStore(ctx, test.method, test.req)); got != test.wantCode {
+				t.Errorf("AuthorizeStore() code = %s, want %s", got, test.wantCode)
+			}
+		})
+	}
+}
+
+func TestStoreAllowed(t *testing.T) {
+	t.Parallel()
+
+	limited := NewContext(context.Background(), &Principal{Name: "sync", APIKey: true, StoreIDs: []string{"a"}})
+	if !StoreAllowed(limited, "a") || StoreAllowed(limited, "b") {
+		t.Errorf("StoreAllowed() of a principal limited to store a, want only a allowed")
+	}
+	unlimited := NewContext(context.Background(), &Principal{Name: "ops", Role: RoleViewer})
+	if !StoreAllowed(unlimited, "b") {
+		t.Errorf("StoreAllowed() of a principal without stores, want every store allowed")
+	}
+}
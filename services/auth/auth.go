@@ -0,0 +1,105 @@
+// Package auth holds the principals, roles and per-RPC permissions
+// used to authorize ems gRPC and gateway calls.
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidRole      = errors.New("invalid role")
+	ErrUnauthenticated  = errors.New("request is not authenticated")
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// Role grants access to a set of RPCs.
+type Role string
+
+const (
+	// RoleViewer can only call read RPCs.
+	RoleViewer Role = "viewer"
+	// RoleMerchandiser can also manage the catalog: products, categories, brands and variant types.
+	RoleMerchandiser Role = "merchandiser"
+	// RoleInventoryOperator can also adjust store inventories.
+	RoleInventoryOperator Role = "inventory-operator"
+	// RoleAdmin can call every RPC.
+	RoleAdmin Role = "admin"
+)
+
+// Roles are all the roles, from the least to the most privileged.
+var Roles = []Role{RoleViewer, RoleMerchandiser, RoleInventoryOperator, RoleAdmin}
+
+// ParseRole parses the role name s.
+func ParseRole(s string) (Role, error) {
+	for _, r := range Roles {
+		if string(r) == s {
+			return r, nil
+		}
+	}
+	return "", ErrInvalidRole
+}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// ID is the id of the user or API key.
+	ID string
+	// Name is the user or API key name.
+	Name string
+	// Role is the role of the principal, the most privileged one when it has several.
+	Role Role
+	// Roles are all the roles of principals with several roles, e.g. bearer tokens
+	// whose role claim maps to several roles, the principal can call the methods
+	// allowed to any of them.
+	Roles []Role
+
+	// APIKey reports whether the principal is authenticated with an API key,
+	// API keys are authorized by their scopes instead of a role.
+	APIKey bool
+	// Scopes are the RPCs allowed to API keys.
+	Scopes []string
+	// StoreIDs limits API keys to the requests of these stores, when not empty.
+	StoreIDs []string
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx holding the principal p.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal of ctx, or nil when the request is not authenticated.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// User is an ems user, authenticated with its username and password.
+type User struct {
+	ID           primitive.ObjectID `bson:"_id"`
+	Username     string             `bson:"username"`
+	PasswordHash string             `bson:"password_hash"`
+	Role         Role               `bson:"role"`
+	Disabled     bool               `bson:"disabled"`
+	CreatedAt    time.Time          `bson:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at"`
+}
+
+// HashPassword returns the bcrypt hash of password.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches the bcrypt hash.
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
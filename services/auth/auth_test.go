@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	// protobuf
+	brpb "github.com/dropezy/proto/ems/v1/brand"
+	invpb "github.com/dropezy/proto/ems/v1/inventory"
+	uspb "github.com/dropezy/proto/ems/v1/user"
+)
+
+func TestParseRole(t *testing.T) {
+	t.Parallel()
+
+	for _, r := range Roles {
+		got, err := ParseRole(string(r))
+		if err != nil || got != r {
+			t.Errorf("ParseRole(%q) = %q, %v, want %q", r, got, err, r)
+		}
+	}
+	if _, err := ParseRole("root"); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("ParseRole(root) error = %v, want %v", err, ErrInvalidRole)
+	}
+}
+
+func TestRoleAllowed(t *testing.T) {
+	t.Parallel()
+
+	var (
+		brandList       = "/" + brpb.BrandService_ServiceDesc.ServiceName + "/List"
+		brandCreate     = "/" + brpb.BrandService_ServiceDesc.ServiceName + "/Create"
+		inventoryUpdate = "/" + invpb.InventoryService_ServiceDesc.ServiceName + "/Update"
+		userCreate      = "/" + uspb.UserService_ServiceDesc.ServiceName + "/Create"
+		unknown         = "/ems.v1.unknown.UnknownService/Get"
+	)
+
+	tests := []struct {
+		name   string
+		role   Role
+		method string
+		want   bool
+	}{
+		{name: "ViewerRead", role: RoleViewer, method: brandList, want: true},
+		{name: "ViewerWrite", role: RoleViewer, method: brandCreate},
+		{name: "MerchandiserCatalog", role: RoleMerchandiser, method: brandCreate, want: true},
+		{name: "MerchandiserInventory", role: RoleMerchandiser, method: inventoryUpdate},
+		{name: "InventoryOperatorInventory", role: RoleInventoryOperator, method: inventoryUpdate, want: true},
+		{name: "InventoryOperatorCatalog", role: RoleInventoryOperator, method: brandCreate},
+		{name: "MerchandiserUsers", role: RoleMerchandiser, method: userCreate},
+		{name: "AdminUsers", role: RoleAdmin, method: userCreate, want: true},
+		{name: "AdminUnknown", role: RoleAdmin, method: unknown, want: true},
+		{name: "ViewerUnknown", role: RoleViewer, method: unknown},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := test.role.Allowed(test.method); got != test.want {
+				t.Errorf("%s.Allowed(%s) = %t, want %t", test.role, test.method, got, test.want)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	t.Parallel()
+
+	method := "/" + brpb.BrandService_ServiceDesc.ServiceName + "/Create"
+	tests := []struct {
+		name      string
+		principal *Principal
+		wantCode  codes.Code
+	}{
+		{name: "Unauthenticated", wantCode: codes.Unauthenticated},
+		{name: "PermissionDenied", principal: &Principal{Name: "viewer", Role: RoleViewer}, wantCode: codes.PermissionDenied},
+		{name: "Allowed", principal: &Principal{Name: "merchandiser", Role: RoleMerchandiser}, wantCode: codes.OK},
+		{
+			name:      "SeveralRoles",
+			principal: &Principal{Name: "ops", Role: RoleInventoryOperator, Roles: []Role{RoleMerchandiser, RoleInventoryOperator}},
+			wantCode:  codes.OK,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			if test.principal != nil {
+				ctx = NewContext(ctx, test.principal)
+			}
+			if got := status.Code(Authorize(ctx, method)); got != test.wantCode {
+				t.Errorf("Authorize() code = %s, want %s", got, test.wantCode)
+			}
+		})
+	}
+}
+
+func TestPassword(t *testing.T) {
+	t.Parallel()
+
+	hash, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !CheckPassword(hash, "correct horse battery staple") {
+		t.Error("CheckPassword() = false for the hashed password")
+	}
+	if CheckPassword(hash, "wrong password") {
+		t.Error("CheckPassword() = true for a wrong password")
+	}
+}
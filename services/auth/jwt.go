@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+var (
+	ErrInvalidToken   = errors.New("invalid bearer token")
+	ErrNoJWTKeys      = errors.New("no jwt verification keys")
+	ErrNoJWTAudience  = errors.New("no jwt audience")
+	ErrInvalidRoleMap = errors.New("invalid jwt role map")
+)
+
+// jwtAlgorithms are the accepted token signature algorithms, only asymmetric
+// algorithms are accepted so the verification keys can be public.
+var jwtAlgorithms = []string{
+	string(jose.RS256), string(jose.RS384), string(jose.RS512),
+	string(jose.PS256), string(jose.PS384), string(jose.PS512),
+	string(jose.ES256), string(jose.ES384), string(jose.ES512),
+	string(jose.EdDSA),
+}
+
+// JWTConfig configures the verification of bearer tokens, the keys are read
+// from files so tokens are verified without reaching the issuer.
+type JWTConfig struct {
+	// JWKSFile is the path of a JSON Web Key Set file, e.g. a copy of the issuer jwks_uri.
+	JWKSFile string
+	// KeyFiles are the paths of PEM encoded public keys.
+	KeyFiles []string
+	// Issuer is matched against the token claims, when not empty.
+	Issuer string
+	// Audience is matched against the token claims, it is required so tokens
+	// the issuer signs for other services are rejected.
+	Audience string
+	// RoleClaim is the claim holding the caller roles, nested claims are
+	// separated by dots, e.g. "realm_access.roles".
+	RoleClaim string
+	// RoleMap maps the role claim values to roles, only the values of the map
+	// grant roles when it is not empty. Otherwise values matching a role name
+	// are mapped to the role.
+	RoleMap map[string]Role
+}
+
+// JWTVerifier verifies bearer tokens and maps their claims to principals.
+type JWTVerifier struct {
+	keys     []jose.JSONWebKey
+	expected jwt.Expected
+	// roleClaim is the path of the role claim.
+	roleClaim []string
+	roleMap   map[string]Role
+}
+
+// NewJWTVerifier returns a verifier of the tokens signed by the keys of cfg.
+func NewJWTVerifier(cfg JWTConfig) (*JWTVerifier, error) {
+	var keys []jose.JSONWebKey
+	if cfg.JWKSFile != "" {
+		b, err := os.ReadFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read jwks file: %w", err)
+		}
+		var set jose.JSONWebKeySet
+		if err := json.Unmarshal(b, &set); err != nil {
+			return nil, fmt.Errorf("failed to decode jwks file: %s, error: %w", cfg.JWKSFile, err)
+		}
+		for _, k := range set.Keys {
+			// symmetric keys are skipped and private keys only used for their public part.
+			if pub := k.Public(); pub.Valid() && (pub.Use == "" || pub.Use == "sig") {
+				keys = append(keys, pub)
+			}
+		}
+	}
+	for _, path := range cfg.KeyFiles {
+		k, err := readPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, jose.JSONWebKey{Key: k})
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoJWTKeys
+	}
+	if cfg.Audience == "" {
+		return nil, ErrNoJWTAudience
+	}
+
+	v := &JWTVerifier{
+		keys:     keys,
+		expected: jwt.Expected{Issuer: cfg.Issuer, Audience: jwt.Audience{cfg.Audience}},
+		roleMap:  cfg.RoleMap,
+	}
+	if cfg.RoleClaim != "" {
+		v.roleClaim = strings.Split(cfg.RoleClaim, ".")
+	}
+	return v, nil
+}
+
+// readPublicKey reads a PEM encoded PKIX public key.
+func readPublicKey(path string) (interface{}, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwt key file: %w", err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode jwt key file: %s, error: no PEM block", path)
+	}
+	k, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse jwt key file: %s, error: %w", path, err)
+	}
+	return k, nil
+}
+
+// ParseRoleMap parses role map entries of the form "value:role" separated by commas,
+// e.g. "ems-admins:admin,catalog:merchandiser".
+func ParseRoleMap(s string) (map[string]Role, error) {
+	m := map[string]Role{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		value, name, ok := strings.Cut(entry, ":")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidRoleMap, entry)
+		}
+		r, err := ParseRole(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidRoleMap, entry, err)
+		}
+		m[value] = r
+	}
+	return m, nil
+}
+
+// Verify verifies the signature and the claims of token and returns its principal,
+// ErrInvalidToken is returned for tokens that can't be trusted. Tokens without
+// a known role get a principal without role, which is denied every RPC.
+func (v *JWTVerifier) Verify(token string) (*Principal, error) {
+	tok, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if len(tok.Headers) != 1 || !validJWTAlgorithm(tok.Headers[0].Algorithm) {
+		return nil, fmt.Errorf("%w: unsupported signature", ErrInvalidToken)
+	}
+	header := tok.Headers[0]
+
+	for _, k := range v.keys {
+		if header.KeyID != "" && k.KeyID != "" && k.KeyID != header.KeyID {
+			continue
+		}
+		if k.Algorithm != "" && k.Algorithm != header.Algorithm {
+			continue
+		}
+
+		var (
+			claims jwt.Claims
+			custom map[string]interface{}
+		)
+		if err := tok.Claims(k.Key, &claims, &custom); err != nil {
+			continue
+		}
+		if claims.Expiry == nil {
+			return nil, fmt.Errorf("%w: missing expiry", ErrInvalidToken)
+		}
+		if err := claims.Validate(v.expected.WithTime(time.Now())); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+		}
+		return v.principal(claims, custom), nil
+	}
+	return nil, fmt.Errorf("%w: signature doesn't match any key", ErrInvalidToken)
+}
+
+func validJWTAlgorithm(alg string) bool {
+	for _, a := range jwtAlgorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// principal maps the verified token claims to a principal. When the role claim maps
+// to several roles the principal holds all of them and can call the methods allowed
+// to any of them, its Role is the most privileged one. Role names are only granted
+// directly when no role map is configured, so a caller can't get a role from a claim
+// value the issuer doesn't reserve for ems.
+func (v *JWTVerifier) principal(claims jwt.Claims, custom map[string]interface{}) *Principal {
+	p := &Principal{ID: claims.Subject, Name: claims.Subject}
+	for _, key := range []string{"preferred_username", "email"} {
+		if name, ok := custom[key].(string); ok && name != "" {
+			p.Name = name
+			break
+		}
+	}
+
+	granted := map[Role]bool{}
+	for _, value := range v.roleValues(custom) {
+		r, ok := v.roleMap[value]
+		if !ok {
+			if len(v.roleMap) > 0 {
+				continue
+			}
+			var err error
+			if r, err = ParseRole(value); err != nil {
+				continue
+			}
+		}
+		granted[r] = true
+	}
+	// roles are kept from the least to the most privileged.
+	for _, r := range Roles {
+		if granted[r] {
+			p.Roles = append(p.Roles, r)
+			p.Role = r
+		}
+	}
+	return p
+}
+
+// roleValues returns the string values of the role claim.
+func (v *JWTVerifier) roleValues(custom map[string]interface{}) []string {
+	if len(v.roleClaim) == 0 {
+		return nil
+	}
+	var claim interface{} = custom
+	for _, key := range v.roleClaim {
+		obj, ok := claim.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		claim = obj[key]
+	}
+
+	switch claim := claim.(type) {
+	case string:
+		return strings.Fields(claim)
+	case []interface{}:
+		var values []string
+		for _, item := range claim {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+func TestJWTVerifier(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the RSA key is served by a JWKS file and the EC key by a PEM file.
+	dir := t.TempDir()
+	jwksFile := filepath.Join(dir, "jwks.json")
+	jwks, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: rsaKey.Public(), KeyID: "rsa", Algorithm: string(jose.RS256), Use: "sig"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(jwksFile, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(ecKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "ec.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := NewJWTVerifier(JWTConfig{
+		JWKSFile:  jwksFile,
+		KeyFiles:  []string{keyFile},
+		Issuer:    "https://sso.dropezy.com",
+		Audience:  "ems-api",
+		RoleClaim: "realm_access.roles",
+		RoleMap:   map[string]Role{"catalog": RoleMerchandiser, "stock": RoleInventoryOperator},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(alg jose.SignatureAlgorithm, key interface{}, kid string, claims jwt.Claims, custom map[string]interface{}) string {
+		opts := (&jose.SignerOptions{}).WithType("JWT")
+		if kid != "" {
+			opts = opts.WithHeader("kid", kid)
+		}
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key}, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := jwt.Signed(signer).Claims(claims).Claims(custom).CompactSerialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	now := time.Now()
+	claims := jwt.Claims{
+		Subject:  "42",
+		Issuer:   "https://sso.dropezy.com",
+		Audience: jwt.Audience{"ems-api"},
+		Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+		IssuedAt: jwt.NewNumericDate(now),
+	}
+	custom := map[string]interface{}{
+		"preferred_username": "jane",
+		"realm_access":       map[string]interface{}{"roles": []string{"offline_access", "viewer", "catalog"}},
+	}
+	expired := claims
+	expired.Expiry = jwt.NewNumericDate(now.Add(-time.Hour))
+	otherIssuer := claims
+	otherIssuer.Issuer = "https://example.com"
+	noExpiry := claims
+	noExpiry.Expiry = nil
+	otherAudience := claims
+	otherAudience.Audience = jwt.Audience{"other-api"}
+	several := map[string]interface{}{
+		"realm_access": map[string]interface{}{"roles": []string{"stock", "catalog"}},
+	}
+	// role names only grant their role when no role map is configured.
+	unmapped := map[string]interface{}{
+		"realm_access": map[string]interface{}{"roles": []string{"admin"}},
+	}
+
+	tests := []struct {
+		name          string
+		token         string
+		wantPrincipal *Principal
+		wantErr       error
+	}{
+		{
+			name:          "JWKS",
+			token:         sign(jose.RS256, rsaKey, "rsa", claims, custom),
+			wantPrincipal: &Principal{ID: "42", Name: "jane", Role: RoleMerchandiser, Roles: []Role{RoleMerchandiser}},
+		},
+		{
+			name:          "SeveralRoles",
+			token:         sign(jose.RS256, rsaKey, "rsa", claims, several),
+			wantPrincipal: &Principal{ID: "42", Name: "42", Role: RoleInventoryOperator, Roles: []Role{RoleMerchandiser, RoleInventoryOperator}},
+		},
+		{
+			name:          "PEM",
+			token:         sign(jose.ES256, ecKey, "", claims, nil),
+			wantPrincipal: &Principal{ID: "42", Name: "42"},
+		},
+		{
+			name:          "UnmappedRoleName",
+			token:         sign(jose.RS256, rsaKey, "rsa", claims, unmapped),
+			wantPrincipal: &Principal{ID: "42", Name: "42"},
+		},
+		{name: "OtherAudience", token: sign(jose.RS256, rsaKey, "rsa", otherAudience, custom), wantErr: ErrInvalidToken},
+		{name: "Expired", token: sign(jose.RS256, rsaKey, "rsa", expired, custom), wantErr: ErrInvalidToken},
+		{name: "OtherIssuer", token: sign(jose.RS256, rsaKey, "rsa", otherIssuer, custom), wantErr: ErrInvalidToken},
+		{name: "NoExpiry", token: sign(jose.RS256, rsaKey, "rsa", noExpiry, custom), wantErr: ErrInvalidToken},
+		{name: "UnknownKey", token: sign(jose.ES256, otherKey, "", claims, custom), wantErr: ErrInvalidToken},
+		{name: "Symmetric", token: sign(jose.HS256, []byte("0123456789abcdef0123456789abcdef"), "", claims, custom), wantErr: ErrInvalidToken},
+		{name: "Malformed", token: "not.a.token", wantErr: ErrInvalidToken},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := v.Verify(test.token)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, test.wantErr)
+			}
+			if test.wantPrincipal == nil {
+				return
+			}
+			if p.ID != test.wantPrincipal.ID || p.Name != test.wantPrincipal.Name || p.Role != test.wantPrincipal.Role || !reflect.DeepEqual(p.Roles, test.wantPrincipal.Roles) {
+				t.Errorf("Verify() = %+v, want %+v", p, test.wantPrincipal)
+			}
+		})
+	}
+}
+
+func TestNewJWTVerifier(t *testing.T) {
+	t.Parallel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "ec.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewJWTVerifier(JWTConfig{KeyFiles: []string{keyFile}}); !errors.Is(err, ErrNoJWTAudience) {
+		t.Errorf("NewJWTVerifier() without audience error = %v, want %v", err, ErrNoJWTAudience)
+	}
+	if _, err := NewJWTVerifier(JWTConfig{Audience: "ems-api"}); !errors.Is(err, ErrNoJWTKeys) {
+		t.Errorf("NewJWTVerifier() without keys error = %v, want %v", err, ErrNoJWTKeys)
+	}
+
+	// without role map, role claim values matching a role name get the role.
+	v, err := NewJWTVerifier(JWTConfig{KeyFiles: []string{keyFile}, Audience: "ems-api", RoleClaim: "role"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Signed(signer).Claims(jwt.Claims{
+		Subject:  "42",
+		Audience: jwt.Audience{"ems-api"},
+		Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).Claims(map[string]interface{}{"role": "viewer"}).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := v.Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Role != RoleViewer {
+		t.Errorf("Verify() role = %q, want %q", p.Role, RoleViewer)
+	}
+}
+
+func TestParseRoleMap(t *testing.T) {
+	t.Parallel()
+
+	m, err := ParseRoleMap("ems-admins:admin, catalog:merchandiser,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["ems-admins"] != RoleAdmin || m["catalog"] != RoleMerchandiser || len(m) != 2 {
+		t.Errorf("ParseRoleMap() = %v", m)
+	}
+
+	for _, s := range []string{"catalog", "catalog:root", ":admin"} {
+		if _, err := ParseRoleMap(s); !errors.Is(err, ErrInvalidRoleMap) {
+			t.Errorf("ParseRoleMap(%q) error = %v, want %v", s, err, ErrInvalidRoleMap)
+		}
+	}
+}
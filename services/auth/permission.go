@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	// protobuf
+	akpb "github.com/dropezy/proto/ems/v1/apikey"
+	adpb "github.com/dropezy/proto/ems/v1/audit"
+	brpb "github.com/dropezy/proto/ems/v1/brand"
+	ctpb "github.com/dropezy/proto/ems/v1/category"
+	invpb "github.com/dropezy/proto/ems/v1/inventory"
+	prpb "github.com/dropezy/proto/ems/v1/product"
+	stpb "github.com/dropezy/proto/ems/v1/store"
+	uspb "github.com/dropezy/proto/ems/v1/user"
+	vtpb "github.com/dropezy/proto/ems/v1/varianttype"
+)
+
+var (
+	// readRoles are the roles allowed to call read RPCs.
+	readRoles = []Role{RoleViewer, RoleMerchandiser, RoleInventoryOperator}
+	// catalogRoles are the roles allowed to manage the catalog.
+	catalogRoles = []Role{RoleMerchandiser}
+	// inventoryRoles are the roles allowed to adjust inventories.
+	inventoryRoles = []Role{RoleInventoryOperator}
+)
+
+// permissions maps full RPC method names to the roles allowed to call them,
+// admins can call every method and methods missing from the map are admin only.
+var permissions = map[string][]Role{}
+
+// storeServices are the services whose requests belong to a single store, principals
+// limited to some stores can only call them for requests of these stores.
+var storeServices = []string{
+	invpb.InventoryService_ServiceDesc.ServiceName,
+	stpb.StoreService_ServiceDesc.ServiceName,
+}
+
+// storeListMethods are the store service methods whose results are filtered
+// to the stores of the principal by their handler, see StoreAllowed.
+var storeListMethods = map[string]bool{
+	"/" + stpb.StoreService_ServiceDesc.ServiceName + "/List": true,
+}
+
+// apiKeyDeniedServices are the services API keys can't call whatever their scopes,
+// so a leaked key can't be used to grant itself more access.
+var apiKeyDeniedServices = []string{
+	uspb.UserService_ServiceDesc.ServiceName,
+	akpb.ApiKeyService_ServiceDesc.ServiceName,
+}
+
+func init() {
+	allow(grpc_health_v1.Health_ServiceDesc, readRoles, "Check")
+
+	allow(brpb.BrandService_ServiceDesc, readRoles, "List", "Get")
+	allow(brpb.BrandService_ServiceDesc, catalogRoles, "Create", "Update")
+
+	allow(ctpb.CategoryService_ServiceDesc, readRoles, "Get")
+	allow(ctpb.CategoryService_ServiceDesc, catalogRoles, "Create", "Update", "Delete", "Move")
+
+	allow(prpb.ProductService_ServiceDesc, readRoles,
+		"Get", "GetByID", "GetBySKU", "GetByBarcode", "GetByShoptreeVariantID")
+	allow(prpb.ProductService_ServiceDesc, catalogRoles, "Create", "Update", "Delete")
+
+	allow(vtpb.VariantTypeService_ServiceDesc, readRoles, "List", "Get")
+	allow(vtpb.VariantTypeService_ServiceDesc, catalogRoles, "Create", "Update")
+
+	allow(invpb.InventoryService_ServiceDesc, readRoles, "List", "GetStock")
+	allow(invpb.InventoryService_ServiceDesc, inventoryRoles, "Update")
+
+	// stores, users and API keys are managed and audited by admins only.
+	allow(stpb.StoreService_ServiceDesc, readRoles, "List", "Get")
+	allow(uspb.UserService_ServiceDesc, nil, "List", "Create", "Update")
+	allow(akpb.ApiKeyService_ServiceDesc, nil, "List", "Create", "Revoke")
+	allow(adpb.AuditService_ServiceDesc, nil, "List")
+}
+
+// allow grants roles access to the methods of the service.
+func allow(desc grpc.ServiceDesc, roles []Role, methods ...string) {
+	for _, m := range methods {
+		method := "/" + desc.ServiceName + "/" + m
+		permissions[method] = append(permissions[method], roles...)
+	}
+}
+
+// Allowed reports whether role can call the full RPC method name,
+// e.g. /ems.v1.brand.BrandService/List.
+func (r Role) Allowed(method string) bool {
+	if r == RoleAdmin {
+		return true
+	}
+	for _, allowed := range permissions[method] {
+		if allowed == r {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize checks the principal of ctx can call method, it returns
+// an Unauthenticated or PermissionDenied gRPC status error otherwise.
+func Authorize(ctx context.Context, method string) error {
+	p := FromContext(ctx)
+	if p == nil {
+		return status.Error(codes.Unauthenticated, ErrUnauthenticated.Error())
+	}
+	if p.APIKey {
+		if !apiKeyAllowed(p.Scopes, method) {
+			return status.Errorf(codes.PermissionDenied, "%s: api key %s cannot call %s", ErrPermissionDenied, p.Name, method)
+		}
+		return nil
+	}
+	if !p.allowed(method) {
+		return status.Errorf(codes.PermissionDenied, "%s: role %s cannot call %s", ErrPermissionDenied, p.roleNames(), method)
+	}
+	return nil
+}
+
+// allowed reports whether the role or one of the roles of the principal can call method.
+func (p *Principal) allowed(method string) bool {
+	if p.Role.Allowed(method) {
+		return true
+	}
+	for _, r := range p.Roles {
+		if r.Allowed(method) {
+			return true
+		}
+	}
+	return false
+}
+
+// roleNames returns the names of the roles of the principal separated by commas.
+func (p *Principal) roleNames() string {
+	if len(p.Roles) == 0 {
+		return string(p.Role)
+	}
+	names := make([]string, 0, len(p.Roles))
+	for _, r := range p.Roles {
+		names = append(names, string(r))
+	}
+	return strings.Join(names, ",")
+}
+
+// apiKeyAllowed reports whether one of the API key scopes allows method.
+func apiKeyAllowed(scopes []string, method string) bool {
+	for _, service := range apiKeyDeniedServices {
+		if strings.HasPrefix(method, "/"+service+"/") {
+			return false
+		}
+	}
+	for _, scope := range scopes {
+		if ScopeAllows(scope, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// storeRequest is implemented by the requests of a single store,
+// e.g. inventory requests.
+type storeRequest interface {
+	GetStoreId() string
+}
+
+// storeMessageRequest is implemented by the requests holding a store,
+// e.g. store update requests.
+type storeMessageRequest interface {
+	GetStore() *stpb.Store
+}
+
+// requestStoreID returns the id of the store req belongs to, or an empty string.
+func requestStoreID(req interface{}) string {
+	switch r := req.(type) {
+	case storeRequest:
+		return r.GetStoreId()
+	case storeMessageRequest:
+		return r.GetStore().GetStoreId()
+	}
+	return ""
+}
+
+// AuthorizeStore checks the principal of ctx can access the store of the req
+// of method, when the principal is limited to some stores. Requests of the store
+// services whose store can't be determined are denied. It returns a
+// PermissionDenied gRPC status error otherwise.
+func AuthorizeStore(ctx context.Context, method string, req interface{}) error {
+	p := FromContext(ctx)
+	if p == nil || len(p.StoreIDs) == 0 || storeListMethods[method] || !storeMethod(method) {
+		return nil
+	}
+	id := requestStoreID(req)
+	if id == "" {
+		return status.Errorf(codes.PermissionDenied, "%s: %s is limited to some stores and the request has no store", ErrPermissionDenied, p.Name)
+	}
+	if !p.storeAllowed(id) {
+		return status.Errorf(codes.PermissionDenied, "%s: %s cannot access store %s", ErrPermissionDenied, p.Name, id)
+	}
+	return nil
+}
+
+// StoreAllowed reports whether the principal of ctx can access the store id,
+// it is used to filter the results of storeListMethods.
+func StoreAllowed(ctx context.Context, id string) bool {
+	p := FromContext(ctx)
+	return p == nil || p.storeAllowed(id)
+}
+
+// storeAllowed reports whether the principal can access the store id.
+func (p *Principal) storeAllowed(id string) bool {
+	if len(p.StoreIDs) == 0 {
+		return true
+	}
+	for _, allowed := range p.StoreIDs {
+		if allowed == id {
+			return true
+		}
+	}
+	return false
+}
+
+// storeMethod reports whether method belongs to one of the storeServices.
+func storeMethod(method string) bool {
+	for _, service := range storeServices {
+		if strings.HasPrefix(method, "/"+service+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// UnaryServerInterceptor authorizes unary calls, gateway calls are authorized
+// as well since the gateway calls the gRPC server.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := Authorize(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		if err := AuthorizeStore(ctx, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor authorizes streaming calls, e.g. server reflection.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := Authorize(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
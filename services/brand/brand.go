@@ -0,0 +1,204 @@
+// Package brand implements brand gRPC service methods
+// to manage dropezy product brands.
+package brand
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/brand"
+
+	"github.com/dropezy/storefront-backend/ems-api/services"
+	"github.com/dropezy/storefront-backend/ems-api/services/audit"
+	"github.com/dropezy/storefront-backend/ems-api/validation"
+
+	// protobuf
+	brpb "github.com/dropezy/proto/ems/v1/brand"
+)
+
+const serviceName = "brand"
+
+// Handler holds brand gRPC service implementation.
+type Handler struct {
+	// utilities
+	logger zerolog.Logger
+
+	// service dependencies
+	store Store
+}
+
+// NewHandler returns a new brand service handler.
+func NewHandler(logger zerolog.Logger, store Store) *Handler {
+	return &Handler{
+		logger: logger.With().Str("service", serviceName).Logger(),
+		store:  store,
+	}
+}
+
+// RegisterService registers the brand service to the gRPC server.
+func RegisterService(logger zerolog.Logger, store Store) func(srv *grpc.Server) error {
+	return func(srv *grpc.Server) error {
+		h := NewHandler(logger, store)
+		brpb.RegisterBrandServiceServer(srv, h)
+		return nil
+	}
+}
+
+func RegisterGateway(ctx context.Context, mux *runtime.ServeMux, addr string, opts []grpc.DialOption) error {
+	return brpb.RegisterBrandServiceHandlerFromEndpoint(ctx, mux, addr, opts)
+}
+
+// List will fetch brands from storage.
+func (h *Handler) List(ctx context.Context, req *brpb.ListRequest) (*brpb.ListResponse, error) {
+	brands, err := h.store.ListBrands(ctx)
+	if err != nil {
+		h.logger.Err(err).Msg("failed to fetch brands from store")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	var brandsPb []*brpb.Brand
+	for _, b := range brands {
+		brandsPb = append(brandsPb, toBrandPb(b))
+	}
+
+	return &brpb.ListResponse{
+		Brands: brandsPb,
+	}, nil
+}
+
+// Get will fetch a brand by its id from storage.
+func (h *Handler) Get(ctx context.Context, req *brpb.GetRequest) (*brpb.GetResponse, error) {
+	id, err := primitive.ObjectIDFromHex(req.GetBrandId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidBrandID.Error())
+	}
+
+	b, err := h.store.GetBrand(ctx, id)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch brand from store")
+	}
+
+	return &brpb.GetResponse{
+		Brand: toBrandPb(b),
+	}, nil
+}
+
+// Create will validate and insert a new brand.
+func (h *Handler) Create(ctx context.Context, req *brpb.CreateRequest) (*brpb.CreateResponse, error) {
+	if req.GetBrand() == nil {
+		return nil, status.Error(codes.InvalidArgument, ErrBrandIsRequired.Error())
+	}
+
+	b := fromBrandPb(req.GetBrand())
+	b.ID = primitive.NewObjectID()
+	if err := h.validateBrand(ctx, b); err != nil {
+		return nil, err
+	}
+
+	if err := h.store.CreateBrand(ctx, b); err != nil {
+		h.logger.Err(err).Msg("failed to create brand on store")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	audit.Track(ctx, audit.EntityBrand, b.ID.Hex(), nil, b)
+
+	return &brpb.CreateResponse{
+		Brand: toBrandPb(b),
+	}, nil
+}
+
+// Update will validate and replace an existing brand.
+func (h *Handler) Update(ctx context.Context, req *brpb.UpdateRequest) (*brpb.UpdateResponse, error) {
+	if req.GetBrand() == nil {
+		return nil, status.Error(codes.InvalidArgument, ErrBrandIsRequired.Error())
+	}
+	id, err := primitive.ObjectIDFromHex(req.GetBrand().GetBrandId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidBrandID.Error())
+	}
+
+	b := fromBrandPb(req.GetBrand())
+	b.ID = id
+	if err := h.validateBrand(ctx, b); err != nil {
+		return nil, err
+	}
+
+	existing, err := h.store.GetBrand(ctx, b.ID)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch brand from store")
+	}
+	if err := h.store.UpdateBrand(ctx, b); err != nil {
+		return nil, h.toStatusError(err, "failed to update brand on store")
+	}
+
+	b, err = h.store.GetBrand(ctx, b.ID)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch brand from store")
+	}
+	audit.Track(ctx, audit.EntityBrand, b.ID.Hex(), existing, b)
+
+	return &brpb.UpdateResponse{
+		Brand: toBrandPb(b),
+	}, nil
+}
+
+// validateBrand checks the brand parameters and makes sure the slug and
+// the name are unique, it returns a gRPC status error. Names are unique
+// since products are linked to brands by name.
+func (h *Handler) validateBrand(ctx context.Context, b *brand.Brand) error {
+	if err := validation.ValidateBrand(b); err != nil {
+		return services.InvalidArgumentError(err)
+	}
+
+	exists, err := h.store.SlugExists(ctx, b.Slug, b.ID)
+	if err != nil {
+		h.logger.Err(err).Msg("failed to check brand slug on store")
+		return status.Error(codes.Internal, err.Error())
+	}
+	if exists {
+		return status.Error(codes.AlreadyExists, ErrSlugAlreadyExists.Error())
+	}
+
+	exists, err = h.store.NameExists(ctx, b.Name, b.ID)
+	if err != nil {
+		h.logger.Err(err).Msg("failed to check brand name on store")
+		return status.Error(codes.Internal, err.Error())
+	}
+	if exists {
+		return status.Error(codes.AlreadyExists, ErrNameAlreadyExists.Error())
+	}
+	return nil
+}
+
+// toStatusError converts store errors to gRPC status errors,
+// unexpected errors are logged with msg.
+func (h *Handler) toStatusError(err error, msg string) error {
+	if errors.Is(err, ErrBrandNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	h.logger.Err(err).Msg(msg)
+	return status.Error(codes.Internal, err.Error())
+}
+
+func toBrandPb(b *brand.Brand) *brpb.Brand {
+	return &brpb.Brand{
+		BrandId: b.ID.Hex(),
+		Name:    b.Name,
+		LogoUrl: b.LogoURL,
+		Slug:    b.Slug,
+	}
+}
+
+func fromBrandPb(pb *brpb.Brand) *brand.Brand {
+	return &brand.Brand{
+		Name:    pb.GetName(),
+		LogoURL: pb.GetLogoUrl(),
+		Slug:    pb.GetSlug(),
+	}
+}
@@ -0,0 +1,11 @@
+package brand
+
+import "errors"
+
+var (
+	ErrInvalidBrandID    = errors.New("invalid brand id")
+	ErrBrandIsRequired   = errors.New("brand is required")
+	ErrBrandNotFound     = errors.New("brand not found")
+	ErrSlugAlreadyExists = errors.New("brand slug already exists")
+	ErrNameAlreadyExists = errors.New("brand name already exists")
+)
@@ -0,0 +1,128 @@
+package brand
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/brand"
+)
+
+const brandCollection = "brand"
+
+// nameCollation compares brand names regardless of their case, the way
+// products are linked to brands by the importer.
+var nameCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// Store is the storage contract required by the brand service.
+type Store interface {
+	ListBrands(ctx context.Context) ([]*brand.Brand, error)
+	GetBrand(ctx context.Context, id primitive.ObjectID) (*brand.Brand, error)
+	SlugExists(ctx context.Context, slug string, excludeID primitive.ObjectID) (bool, error)
+	NameExists(ctx context.Context, name string, excludeID primitive.ObjectID) (bool, error)
+	CreateBrand(ctx context.Context, b *brand.Brand) error
+	UpdateBrand(ctx context.Context, b *brand.Brand) error
+}
+
+// MongoStore implements Store backed by mongo database.
+type MongoStore struct {
+	db *mongo.Database
+}
+
+// NewMongoStore returns a new brand store backed by db.
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{db: db}
+}
+
+// CreateIndexes creates the indexes of the brand collection, brand names
+// are unique regardless of their case.
+func (s *MongoStore) CreateIndexes(ctx context.Context) error {
+	if _, err := s.db.Collection(brandCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true).SetCollation(nameCollation),
+	}); err != nil {
+		return fmt.Errorf("failed to create brand name index: %w", err)
+	}
+	return nil
+}
+
+// ListBrands fetches all brands ordered by their name.
+func (s *MongoStore) ListBrands(ctx context.Context) ([]*brand.Brand, error) {
+	cur, err := s.db.Collection(brandCollection).Find(ctx, bson.M{},
+		options.Find().SetSort(bson.D{{Key: "name", Value: 1}}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Find brands: %w", err)
+	}
+
+	var brands []*brand.Brand
+	if err := cur.All(ctx, &brands); err != nil {
+		return nil, fmt.Errorf("failed to decode brands: %w", err)
+	}
+	return brands, nil
+}
+
+// GetBrand fetches a brand by its id.
+func (s *MongoStore) GetBrand(ctx context.Context, id primitive.ObjectID) (*brand.Brand, error) {
+	b := &brand.Brand{}
+	if err := s.db.Collection(brandCollection).FindOne(ctx, bson.M{"_id": id}).Decode(b); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrBrandNotFound
+		}
+		return nil, fmt.Errorf("failed to execute FindOne brand: %w", err)
+	}
+	return b, nil
+}
+
+// SlugExists checks whether a brand other than excludeID already uses the slug.
+func (s *MongoStore) SlugExists(ctx context.Context, slug string, excludeID primitive.ObjectID) (bool, error) {
+	n, err := s.db.Collection(brandCollection).CountDocuments(ctx, bson.M{
+		"_id":  bson.M{"$ne": excludeID},
+		"slug": slug,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to execute CountDocuments brand: %w", err)
+	}
+	return n > 0, nil
+}
+
+// NameExists checks whether a brand other than excludeID already uses the name,
+// names are compared regardless of their case.
+func (s *MongoStore) NameExists(ctx context.Context, name string, excludeID primitive.ObjectID) (bool, error) {
+	n, err := s.db.Collection(brandCollection).CountDocuments(ctx,
+		bson.M{
+			"_id":  bson.M{"$ne": excludeID},
+			"name": name,
+		},
+		options.Count().SetCollation(nameCollation),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute CountDocuments brand: %w", err)
+	}
+	return n > 0, nil
+}
+
+// CreateBrand inserts a new brand.
+func (s *MongoStore) CreateBrand(ctx context.Context, b *brand.Brand) error {
+	if _, err := s.db.Collection(brandCollection).InsertOne(ctx, b); err != nil {
+		return fmt.Errorf("failed to execute InsertOne brand: %w", err)
+	}
+	return nil
+}
+
+// UpdateBrand replaces an existing brand.
+func (s *MongoStore) UpdateBrand(ctx context.Context, b *brand.Brand) error {
+	res, err := s.db.Collection(brandCollection).ReplaceOne(ctx, bson.M{"_id": b.ID}, b)
+	if err != nil {
+		return fmt.Errorf("failed to execute ReplaceOne brand: %w", err)
+	}
+	if res.MatchedCount < 1 {
+		return ErrBrandNotFound
+	}
+	return nil
+}
@@ -4,16 +4,23 @@ package category
 
 import (
 	"context"
+	"errors"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
-	"github.com/dropezy/storefront-backend/internal/storage"
 	"github.com/dropezy/storefront-backend/internal/storage/model/category"
 
+	"github.com/dropezy/storefront-backend/ems-api/services"
+	"github.com/dropezy/storefront-backend/ems-api/services/audit"
+	"github.com/dropezy/storefront-backend/ems-api/services/i18n"
+	"github.com/dropezy/storefront-backend/ems-api/validation"
+
 	// protobuf
 	ctpb "github.com/dropezy/proto/ems/v1/category"
 
@@ -29,11 +36,11 @@ type Handler struct {
 	logger zerolog.Logger
 
 	// service dependencies
-	store storage.CategoryStore
+	store Store
 }
 
 // NewHandler returns a new category service handler.
-func NewHandler(logger zerolog.Logger, store storage.CategoryStore) *Handler {
+func NewHandler(logger zerolog.Logger, store Store) *Handler {
 	return &Handler{
 		logger: logger.With().Str("service", serviceName).Logger(),
 		store:  store,
@@ -41,7 +48,7 @@ func NewHandler(logger zerolog.Logger, store storage.CategoryStore) *Handler {
 }
 
 // RegisterService registers the category service to the gRPC server.
-func RegisterService(logger zerolog.Logger, store storage.CategoryStore) func(srv *grpc.Server) error {
+func RegisterService(logger zerolog.Logger, store Store) func(srv *grpc.Server) error {
 	return func(srv *grpc.Server) error {
 		h := NewHandler(logger, store)
 		ctpb.RegisterCategoryServiceServer(srv, h)
@@ -62,36 +69,302 @@ func (h *Handler) Get(ctx context.Context, req *ctpb.GetRequest) (*ctpb.GetRespo
 	}
 
 	return &ctpb.GetResponse{
-		Categories: toCategoriesPB(categories),
+		Categories: toCategoriesPB(categories, i18n.FromContext(ctx)),
 	}, nil
 }
 
-func toCategoriesPB(categories []*category.Category) []*s_ctpb.Category {
-	var categoriesPB []*s_ctpb.Category
+// Create will insert a new category. When parent category id is given the category
+// is added as a level 2 category of the parent, else it is added as a level 1 category.
+func (h *Handler) Create(ctx context.Context, req *ctpb.CreateRequest) (*ctpb.CreateResponse, error) {
+	if req.GetCategory() == nil {
+		return nil, status.Error(codes.InvalidArgument, ErrCategoryIsRequired.Error())
+	}
 
-	for _, category := range categories {
-		l2Categories := category.ChildCategories
-
-		// map level 2 categories for this c1 category
-		var l2CategoriesPB []*s_ctpb.Category
-		for _, l2Category := range l2Categories {
-			l2CategoriesPB = append(l2CategoriesPB, &s_ctpb.Category{
-				CategoryId: l2Category.ID.Hex(),
-				Level:      l2Category.Level,
-				Name:       l2Category.Name_ID,
-				ImagesUrls: l2Category.ImagesURLs,
-			})
+	id := primitive.NewObjectID()
+	c := fromCategoryPB(req.GetCategory())
+	c.ID = id
+	// follow the importer image naming when no image is given.
+	if len(c.ImagesURLs) < 1 {
+		c.ImagesURLs = []string{id.Hex() + "-0.webp"}
+	}
+
+	if req.GetParentCategoryId() == "" {
+		c.Level = s_ctpb.CategoryLevel_CATEGORY_LEVEL_1
+		if err := h.validateParentCategory(ctx, c); err != nil {
+			return nil, err
+		}
+		if err := h.store.CreateCategory(ctx, c); err != nil {
+			return nil, h.toStatusError(err, "failed to create category on store")
+		}
+		audit.Track(ctx, audit.EntityCategory, id.Hex(), nil, c)
+		return &ctpb.CreateResponse{
+			Category: toCategoryPB(c, i18n.FromContext(ctx)),
+		}, nil
+	}
+
+	parentID, err := primitive.ObjectIDFromHex(req.GetParentCategoryId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidParentCategoryID.Error())
+	}
+	parent, err := h.store.GetCategory(ctx, parentID)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch parent category from store")
+	}
+
+	c.Level = s_ctpb.CategoryLevel_CATEGORY_LEVEL_2
+	c.Abbreviation = ""
+	if err := validateChildCategory(parent, c); err != nil {
+		return nil, err
+	}
+	if err := h.store.AddChildCategory(ctx, parent.ID, *c); err != nil {
+		return nil, h.toStatusError(err, "failed to add child category on store")
+	}
+	audit.Track(ctx, audit.EntityCategory, id.Hex(), nil, c)
+
+	return &ctpb.CreateResponse{
+		Category: toCategoryPB(c, i18n.FromContext(ctx)),
+	}, nil
+}
+
+// Update will update the names, abbreviation and images of a category,
+// its level and parent category are left untouched.
+func (h *Handler) Update(ctx context.Context, req *ctpb.UpdateRequest) (*ctpb.UpdateResponse, error) {
+	if req.GetCategory() == nil {
+		return nil, status.Error(codes.InvalidArgument, ErrCategoryIsRequired.Error())
+	}
+	id, err := primitive.ObjectIDFromHex(req.GetCategory().GetCategoryId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidCategoryID.Error())
+	}
+
+	c := fromCategoryPB(req.GetCategory())
+	c.ID = id
+
+	parent, child, err := h.getCategory(ctx, id)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch category from store")
+	}
+
+	if child == nil {
+		c.Level = s_ctpb.CategoryLevel_CATEGORY_LEVEL_1
+		c.ChildCategories = parent.ChildCategories
+		if err := h.validateParentCategory(ctx, c); err != nil {
+			return nil, err
+		}
+		if err := h.store.UpdateCategory(ctx, c); err != nil {
+			return nil, h.toStatusError(err, "failed to update category on store")
+		}
+		if c, _, err = h.getCategory(ctx, id); err != nil {
+			return nil, h.toStatusError(err, "failed to fetch category from store")
+		}
+		audit.Track(ctx, audit.EntityCategory, id.Hex(), parent, c)
+		return &ctpb.UpdateResponse{
+			Category: toCategoryPB(c, i18n.FromContext(ctx)),
+		}, nil
+	}
+
+	c.Level = s_ctpb.CategoryLevel_CATEGORY_LEVEL_2
+	c.Abbreviation = ""
+	if err := validateChildCategory(parent, c); err != nil {
+		return nil, err
+	}
+	if err := h.store.UpdateChildCategory(ctx, parent.ID, *c); err != nil {
+		return nil, h.toStatusError(err, "failed to update child category on store")
+	}
+	if _, c, err = h.getCategory(ctx, id); err != nil {
+		return nil, h.toStatusError(err, "failed to fetch category from store")
+	}
+	audit.Track(ctx, audit.EntityCategory, id.Hex(), child, c)
+
+	return &ctpb.UpdateResponse{
+		Category: toCategoryPB(c, i18n.FromContext(ctx)),
+	}, nil
+}
+
+// Delete will delete a category, categories which still have products or
+// child categories can't be deleted.
+func (h *Handler) Delete(ctx context.Context, req *ctpb.DeleteRequest) (*ctpb.DeleteResponse, error) {
+	id, err := primitive.ObjectIDFromHex(req.GetCategoryId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidCategoryID.Error())
+	}
+
+	parent, child, err := h.getCategory(ctx, id)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch category from store")
+	}
+
+	deleted := parent
+	if child == nil {
+		err = h.store.DeleteCategory(ctx, id)
+	} else {
+		deleted = child
+		err = h.store.DeleteChildCategory(ctx, parent.ID, id)
+	}
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to delete category on store")
+	}
+	audit.Track(ctx, audit.EntityCategory, id.Hex(), deleted, nil)
+
+	return &ctpb.DeleteResponse{}, nil
+}
+
+// Move will re-parent a level 2 category under another level 1 category,
+// products of the moved category are updated to reference the new parent.
+// Categories whose product SKUs don't carry the new parent abbreviation can't be moved.
+func (h *Handler) Move(ctx context.Context, req *ctpb.MoveRequest) (*ctpb.MoveResponse, error) {
+	id, err := primitive.ObjectIDFromHex(req.GetCategoryId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidCategoryID.Error())
+	}
+	parentID, err := primitive.ObjectIDFromHex(req.GetParentCategoryId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidParentCategoryID.Error())
+	}
+
+	oldParent, child, err := h.getCategory(ctx, id)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch category from store")
+	}
+	if child == nil {
+		return nil, status.Error(codes.FailedPrecondition, ErrCategoryIsNotChildCategory.Error())
+	}
+	if oldParent.ID == parentID {
+		return &ctpb.MoveResponse{
+			Category: toCategoryPB(child, i18n.FromContext(ctx)),
+		}, nil
+	}
+
+	newParent, err := h.store.GetCategory(ctx, parentID)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch parent category from store")
+	}
+	if err := validateChildCategory(newParent, child); err != nil {
+		return nil, err
+	}
+
+	if err := h.store.MoveChildCategory(ctx, oldParent.ID, newParent.ID, *child, newParent.Abbreviation); err != nil {
+		return nil, h.toStatusError(err, "failed to move child category on store")
+	}
+	audit.Track(ctx, audit.EntityCategory, id.Hex(),
+		bson.M{"parent_category_id": oldParent.ID},
+		bson.M{"parent_category_id": newParent.ID},
+	)
+
+	return &ctpb.MoveResponse{
+		Category: toCategoryPB(child, i18n.FromContext(ctx)),
+	}, nil
+}
+
+// getCategory looks for a category by its id. For level 1 categories it returns
+// the category as parent with nil child, for level 2 categories it returns
+// the parent category along with the child category.
+func (h *Handler) getCategory(ctx context.Context, id primitive.ObjectID) (parent, child *category.Category, err error) {
+	parent, err = h.store.GetCategory(ctx, id)
+	if err == nil {
+		return parent, nil, nil
+	}
+	if !errors.Is(err, ErrCategoryIDNotFound) {
+		return nil, nil, err
+	}
+
+	parent, err = h.store.GetParentCategory(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range parent.ChildCategories {
+		if parent.ChildCategories[i].ID == id {
+			return parent, &parent.ChildCategories[i], nil
 		}
+	}
+	return nil, nil, ErrCategoryIDNotFound
+}
+
+// validateParentCategory checks the level 1 category parameters
+// and makes sure the abbreviation is unique, it returns a gRPC status error.
+func (h *Handler) validateParentCategory(ctx context.Context, c *category.Category) error {
+	if err := validation.ValidateParentCategory(c); err != nil {
+		return services.InvalidArgumentError(err)
+	}
 
-		l1CategoryPB := &s_ctpb.Category{
-			CategoryId:      category.ID.Hex(),
-			Level:           category.Level,
-			Name:            category.Name_ID,
-			ImagesUrls:      category.ImagesURLs,
-			ChildCategories: l2CategoriesPB,
+	exists, err := h.store.AbbreviationExists(ctx, c.Abbreviation, c.ID)
+	if err != nil {
+		h.logger.Err(err).Msg("failed to check category abbreviation on store")
+		return status.Error(codes.Internal, err.Error())
+	}
+	if exists {
+		return status.Error(codes.AlreadyExists, ErrAbbreviationAlreadyExists.Error())
+	}
+	return nil
+}
+
+// validateChildCategory checks the level 2 category parameters and makes sure
+// no other child of the parent has the same EN name, it returns a gRPC status error.
+func validateChildCategory(parent, c *category.Category) error {
+	if err := validation.ValidateChildCategory(c); err != nil {
+		return services.InvalidArgumentError(err)
+	}
+	for _, child := range parent.ChildCategories {
+		if child.ID != c.ID && child.Name_EN == c.Name_EN {
+			return status.Error(codes.AlreadyExists, ErrCategoryNameAlreadyExists.Error())
 		}
+	}
+	return nil
+}
 
-		categoriesPB = append(categoriesPB, l1CategoryPB)
+// toStatusError converts store errors to gRPC status errors,
+// unexpected errors are logged with msg.
+func (h *Handler) toStatusError(err error, msg string) error {
+	switch {
+	case errors.Is(err, ErrCategoryIDNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrAbbreviationAlreadyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, ErrCategoryHasProducts),
+		errors.Is(err, ErrCategoryHasChildCategories),
+		errors.Is(err, ErrProductSKUMismatch):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	h.logger.Err(err).Msg(msg)
+	return status.Error(codes.Internal, err.Error())
+}
+
+func toCategoriesPB(categories []*category.Category, loc *i18n.Localizer) []*s_ctpb.Category {
+	var categoriesPB []*s_ctpb.Category
+	for _, category := range categories {
+		categoriesPB = append(categoriesPB, toCategoryPB(category, loc))
 	}
 	return categoriesPB
 }
+
+// toCategoryPB converts a category along with its child categories to protobuf message,
+// the name is localized with loc while the names of every language are always set for editing.
+func toCategoryPB(c *category.Category, loc *i18n.Localizer) *s_ctpb.Category {
+	// map level 2 categories for this c1 category
+	var childCategoriesPB []*s_ctpb.Category
+	for i := range c.ChildCategories {
+		childCategoriesPB = append(childCategoriesPB, toCategoryPB(&c.ChildCategories[i], loc))
+	}
+
+	pb := &s_ctpb.Category{
+		CategoryId:      c.ID.Hex(),
+		Level:           c.Level,
+		Name:            loc.String(i18n.Text{ID: c.Name_ID, EN: c.Name_EN}),
+		NameEn:          c.Name_EN,
+		NameId:          c.Name_ID,
+		Abbreviation:    c.Abbreviation,
+		ImagesUrls:      c.ImagesURLs,
+		ChildCategories: childCategoriesPB,
+	}
+	return pb
+}
+
+// fromCategoryPB converts the editable category protobuf fields to category model.
+func fromCategoryPB(pb *s_ctpb.Category) *category.Category {
+	return &category.Category{
+		Name_EN:      pb.GetNameEn(),
+		Name_ID:      pb.GetNameId(),
+		Abbreviation: pb.GetAbbreviation(),
+		ImagesURLs:   pb.GetImagesUrls(),
+	}
+}
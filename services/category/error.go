@@ -5,4 +5,14 @@ import "errors"
 var (
 	ErrStoreIDIsRequired  = errors.New("store id is required")
 	ErrCategoryIDNotFound = errors.New("category id not found")
+
+	ErrInvalidCategoryID          = errors.New("invalid category id")
+	ErrInvalidParentCategoryID    = errors.New("invalid parent category id")
+	ErrCategoryIsRequired         = errors.New("category is required")
+	ErrAbbreviationAlreadyExists  = errors.New("abbreviation already exists")
+	ErrCategoryNameAlreadyExists  = errors.New("category name already exists under the parent category")
+	ErrCategoryHasProducts        = errors.New("category still has products")
+	ErrCategoryHasChildCategories = errors.New("category still has child categories")
+	ErrCategoryIsNotChildCategory = errors.New("only level 2 categories can be moved")
+	ErrProductSKUMismatch         = errors.New("category products have skus without the abbreviation of the parent category")
 )
@@ -0,0 +1,315 @@
+package category
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/category"
+)
+
+const (
+	categoryCollection = "category"
+	productCollection  = "product"
+)
+
+// Store is the storage contract required by the category service.
+type Store interface {
+	GetCategories(ctx context.Context) ([]*category.Category, error)
+	GetCategory(ctx context.Context, id primitive.ObjectID) (*category.Category, error)
+	GetParentCategory(ctx context.Context, childID primitive.ObjectID) (*category.Category, error)
+	AbbreviationExists(ctx context.Context, abbreviation string, excludeID primitive.ObjectID) (bool, error)
+
+	CreateCategory(ctx context.Context, c *category.Category) error
+	UpdateCategory(ctx context.Context, c *category.Category) error
+	DeleteCategory(ctx context.Context, id primitive.ObjectID) error
+	DeleteChildCategory(ctx context.Context, parentID, childID primitive.ObjectID) error
+
+	AddChildCategory(ctx context.Context, parentID primitive.ObjectID, child category.Category) error
+	UpdateChildCategory(ctx context.Context, parentID primitive.ObjectID, child category.Category) error
+	RemoveChildCategory(ctx context.Context, parentID, childID primitive.ObjectID) error
+	MoveChildCategory(ctx context.Context, fromParentID, toParentID primitive.ObjectID, child category.Category, abbreviation string) error
+
+	CountProducts(ctx context.Context, categoryID primitive.ObjectID) (int64, error)
+}
+
+// MongoStore implements Store backed by mongo database.
+type MongoStore struct {
+	db *mongo.Database
+}
+
+// NewMongoStore returns a new category store backed by db.
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{db: db}
+}
+
+// CreateIndexes creates the indexes of the category collection,
+// level 1 category abbreviations are unique.
+func (s *MongoStore) CreateIndexes(ctx context.Context) error {
+	if _, err := s.db.Collection(categoryCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "abbreviation", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return fmt.Errorf("failed to create category abbreviation index: %w", err)
+	}
+	return nil
+}
+
+// GetCategories fetches all level 1 categories along with their child categories.
+func (s *MongoStore) GetCategories(ctx context.Context) ([]*category.Category, error) {
+	cur, err := s.db.Collection(categoryCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Find categories: %w", err)
+	}
+
+	var categories []*category.Category
+	if err := cur.All(ctx, &categories); err != nil {
+		return nil, fmt.Errorf("failed to decode categories: %w", err)
+	}
+	return categories, nil
+}
+
+// GetCategory fetches a level 1 category by its id.
+func (s *MongoStore) GetCategory(ctx context.Context, id primitive.ObjectID) (*category.Category, error) {
+	return s.findCategory(ctx, bson.M{"_id": id})
+}
+
+// GetParentCategory fetches the level 1 category owning the child category.
+func (s *MongoStore) GetParentCategory(ctx context.Context, childID primitive.ObjectID) (*category.Category, error) {
+	return s.findCategory(ctx, bson.M{"child_categories._id": childID})
+}
+
+// findCategory fetches the first category matching the query,
+// it returns ErrCategoryIDNotFound when there is no matching category.
+func (s *MongoStore) findCategory(ctx context.Context, query bson.M) (*category.Category, error) {
+	c := &category.Category{}
+	if err := s.db.Collection(categoryCollection).FindOne(ctx, query).Decode(c); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrCategoryIDNotFound
+		}
+		return nil, fmt.Errorf("failed to execute FindOne category: %w", err)
+	}
+	return c, nil
+}
+
+// AbbreviationExists checks whether a level 1 category other than
+// excludeID already uses the abbreviation.
+func (s *MongoStore) AbbreviationExists(ctx context.Context, abbreviation string, excludeID primitive.ObjectID) (bool, error) {
+	n, err := s.db.Collection(categoryCollection).CountDocuments(ctx, bson.M{
+		"_id":          bson.M{"$ne": excludeID},
+		"abbreviation": abbreviation,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to execute CountDocuments category: %w", err)
+	}
+	return n > 0, nil
+}
+
+// CreateCategory inserts a new level 1 category.
+func (s *MongoStore) CreateCategory(ctx context.Context, c *category.Category) error {
+	if _, err := s.db.Collection(categoryCollection).InsertOne(ctx, c); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrAbbreviationAlreadyExists
+		}
+		return fmt.Errorf("failed to execute InsertOne category: %w", err)
+	}
+	return nil
+}
+
+// UpdateCategory updates the fields of a level 1 category,
+// its child categories are left untouched.
+func (s *MongoStore) UpdateCategory(ctx context.Context, c *category.Category) error {
+	res, err := s.db.Collection(categoryCollection).UpdateOne(ctx,
+		bson.M{"_id": c.ID},
+		bson.M{"$set": bson.M{
+			"name_en":      c.Name_EN,
+			"name_id":      c.Name_ID,
+			"abbreviation": c.Abbreviation,
+			"images_urls":  c.ImagesURLs,
+		}},
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrAbbreviationAlreadyExists
+		}
+		return fmt.Errorf("failed to execute UpdateOne category: %w", err)
+	}
+	if res.MatchedCount < 1 {
+		return ErrCategoryIDNotFound
+	}
+	return nil
+}
+
+// DeleteCategory deletes a level 1 category without child categories nor products,
+// in a single transaction so no product can be added between the check and the delete.
+// It returns ErrCategoryHasChildCategories or ErrCategoryHasProducts when the category is in use.
+func (s *MongoStore) DeleteCategory(ctx context.Context, id primitive.ObjectID) error {
+	return s.deleteUnused(ctx, id, func(sc mongo.SessionContext) error {
+		coll := s.db.Collection(categoryCollection)
+		res, err := coll.DeleteOne(sc, bson.M{"_id": id, "child_categories.0": bson.M{"$exists": false}})
+		if err != nil {
+			return fmt.Errorf("failed to execute DeleteOne category: %w", err)
+		}
+		if res.DeletedCount > 0 {
+			return nil
+		}
+		n, err := coll.CountDocuments(sc, bson.M{"_id": id})
+		if err != nil {
+			return fmt.Errorf("failed to execute CountDocuments category: %w", err)
+		}
+		if n > 0 {
+			return ErrCategoryHasChildCategories
+		}
+		return ErrCategoryIDNotFound
+	})
+}
+
+// DeleteChildCategory removes a level 2 category without products from the parent
+// child categories, in a single transaction so no product can be added between the
+// check and the delete. It returns ErrCategoryHasProducts when the category is in use.
+func (s *MongoStore) DeleteChildCategory(ctx context.Context, parentID, childID primitive.ObjectID) error {
+	return s.deleteUnused(ctx, childID, func(sc mongo.SessionContext) error {
+		return s.RemoveChildCategory(sc, parentID, childID)
+	})
+}
+
+// deleteUnused runs del in a transaction once it checked the category has no products.
+func (s *MongoStore) deleteUnused(ctx context.Context, id primitive.ObjectID, del func(sc mongo.SessionContext) error) error {
+	session, err := s.db.Client().StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start mongo session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		n, err := s.CountProducts(sc, id)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			return nil, ErrCategoryHasProducts
+		}
+		return nil, del(sc)
+	})
+	return err
+}
+
+// AddChildCategory appends a level 2 category to the parent child categories.
+func (s *MongoStore) AddChildCategory(ctx context.Context, parentID primitive.ObjectID, child category.Category) error {
+	res, err := s.db.Collection(categoryCollection).UpdateOne(ctx,
+		bson.M{"_id": parentID},
+		bson.M{"$push": bson.M{"child_categories": child}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute UpdateOne category: %w", err)
+	}
+	if res.MatchedCount < 1 {
+		return ErrCategoryIDNotFound
+	}
+	return nil
+}
+
+// UpdateChildCategory updates the fields of a level 2 category embedded in the parent.
+func (s *MongoStore) UpdateChildCategory(ctx context.Context, parentID primitive.ObjectID, child category.Category) error {
+	res, err := s.db.Collection(categoryCollection).UpdateOne(ctx,
+		bson.M{"_id": parentID, "child_categories._id": child.ID},
+		bson.M{"$set": bson.M{
+			"child_categories.$.name_en":     child.Name_EN,
+			"child_categories.$.name_id":     child.Name_ID,
+			"child_categories.$.images_urls": child.ImagesURLs,
+		}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute UpdateOne category: %w", err)
+	}
+	if res.MatchedCount < 1 {
+		return ErrCategoryIDNotFound
+	}
+	return nil
+}
+
+// RemoveChildCategory removes a level 2 category from the parent child categories.
+func (s *MongoStore) RemoveChildCategory(ctx context.Context, parentID, childID primitive.ObjectID) error {
+	res, err := s.db.Collection(categoryCollection).UpdateOne(ctx,
+		bson.M{"_id": parentID},
+		bson.M{"$pull": bson.M{"child_categories": bson.M{"_id": childID}}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute UpdateOne category: %w", err)
+	}
+	if res.ModifiedCount < 1 {
+		return ErrCategoryIDNotFound
+	}
+	return nil
+}
+
+// MoveChildCategory moves a level 2 category from a parent to another and points
+// its products to the new parent, in a single transaction so a failure leaves
+// neither the category nor its products half moved. The variant SKUs of the products
+// must contain abbreviation, the abbreviation of the new parent, else it returns
+// ErrProductSKUMismatch and nothing is moved.
+func (s *MongoStore) MoveChildCategory(ctx context.Context, fromParentID, toParentID primitive.ObjectID, child category.Category, abbreviation string) error {
+	session, err := s.db.Client().StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start mongo session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		n, err := s.db.Collection(productCollection).CountDocuments(sc, bson.M{
+			"category2_id": child.ID,
+			"deleted_at":   nil,
+			"variants": bson.M{"$elemMatch": bson.M{
+				"sku": bson.M{"$not": primitive.Regex{Pattern: regexp.QuoteMeta(abbreviation)}},
+			}},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute CountDocuments product: %w", err)
+		}
+		if n > 0 {
+			return nil, ErrProductSKUMismatch
+		}
+
+		if err := s.AddChildCategory(sc, toParentID, child); err != nil {
+			return nil, err
+		}
+		if err := s.RemoveChildCategory(sc, fromParentID, child.ID); err != nil {
+			return nil, err
+		}
+		return nil, s.updateProductsCategory1(sc, child.ID, toParentID)
+	})
+	return err
+}
+
+// CountProducts counts the products, soft deleted ones excluded, referencing
+// the category either as their level 1 or level 2 category.
+func (s *MongoStore) CountProducts(ctx context.Context, categoryID primitive.ObjectID) (int64, error) {
+	n, err := s.db.Collection(productCollection).CountDocuments(ctx, bson.M{
+		"deleted_at": nil,
+		"$or": bson.A{
+			bson.M{"category1_id": categoryID},
+			bson.M{"category2_id": categoryID},
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute CountDocuments product: %w", err)
+	}
+	return n, nil
+}
+
+// updateProductsCategory1 points the products of a level 2 category to its new level 1 category.
+func (s *MongoStore) updateProductsCategory1(ctx context.Context, category2ID, category1ID primitive.ObjectID) error {
+	if _, err := s.db.Collection(productCollection).UpdateMany(ctx,
+		bson.M{"category2_id": category2ID},
+		bson.M{"$set": bson.M{"category1_id": category1ID}},
+	); err != nil {
+		return fmt.Errorf("failed to execute UpdateMany product: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+// Package darkstore implements store gRPC service methods
+// to manage dropezy darkstores.
+package darkstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/genproto/googleapis/type/dayofweek"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/darkstore"
+
+	"github.com/dropezy/storefront-backend/ems-api/services"
+	"github.com/dropezy/storefront-backend/ems-api/services/audit"
+	"github.com/dropezy/storefront-backend/ems-api/services/auth"
+	"github.com/dropezy/storefront-backend/ems-api/validation"
+
+	// protobuf
+	stpb "github.com/dropezy/proto/ems/v1/store"
+)
+
+const serviceName = "store"
+
+// Handler holds store gRPC service implementation.
+type Handler struct {
+	// utilities
+	logger zerolog.Logger
+
+	// service dependencies
+	store Store
+}
+
+// NewHandler returns a new store service handler.
+func NewHandler(logger zerolog.Logger, store Store) *Handler {
+	return &Handler{
+		logger: logger.With().Str("service", serviceName).Logger(),
+		store:  store,
+	}
+}
+
+// RegisterService registers the store service to the gRPC server.
+func RegisterService(logger zerolog.Logger, store Store) func(srv *grpc.Server) error {
+	return func(srv *grpc.Server) error {
+		h := NewHandler(logger, store)
+		stpb.RegisterStoreServiceServer(srv, h)
+		return nil
+	}
+}
+
+func RegisterGateway(ctx context.Context, mux *runtime.ServeMux, addr string, opts []grpc.DialOption) error {
+	return stpb.RegisterStoreServiceHandlerFromEndpoint(ctx, mux, addr, opts)
+}
+
+// List will fetch darkstores from storage, principals limited to some stores
+// only get their stores.
+func (h *Handler) List(ctx context.Context, req *stpb.ListRequest) (*stpb.ListResponse, error) {
+	darkstores, err := h.store.ListStores(ctx, req.GetActiveOnly())
+	if err != nil {
+		h.logger.Err(err).Msg("failed to fetch stores from store")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	var storesPb []*stpb.Store
+	for _, ds := range darkstores {
+		if !auth.StoreAllowed(ctx, ds.ID.Hex()) {
+			continue
+		}
+		storesPb = append(storesPb, toStorePb(ds))
+	}
+
+	return &stpb.ListResponse{
+		Stores: storesPb,
+	}, nil
+}
+
+// Get will fetch a darkstore by its id from storage.
+func (h *Handler) Get(ctx context.Context, req *stpb.GetRequest) (*stpb.GetResponse, error) {
+	id, err := primitive.ObjectIDFromHex(req.GetStoreId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidStoreID.Error())
+	}
+
+	ds, err := h.store.GetStore(ctx, id)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch store from store")
+	}
+
+	return &stpb.GetResponse{
+		Store: toStorePb(ds),
+	}, nil
+}
+
+// Create will validate and insert a new darkstore.
+func (h *Handler) Create(ctx context.Context, req *stpb.CreateRequest) (*stpb.CreateResponse, error) {
+	if req.GetStore() == nil {
+		return nil, status.Error(codes.InvalidArgument, ErrStoreIsRequired.Error())
+	}
+
+	ds := fromStorePb(req.GetStore())
+	ds.ID = primitive.NewObjectID()
+	if err := h.validateStore(ctx, ds); err != nil {
+		return nil, err
+	}
+
+	if err := h.store.CreateStore(ctx, ds); err != nil {
+		h.logger.Err(err).Msg("failed to create store on store")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	audit.Track(ctx, audit.EntityStore, ds.ID.Hex(), nil, ds)
+
+	return &stpb.CreateResponse{
+		Store: toStorePb(ds),
+	}, nil
+}
+
+// Update will validate and replace an existing darkstore.
+func (h *Handler) Update(ctx context.Context, req *stpb.UpdateRequest) (*stpb.UpdateResponse, error) {
+	if req.GetStore() == nil {
+		return nil, status.Error(codes.InvalidArgument, ErrStoreIsRequired.Error())
+	}
+	id, err := primitive.ObjectIDFromHex(req.GetStore().GetStoreId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidStoreID.Error())
+	}
+
+	ds := fromStorePb(req.GetStore())
+	ds.ID = id
+	if err := h.validateStore(ctx, ds); err != nil {
+		return nil, err
+	}
+
+	existing, err := h.store.GetStore(ctx, ds.ID)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch store from store")
+	}
+	if err := h.store.UpdateStore(ctx, ds); err != nil {
+		return nil, h.toStatusError(err, "failed to update store on store")
+	}
+
+	ds, err = h.store.GetStore(ctx, ds.ID)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch store from store")
+	}
+	audit.Track(ctx, audit.EntityStore, ds.ID.Hex(), existing, ds)
+
+	return &stpb.UpdateResponse{
+		Store: toStorePb(ds),
+	}, nil
+}
+
+// validateStore checks the darkstore parameters and makes sure
+// the location code is unique, it returns a gRPC status error.
+func (h *Handler) validateStore(ctx context.Context, ds *darkstore.Store) error {
+	if err := validation.ValidateStore(ds); err != nil {
+		return services.InvalidArgumentError(err)
+	}
+
+	exists, err := h.store.LocationCodeExists(ctx, ds.LocationCode, ds.ID)
+	if err != nil {
+		h.logger.Err(err).Msg("failed to check store location code on store")
+		return status.Error(codes.Internal, err.Error())
+	}
+	if exists {
+		return status.Error(codes.AlreadyExists, ErrLocationCodeAlreadyExists.Error())
+	}
+	return nil
+}
+
+// toStatusError converts store errors to gRPC status errors,
+// unexpected errors are logged with msg.
+func (h *Handler) toStatusError(err error, msg string) error {
+	if errors.Is(err, ErrStoreNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	h.logger.Err(err).Msg(msg)
+	return status.Error(codes.Internal, err.Error())
+}
+
+func toStorePb(ds *darkstore.Store) *stpb.Store {
+	var openingHoursPb []*stpb.OpeningHours
+	for _, oh := range ds.OpeningHours {
+		day := dayofweek.DayOfWeek(oh.Day)
+		if oh.Day == time.Sunday {
+			day = dayofweek.DayOfWeek_SUNDAY
+		}
+		openingHoursPb = append(openingHoursPb, &stpb.OpeningHours{
+			Day:       day,
+			OpenTime:  oh.Open,
+			CloseTime: oh.Close,
+		})
+	}
+
+	return &stpb.Store{
+		StoreId:            ds.ID.Hex(),
+		Name:               ds.Name,
+		LocationCode:       ds.LocationCode,
+		ShoptreeLocationId: ds.ShoptreeLocationID,
+		Address:            ds.Address,
+		Latitude:           ds.Latitude,
+		Longitude:          ds.Longitude,
+		OpeningHours:       openingHoursPb,
+		Active:             ds.Active,
+	}
+}
+
+func fromStorePb(pb *stpb.Store) *darkstore.Store {
+	ds := &darkstore.Store{
+		Name:               pb.GetName(),
+		LocationCode:       pb.GetLocationCode(),
+		ShoptreeLocationID: pb.GetShoptreeLocationId(),
+		Address:            pb.GetAddress(),
+		Latitude:           pb.GetLatitude(),
+		Longitude:          pb.GetLongitude(),
+		Active:             pb.GetActive(),
+	}
+	for _, ohPb := range pb.GetOpeningHours() {
+		// time.Weekday starts from sunday while dayofweek starts from monday,
+		// unspecified day is mapped to an invalid weekday to fail validation.
+		day := time.Weekday(ohPb.GetDay())
+		switch ohPb.GetDay() {
+		case dayofweek.DayOfWeek_SUNDAY:
+			day = time.Sunday
+		case dayofweek.DayOfWeek_DAY_OF_WEEK_UNSPECIFIED:
+			day = -1
+		}
+		ds.OpeningHours = append(ds.OpeningHours, &darkstore.OpeningHours{
+			Day:   day,
+			Open:  ohPb.GetOpenTime(),
+			Close: ohPb.GetCloseTime(),
+		})
+	}
+	return ds
+}
@@ -0,0 +1,10 @@
+package darkstore
+
+import "errors"
+
+var (
+	ErrInvalidStoreID            = errors.New("invalid store id")
+	ErrStoreIsRequired           = errors.New("store is required")
+	ErrStoreNotFound             = errors.New("store not found")
+	ErrLocationCodeAlreadyExists = errors.New("store location code already exists")
+)
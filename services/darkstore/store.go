@@ -0,0 +1,100 @@
+package darkstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/darkstore"
+)
+
+const darkstoreCollection = "store"
+
+// Store is the storage contract required by the darkstore service.
+type Store interface {
+	ListStores(ctx context.Context, activeOnly bool) ([]*darkstore.Store, error)
+	GetStore(ctx context.Context, id primitive.ObjectID) (*darkstore.Store, error)
+	LocationCodeExists(ctx context.Context, locationCode string, excludeID primitive.ObjectID) (bool, error)
+	CreateStore(ctx context.Context, s *darkstore.Store) error
+	UpdateStore(ctx context.Context, s *darkstore.Store) error
+}
+
+// MongoStore implements Store backed by mongo database.
+type MongoStore struct {
+	db *mongo.Database
+}
+
+// NewMongoStore returns a new darkstore store backed by db.
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{db: db}
+}
+
+// ListStores fetches all darkstores ordered by their name.
+func (s *MongoStore) ListStores(ctx context.Context, activeOnly bool) ([]*darkstore.Store, error) {
+	query := bson.M{}
+	if activeOnly {
+		query["active"] = true
+	}
+
+	cur, err := s.db.Collection(darkstoreCollection).Find(ctx, query,
+		options.Find().SetSort(bson.D{{Key: "name", Value: 1}}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Find stores: %w", err)
+	}
+
+	var darkstores []*darkstore.Store
+	if err := cur.All(ctx, &darkstores); err != nil {
+		return nil, fmt.Errorf("failed to decode darkstores: %w", err)
+	}
+	return darkstores, nil
+}
+
+// GetStore fetches a darkstore by its id.
+func (s *MongoStore) GetStore(ctx context.Context, id primitive.ObjectID) (*darkstore.Store, error) {
+	ds := &darkstore.Store{}
+	if err := s.db.Collection(darkstoreCollection).FindOne(ctx, bson.M{"_id": id}).Decode(ds); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrStoreNotFound
+		}
+		return nil, fmt.Errorf("failed to execute FindOne store: %w", err)
+	}
+	return ds, nil
+}
+
+// LocationCodeExists checks whether a darkstore other than excludeID already uses the location code.
+func (s *MongoStore) LocationCodeExists(ctx context.Context, locationCode string, excludeID primitive.ObjectID) (bool, error) {
+	n, err := s.db.Collection(darkstoreCollection).CountDocuments(ctx, bson.M{
+		"_id":           bson.M{"$ne": excludeID},
+		"location_code": locationCode,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to execute CountDocuments store: %w", err)
+	}
+	return n > 0, nil
+}
+
+// CreateStore inserts a new darkstore.
+func (s *MongoStore) CreateStore(ctx context.Context, ds *darkstore.Store) error {
+	if _, err := s.db.Collection(darkstoreCollection).InsertOne(ctx, ds); err != nil {
+		return fmt.Errorf("failed to execute InsertOne store: %w", err)
+	}
+	return nil
+}
+
+// UpdateStore replaces an existing darkstore.
+func (s *MongoStore) UpdateStore(ctx context.Context, ds *darkstore.Store) error {
+	res, err := s.db.Collection(darkstoreCollection).ReplaceOne(ctx, bson.M{"_id": ds.ID}, ds)
+	if err != nil {
+		return fmt.Errorf("failed to execute ReplaceOne store: %w", err)
+	}
+	if res.MatchedCount < 1 {
+		return ErrStoreNotFound
+	}
+	return nil
+}
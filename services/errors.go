@@ -0,0 +1,28 @@
+package services
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dropezy/storefront-backend/ems-api/validation"
+)
+
+// InvalidArgumentError returns an InvalidArgument status error,
+// validation field errors are attached as bad request field violations.
+func InvalidArgumentError(err error) error {
+	st := status.New(codes.InvalidArgument, err.Error())
+
+	if fields := validation.Fields(err); len(fields) > 0 {
+		br := &errdetails.BadRequest{}
+		for _, fe := range fields {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field: fe.Field, Description: fe.Err.Error(),
+			})
+		}
+		if withDetails, err := st.WithDetails(br); err == nil {
+			st = withDetails
+		}
+	}
+	return st.Err()
+}
@@ -0,0 +1,136 @@
+// Package i18n resolves the language used to localize service responses
+// from the Accept-Language header or gRPC metadata.
+package i18n
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/metadata"
+)
+
+// Language is a supported response language.
+type Language string
+
+const (
+	Indonesian Language = "id"
+	English    Language = "en"
+
+	// DefaultLanguage is used when the client has no supported language preference.
+	DefaultLanguage = Indonesian
+)
+
+// metadataKey is the gRPC metadata key holding the client language preference,
+// gateway requests forward the Accept-Language header with the gateway prefix.
+const metadataKey = "accept-language"
+
+// fallbackLanguages are tried in order after the client preferred languages.
+var fallbackLanguages = []Language{Indonesian, English}
+
+// Text holds the localized values of a single text field.
+type Text struct {
+	ID string
+	EN string
+}
+
+// get returns the value of the text in the given language.
+func (t Text) get(lang Language) string {
+	if lang == English {
+		return t.EN
+	}
+	return t.ID
+}
+
+// Localizer picks the localized value of texts based on the client preference.
+type Localizer struct {
+	languages []Language
+}
+
+// FromContext returns the localizer for the language preference sent by the client.
+func FromContext(ctx context.Context) *Localizer {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, key := range []string{metadataKey, runtime.MetadataPrefix + metadataKey} {
+		if values := md.Get(key); len(values) > 0 {
+			return Parse(strings.Join(values, ","))
+		}
+	}
+	return Parse("")
+}
+
+// Parse returns the localizer for an Accept-Language header value,
+// e.g. "en-US,en;q=0.9,id;q=0.8".
+func Parse(header string) *Localizer {
+	type preference struct {
+		lang    Language
+		quality float64
+	}
+
+	var prefs []preference
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+
+		quality := 1.0
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			v, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64)
+			if err != nil || v <= 0 {
+				continue
+			}
+			quality = v
+		}
+
+		if tag == "*" {
+			continue
+		}
+
+		// only the primary language subtag matters, e.g. en-US is en.
+		base, _, _ := strings.Cut(tag, "-")
+		switch base {
+		case "id", "in": // "in" is the deprecated Indonesian code still sent by old clients.
+			prefs = append(prefs, preference{Indonesian, quality})
+		case "en":
+			prefs = append(prefs, preference{English, quality})
+		}
+	}
+	sort.SliceStable(prefs, func(i, j int) bool {
+		return prefs[i].quality > prefs[j].quality
+	})
+
+	l := &Localizer{}
+	seen := map[Language]bool{}
+	for _, p := range prefs {
+		if !seen[p.lang] {
+			seen[p.lang] = true
+			l.languages = append(l.languages, p.lang)
+		}
+	}
+	for _, lang := range fallbackLanguages {
+		if !seen[lang] {
+			seen[lang] = true
+			l.languages = append(l.languages, lang)
+		}
+	}
+	return l
+}
+
+// Language returns the preferred language of the client.
+func (l *Localizer) Language() Language {
+	return l.languages[0]
+}
+
+// String returns the value of the text in the first language
+// of the client preference that has a non empty value.
+func (l *Localizer) String(t Text) string {
+	for _, lang := range l.languages {
+		if v := t.get(lang); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package i18n
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		header   string
+		wantLang Language
+	}{
+		{name: "Empty", header: "", wantLang: Indonesian},
+		{name: "Wildcard", header: "*", wantLang: Indonesian},
+		{name: "English", header: "en", wantLang: English},
+		{name: "Region", header: "en-US", wantLang: English},
+		{name: "DeprecatedIndonesian", header: "in", wantLang: Indonesian},
+		{name: "Quality", header: "en;q=0.5,id;q=0.8", wantLang: Indonesian},
+		{name: "Unsupported", header: "fr,en;q=0.2", wantLang: English},
+		{name: "UnsupportedOnly", header: "fr", wantLang: DefaultLanguage},
+		{name: "WildcardWithLanguage", header: "en,*;q=0.1", wantLang: English},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			l := Parse(test.header)
+			if got := l.Language(); got != test.wantLang {
+				t.Errorf("Parse(%q).Language(), got = %s, want = %s", test.header, got, test.wantLang)
+			}
+		})
+	}
+}
+
+func TestLocalizerString(t *testing.T) {
+	t.Parallel()
+
+	text := Text{ID: "Telur", EN: "Eggs"}
+	if got := Parse("en").String(text); got != "Eggs" {
+		t.Errorf("String(_), got = %s, want = Eggs", got)
+	}
+	if got := Parse("id").String(text); got != "Telur" {
+		t.Errorf("String(_), got = %s, want = Telur", got)
+	}
+	// falls back to the next language when the preferred one is empty.
+	if got := Parse("en").String(Text{ID: "Telur"}); got != "Telur" {
+		t.Errorf("String(_), got = %s, want = Telur", got)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	t.Parallel()
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs("grpcgateway-accept-language", "en-GB"),
+	)
+	if got := FromContext(ctx).Language(); got != English {
+		t.Errorf("FromContext(_).Language(), got = %s, want = %s", got, English)
+	}
+	if got := FromContext(context.Background()).Language(); got != DefaultLanguage {
+		t.Errorf("FromContext(_).Language(), got = %s, want = %s", got, DefaultLanguage)
+	}
+}
@@ -0,0 +1,16 @@
+package inventory
+
+import "errors"
+
+var (
+	ErrInvalidStoreID           = errors.New("invalid store id")
+	ErrInvalidVariantID         = errors.New("invalid variant id")
+	ErrVariantIsRequired        = errors.New("variant id or shoptree variant id is required")
+	ErrUpdateMaskIsRequired     = errors.New("update mask is required")
+	ErrInvalidUpdateMaskPath    = errors.New("invalid update mask path")
+	ErrInvalidStock             = errors.New("stock can't be negative")
+	ErrPriceIsRequired          = errors.New("price is required")
+	ErrInvalidStatus            = errors.New("invalid product status")
+	ErrInventoryItemNotFound    = errors.New("inventory item not found")
+	ErrInventoryVersionMismatch = errors.New("inventory item has been modified, fetch the latest version and retry")
+)
@@ -0,0 +1,228 @@
+// Package inventory implements inventory gRPC service methods
+// to manage dropezy store stocks and prices.
+package inventory
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model"
+
+	"github.com/dropezy/storefront-backend/ems-api/services/audit"
+	"github.com/dropezy/storefront-backend/ems-api/services/pagination"
+
+	// protobuf
+	invpb "github.com/dropezy/proto/ems/v1/inventory"
+	mpb "github.com/dropezy/proto/meta"
+
+	// old protobuf
+	prpb "github.com/dropezy/proto/v1/product"
+)
+
+const serviceName = "inventory"
+
+// Handler holds inventory gRPC service implementation.
+type Handler struct {
+	// utilities
+	logger zerolog.Logger
+
+	// service dependencies
+	store Store
+}
+
+// NewHandler returns a new inventory service handler.
+func NewHandler(logger zerolog.Logger, store Store) *Handler {
+	return &Handler{
+		logger: logger.With().Str("service", serviceName).Logger(),
+		store:  store,
+	}
+}
+
+// RegisterService registers the inventory service to the gRPC server.
+func RegisterService(logger zerolog.Logger, store Store) func(srv *grpc.Server) error {
+	return func(srv *grpc.Server) error {
+		h := NewHandler(logger, store)
+		invpb.RegisterInventoryServiceServer(srv, h)
+		return nil
+	}
+}
+
+func RegisterGateway(ctx context.Context, mux *runtime.ServeMux, addr string, opts []grpc.DialOption) error {
+	return invpb.RegisterInventoryServiceHandlerFromEndpoint(ctx, mux, addr, opts)
+}
+
+// List will fetch a page of a store inventory items from storage.
+func (h *Handler) List(ctx context.Context, req *invpb.ListRequest) (*invpb.ListResponse, error) {
+	storeID, err := primitive.ObjectIDFromHex(req.GetStoreId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidStoreID.Error())
+	}
+	after, err := pagination.DecodeToken(req.GetPageToken())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// fetch one extra item to know whether there is a next page.
+	pageSize := pagination.PageSize(req.GetPageSize())
+	items, err := h.store.ListItems(ctx, storeID, after, pageSize+1)
+	if err != nil {
+		h.logger.Err(err).Msg("failed to fetch inventory items from store")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	var nextPageToken string
+	if int64(len(items)) > pageSize {
+		items = items[:pageSize]
+		nextPageToken = pagination.EncodeToken(items[pageSize-1].ID)
+	}
+
+	var itemsPb []*invpb.InventoryItem
+	for _, item := range items {
+		itemsPb = append(itemsPb, toInventoryItemPb(item))
+	}
+
+	return &invpb.ListResponse{
+		Items:         itemsPb,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+// GetStock will fetch the stock, price and status of a variant in a store.
+func (h *Handler) GetStock(ctx context.Context, req *invpb.GetStockRequest) (*invpb.GetStockResponse, error) {
+	storeID, err := primitive.ObjectIDFromHex(req.GetStoreId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidStoreID.Error())
+	}
+
+	key := ItemKey{ShoptreeVariantID: req.GetShoptreeVariantId()}
+	switch {
+	case req.GetVariantId() != "":
+		if key.VariantID, err = primitive.ObjectIDFromHex(req.GetVariantId()); err != nil {
+			return nil, status.Error(codes.InvalidArgument, ErrInvalidVariantID.Error())
+		}
+	case key.ShoptreeVariantID == "":
+		return nil, status.Error(codes.InvalidArgument, ErrVariantIsRequired.Error())
+	}
+
+	item, err := h.store.GetItem(ctx, storeID, key)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch inventory item from store")
+	}
+
+	return &invpb.GetStockResponse{
+		Item: toInventoryItemPb(item),
+	}, nil
+}
+
+// Update will update the stock, price and status of a variant in a store listed
+// in the update mask. The item version must match the stored version, otherwise
+// the update is rejected with Aborted so the operator can reload and retry.
+func (h *Handler) Update(ctx context.Context, req *invpb.UpdateRequest) (*invpb.UpdateResponse, error) {
+	storeID, err := primitive.ObjectIDFromHex(req.GetStoreId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidStoreID.Error())
+	}
+
+	update, err := toItemUpdate(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	existing, err := h.store.UpdateItem(ctx, storeID, update)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to update inventory item on store")
+	}
+	item := update.apply(existing)
+	// inventory items are identified by their store and variant.
+	audit.Track(ctx, audit.EntityInventory, storeID.Hex()+"/"+update.VariantID.Hex(), existing, item)
+
+	return &invpb.UpdateResponse{
+		Item: toInventoryItemPb(item),
+	}, nil
+}
+
+// toStatusError converts store errors to gRPC status errors,
+// unexpected errors are logged with msg.
+func (h *Handler) toStatusError(err error, msg string) error {
+	switch {
+	case errors.Is(err, ErrInventoryItemNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrInventoryVersionMismatch):
+		return status.Error(codes.Aborted, err.Error())
+	}
+	h.logger.Err(err).Msg(msg)
+	return status.Error(codes.Internal, err.Error())
+}
+
+// toItemUpdate converts the update request item fields listed in the update mask to item update.
+func toItemUpdate(req *invpb.UpdateRequest) (*ItemUpdate, error) {
+	item := req.GetItem()
+	variantID, err := primitive.ObjectIDFromHex(item.GetVariantId())
+	if err != nil {
+		return nil, ErrInvalidVariantID
+	}
+
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) < 1 {
+		return nil, ErrUpdateMaskIsRequired
+	}
+
+	update := &ItemUpdate{
+		VariantID: variantID,
+		Version:   item.GetVersion(),
+	}
+	for _, path := range paths {
+		switch path {
+		case "stock":
+			stock := item.GetStock()
+			if stock < 0 {
+				return nil, ErrInvalidStock
+			}
+			update.Stock = &stock
+		case "price":
+			if item.GetPrice() == nil {
+				return nil, ErrPriceIsRequired
+			}
+			update.Price = &model.Amount{
+				Num: item.GetPrice().GetNum(),
+				Cur: item.GetPrice().GetCur(),
+			}
+		case "status":
+			st := item.GetStatus()
+			if st != prpb.ProductStatus_PRODUCT_STATUS_ENABLED &&
+				st != prpb.ProductStatus_PRODUCT_STATUS_DISABLED {
+				return nil, ErrInvalidStatus
+			}
+			update.Status = &st
+		default:
+			return nil, ErrInvalidUpdateMaskPath
+		}
+	}
+	return update, nil
+}
+
+func toInventoryItemPb(item *Item) *invpb.InventoryItem {
+	pb := &invpb.InventoryItem{
+		InventoryProductId: item.ID.Hex(),
+		ProductId:          item.ProductID.Hex(),
+		VariantId:          item.VariantID.Hex(),
+		ShoptreeVariantId:  item.ShoptreeVariantID,
+		Stock:              item.Stock,
+		Status:             item.Status,
+		Version:            item.Version,
+	}
+	if item.Price != nil {
+		pb.Price = &mpb.Amount{
+			Num: item.Price.Num,
+			Cur: item.Price.Cur,
+		}
+	}
+	return pb
+}
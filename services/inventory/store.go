@@ -0,0 +1,184 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model"
+	"github.com/dropezy/storefront-backend/internal/storage/model/inventory"
+
+	// protobuf
+	prpb "github.com/dropezy/proto/v1/product"
+)
+
+const inventoryCollection = "inventory"
+
+// Store is the storage contract required by the inventory service.
+type Store interface {
+	ListItems(ctx context.Context, storeID, after primitive.ObjectID, limit int64) ([]*Item, error)
+	GetItem(ctx context.Context, storeID primitive.ObjectID, key ItemKey) (*Item, error)
+	UpdateItem(ctx context.Context, storeID primitive.ObjectID, update *ItemUpdate) (*Item, error)
+}
+
+// Item is a single product of a store inventory. Version is increased on every
+// update and is used to detect concurrent modifications, items which have never
+// been updated through the inventory service have version 0.
+type Item struct {
+	inventory.Product `bson:",inline"`
+	Version           int64 `bson:"version"`
+}
+
+// ItemKey identifies an inventory item either by its variant id or shoptree variant id.
+type ItemKey struct {
+	VariantID         primitive.ObjectID
+	ShoptreeVariantID string
+}
+
+// ItemUpdate holds the inventory item fields to be updated, nil fields are left untouched.
+type ItemUpdate struct {
+	VariantID primitive.ObjectID
+	// Version is the item version the update is based on.
+	Version int64
+
+	Stock  *int32
+	Price  *model.Amount
+	Status *prpb.ProductStatus
+}
+
+// apply returns a copy of item with the update fields set and its version increased.
+func (u *ItemUpdate) apply(item *Item) *Item {
+	updated := *item
+	if u.Stock != nil {
+		updated.Stock = *u.Stock
+	}
+	if u.Price != nil {
+		updated.Price = u.Price
+	}
+	if u.Status != nil {
+		updated.Status = *u.Status
+	}
+	updated.Version++
+	return &updated
+}
+
+// MongoStore implements Store backed by mongo database.
+type MongoStore struct {
+	db *mongo.Database
+}
+
+// NewMongoStore returns a new inventory store backed by db.
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{db: db}
+}
+
+// ListItems fetches the inventory items of a store ordered by their id.
+func (s *MongoStore) ListItems(ctx context.Context, storeID, after primitive.ObjectID, limit int64) ([]*Item, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"store_id": storeID}}},
+		{{Key: "$unwind", Value: "$products"}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$products"}}},
+	}
+	if after != primitive.NilObjectID {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"_id": bson.M{"$gt": after}}}})
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		bson.D{{Key: "$limit", Value: limit}},
+	)
+
+	cur, err := s.db.Collection(inventoryCollection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Aggregate inventory items: %w", err)
+	}
+
+	var items []*Item
+	if err := cur.All(ctx, &items); err != nil {
+		return nil, fmt.Errorf("failed to decode inventory items: %w", err)
+	}
+	return items, nil
+}
+
+// GetItem fetches a single inventory item of a store.
+func (s *MongoStore) GetItem(ctx context.Context, storeID primitive.ObjectID, key ItemKey) (*Item, error) {
+	match := bson.M{"variant_id": key.VariantID}
+	if key.VariantID == primitive.NilObjectID {
+		match = bson.M{"shoptree_variant_id": key.ShoptreeVariantID}
+	}
+
+	inv := struct {
+		Products []*Item `bson:"products"`
+	}{}
+	if err := s.db.Collection(inventoryCollection).FindOne(ctx,
+		bson.M{"store_id": storeID, "products": bson.M{"$elemMatch": match}},
+		options.FindOne().SetProjection(bson.M{"products.$": 1}),
+	).Decode(&inv); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInventoryItemNotFound
+		}
+		return nil, fmt.Errorf("failed to execute FindOne inventory: %w", err)
+	}
+	if len(inv.Products) < 1 {
+		return nil, ErrInventoryItemNotFound
+	}
+	return inv.Products[0], nil
+}
+
+// UpdateItem updates an inventory item only when its version still matches
+// the update version and returns the item as it was before the update,
+// it returns ErrInventoryVersionMismatch when the item has been modified in the meantime.
+func (s *MongoStore) UpdateItem(ctx context.Context, storeID primitive.ObjectID, update *ItemUpdate) (*Item, error) {
+	set := bson.M{}
+	if update.Stock != nil {
+		set["products.$.stock"] = *update.Stock
+	}
+	if update.Price != nil {
+		set["products.$.price"] = update.Price
+	}
+	if update.Status != nil {
+		set["products.$.status"] = *update.Status
+	}
+
+	var version interface{} = update.Version
+	if update.Version == 0 {
+		// items which have never been updated don't have version yet.
+		version = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	inv := struct {
+		Products []*Item `bson:"products"`
+	}{}
+	err := s.db.Collection(inventoryCollection).FindOneAndUpdate(ctx,
+		bson.M{
+			"store_id": storeID,
+			"products": bson.M{"$elemMatch": bson.M{
+				"variant_id": update.VariantID,
+				"version":    version,
+			}},
+		},
+		bson.M{
+			"$set": set,
+			"$inc": bson.M{"products.$.version": 1},
+		},
+		options.FindOneAndUpdate().
+			SetProjection(bson.M{"products.$": 1}).
+			SetReturnDocument(options.Before),
+	).Decode(&inv)
+	if err == nil && len(inv.Products) > 0 {
+		return inv.Products[0], nil
+	}
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to execute FindOneAndUpdate inventory: %w", err)
+	}
+
+	// find out whether the item doesn't exist or it has been modified.
+	if _, err := s.GetItem(ctx, storeID, ItemKey{VariantID: update.VariantID}); err != nil {
+		return nil, err
+	}
+	return nil, ErrInventoryVersionMismatch
+}
@@ -0,0 +1,54 @@
+// Package pagination provides helpers to build and parse the
+// cursor based page tokens used by ems list methods.
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// DefaultPageSize is used when the client doesn't specify a page size.
+	DefaultPageSize = 50
+	// MaxPageSize is the maximum number of items returned in a single page.
+	MaxPageSize = 500
+)
+
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// PageSize returns the page size to be used for the requested size,
+// falling back to DefaultPageSize and capping it to MaxPageSize.
+func PageSize(size int32) int64 {
+	switch {
+	case size <= 0:
+		return DefaultPageSize
+	case size > MaxPageSize:
+		return MaxPageSize
+	}
+	return int64(size)
+}
+
+// EncodeToken builds an opaque page token from the id of
+// the last item returned on the current page.
+func EncodeToken(id primitive.ObjectID) string {
+	return base64.RawURLEncoding.EncodeToString(id[:])
+}
+
+// DecodeToken returns the id of the last item returned on the previous page.
+// An empty token means the first page is requested, in which case
+// primitive.NilObjectID is returned.
+func DecodeToken(token string) (primitive.ObjectID, error) {
+	var id primitive.ObjectID
+	if token == "" {
+		return id, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) != len(id) {
+		return id, ErrInvalidPageToken
+	}
+	copy(id[:], b)
+	return id, nil
+}
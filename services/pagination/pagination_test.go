@@ -0,0 +1,74 @@
+package pagination
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestToken(t *testing.T) {
+	t.Parallel()
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		t.Parallel()
+
+		id := primitive.NewObjectID()
+		got, err := DecodeToken(EncodeToken(id))
+		if err != nil {
+			t.Fatalf("expected nil error, got = %v", err)
+		}
+		if got != id {
+			t.Fatalf("DecodeToken(EncodeToken(%v)), got = %v", id, got)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := DecodeToken("")
+		if err != nil {
+			t.Fatalf("expected nil error, got = %v", err)
+		}
+		if got != primitive.NilObjectID {
+			t.Fatalf("DecodeToken(\"\"), got = %v, want = %v", got, primitive.NilObjectID)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "NotBase64", token: "not a token!"},
+		{name: "InvalidLength", token: "YWJj"},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := DecodeToken(test.token); !errors.Is(err, ErrInvalidPageToken) {
+				t.Fatalf("DecodeToken(%q) error, got = %v, want = %v", test.token, err, ErrInvalidPageToken)
+			}
+		})
+	}
+}
+
+func TestPageSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		size int32
+		want int64
+	}{
+		{size: 0, want: DefaultPageSize},
+		{size: -1, want: DefaultPageSize},
+		{size: 10, want: 10},
+		{size: MaxPageSize + 1, want: MaxPageSize},
+	}
+	for _, test := range tests {
+		if got := PageSize(test.size); got != test.want {
+			t.Errorf("PageSize(%d), got = %d, want = %d", test.size, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package product
+
+import "errors"
+
+var (
+	ErrInvalidCategory1ID = errors.New("invalid category 1 id")
+	ErrInvalidCategory2ID = errors.New("invalid category 2 id")
+	ErrInvalidBrandID     = errors.New("invalid brand id")
+
+	ErrInvalidProductID            = errors.New("invalid product id")
+	ErrSKUIsRequired               = errors.New("sku is required")
+	ErrBarcodeIsRequired           = errors.New("barcode is required")
+	ErrShoptreeVariantIDIsRequired = errors.New("shoptree variant id is required")
+	ErrProductNotFound             = errors.New("product not found")
+	ErrProductIsRequired           = errors.New("product is required")
+	ErrUpdateMaskIsRequired        = errors.New("update mask is required")
+	ErrInvalidUpdateMaskPath       = errors.New("invalid update mask path")
+	ErrProductChanged              = errors.New("product categories changed while it was updated")
+
+	ErrInvalidVariantID          = errors.New("invalid variant id")
+	ErrInvalidVariantTypeID      = errors.New("invalid variant type id")
+	ErrVariantTypeNotFound       = errors.New("variant type not found")
+	ErrCategoryNotFound          = errors.New("category not found")
+	ErrCategory2NotChildOfParent = errors.New("category 2 is not a child of category 1")
+)
@@ -4,16 +4,24 @@ package product
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
-	"github.com/dropezy/storefront-backend/internal/storage"
 	"github.com/dropezy/storefront-backend/internal/storage/model/product"
 
+	"github.com/dropezy/storefront-backend/ems-api/services"
+	"github.com/dropezy/storefront-backend/ems-api/services/audit"
+	"github.com/dropezy/storefront-backend/ems-api/services/i18n"
+	"github.com/dropezy/storefront-backend/ems-api/services/pagination"
+	"github.com/dropezy/storefront-backend/ems-api/validation"
+
 	// protobuf
 	prpb "github.com/dropezy/proto/ems/v1/product"
 
@@ -30,11 +38,11 @@ type Handler struct {
 	logger zerolog.Logger
 
 	// service dependencies
-	store storage.ProductStore
+	store Store
 }
 
 // NewHandler returns a new product service handler.
-func NewHandler(logger zerolog.Logger, store storage.ProductStore) *Handler {
+func NewHandler(logger zerolog.Logger, store Store) *Handler {
 	return &Handler{
 		logger: logger.With().Str("service", serviceName).Logger(),
 		store:  store,
@@ -42,7 +50,7 @@ func NewHandler(logger zerolog.Logger, store storage.ProductStore) *Handler {
 }
 
 // RegisterService registers the product service to the gRPC server.
-func RegisterService(logger zerolog.Logger, store storage.ProductStore) func(srv *grpc.Server) error {
+func RegisterService(logger zerolog.Logger, store Store) func(srv *grpc.Server) error {
 	return func(srv *grpc.Server) error {
 		h := NewHandler(logger, store)
 		prpb.RegisterProductServiceServer(srv, h)
@@ -54,33 +62,537 @@ func RegisterGateway(ctx context.Context, mux *runtime.ServeMux, addr string, op
 	return prpb.RegisterProductServiceHandlerFromEndpoint(ctx, mux, addr, opts)
 }
 
-// Get will fetch products from storage.
+// Get will fetch a page of products matching the request filters from storage.
 func (h *Handler) Get(ctx context.Context, req *prpb.GetRequest) (*prpb.GetResponse, error) {
-	products, err := h.store.GetProducts(ctx)
+	filter, err := toListFilter(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// fetch one extra product to know whether there is a next page.
+	pageSize := filter.Limit
+	filter.Limit++
+
+	products, err := h.store.ListProducts(ctx, filter)
 	if err != nil {
 		h.logger.Err(err).Msg("failed to fetch products from store")
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
+	var nextPageToken string
+	if int64(len(products)) > pageSize {
+		products = products[:pageSize]
+		nextPageToken = pagination.EncodeToken(products[pageSize-1].ID)
+	}
+
+	variantTypes, err := h.getVariantTypes(ctx, products...)
+	if err != nil {
+		return nil, err
+	}
+
 	return &prpb.GetResponse{
-		Products: toProductsPb(products),
+		Products:      toProductsPb(products, variantTypes, i18n.FromContext(ctx)),
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+// GetByID will fetch a product by its id from storage.
+func (h *Handler) GetByID(ctx context.Context, req *prpb.GetByIDRequest) (*prpb.GetByIDResponse, error) {
+	id, err := primitive.ObjectIDFromHex(req.GetProductId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidProductID.Error())
+	}
+
+	p, err := h.store.GetProduct(ctx, id)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch product by id from store")
+	}
+
+	variantTypes, err := h.getVariantTypes(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &prpb.GetByIDResponse{
+		Product: toProductPb(p, variantTypes, i18n.FromContext(ctx)),
+	}, nil
+}
+
+// GetBySKU will fetch the product owning the variant with the requested structured sku.
+func (h *Handler) GetBySKU(ctx context.Context, req *prpb.GetBySKURequest) (*prpb.GetBySKUResponse, error) {
+	if req.GetSku() == "" {
+		return nil, status.Error(codes.InvalidArgument, ErrSKUIsRequired.Error())
+	}
+
+	p, err := h.store.GetProductBySKU(ctx, req.GetSku())
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch product by sku from store")
+	}
+
+	variantTypes, err := h.getVariantTypes(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &prpb.GetBySKUResponse{
+		Product: toProductPb(p, variantTypes, i18n.FromContext(ctx)),
+	}, nil
+}
+
+// GetByBarcode will fetch the product owning the variant with the requested barcode.
+func (h *Handler) GetByBarcode(ctx context.Context, req *prpb.GetByBarcodeRequest) (*prpb.GetByBarcodeResponse, error) {
+	if req.GetBarcode() == "" {
+		return nil, status.Error(codes.InvalidArgument, ErrBarcodeIsRequired.Error())
+	}
+
+	p, err := h.store.GetProductByBarcode(ctx, req.GetBarcode())
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch product by barcode from store")
+	}
+
+	variantTypes, err := h.getVariantTypes(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &prpb.GetByBarcodeResponse{
+		Product: toProductPb(p, variantTypes, i18n.FromContext(ctx)),
+	}, nil
+}
+
+// GetByShoptreeVariantID will fetch the product owning the variant
+// with the requested shoptree variant id.
+func (h *Handler) GetByShoptreeVariantID(ctx context.Context, req *prpb.GetByShoptreeVariantIDRequest) (*prpb.GetByShoptreeVariantIDResponse, error) {
+	if req.GetShoptreeVariantId() == "" {
+		return nil, status.Error(codes.InvalidArgument, ErrShoptreeVariantIDIsRequired.Error())
+	}
+
+	p, err := h.store.GetProductByShoptreeVariantID(ctx, req.GetShoptreeVariantId())
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch product by shoptree variant id from store")
+	}
+
+	variantTypes, err := h.getVariantTypes(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &prpb.GetByShoptreeVariantIDResponse{
+		Product: toProductPb(p, variantTypes, i18n.FromContext(ctx)),
+	}, nil
+}
+
+// Create will validate and insert a new product along with its variants.
+func (h *Handler) Create(ctx context.Context, req *prpb.CreateRequest) (*prpb.CreateResponse, error) {
+	p, err := fromProductPb(req.GetProduct())
+	if err != nil {
+		return nil, services.InvalidArgumentError(err)
+	}
+
+	// new products and variants always get new ids.
+	p.ID = primitive.NewObjectID()
+	for _, v := range p.Variants {
+		v.ID = primitive.NewObjectID()
+	}
+
+	variantTypes, err := h.validateProduct(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.store.CreateProduct(ctx, p); err != nil {
+		h.logger.Err(err).Msg("failed to create product on store")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	audit.Track(ctx, audit.EntityProduct, p.ID.Hex(), nil, p)
+
+	return &prpb.CreateResponse{
+		Product: toProductPb(p, variantTypes, i18n.FromContext(ctx)),
+	}, nil
+}
+
+// Update will validate and update the fields of an existing product listed in the update mask,
+// variants without variant id are added as new variants when the variants are updated.
+func (h *Handler) Update(ctx context.Context, req *prpb.UpdateRequest) (*prpb.UpdateResponse, error) {
+	p, err := fromProductPb(req.GetProduct())
+	if err != nil {
+		return nil, services.InvalidArgumentError(err)
+	}
+	if p.ID == primitive.NilObjectID {
+		return nil, services.InvalidArgumentError(&validation.FieldError{
+			Field: "product_id",
+			Err:   validation.ErrProductIDIsRequired,
+		})
+	}
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) < 1 {
+		return nil, status.Error(codes.InvalidArgument, ErrUpdateMaskIsRequired.Error())
+	}
+	for _, v := range p.Variants {
+		if v.ID == primitive.NilObjectID {
+			v.ID = primitive.NewObjectID()
+		}
+	}
+
+	// the whole product is validated, the fields the update doesn't set included.
+	existing, err := h.store.GetProduct(ctx, p.ID)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch product from store")
+	}
+	updated, err := applyProductUpdate(existing, p, paths)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	variantTypes, err := h.validateProduct(ctx, updated)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := h.store.UpdateProduct(ctx, &ProductUpdate{
+		Product:     updated,
+		Paths:       paths,
+		Category1ID: existing.Category1ID,
+		Category2ID: existing.Category2ID,
+	})
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to update product on store")
+	}
+	after, err := applyProductUpdate(before, p, paths)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	audit.Track(ctx, audit.EntityProduct, p.ID.Hex(), before, after)
+
+	return &prpb.UpdateResponse{
+		Product: toProductPb(after, variantTypes, i18n.FromContext(ctx)),
 	}, nil
 }
 
-func toProductsPb(products []*product.Product) []*s_prpb.Product {
+// Delete will soft delete a product, deleted products are no longer returned
+// by any of the product service methods.
+func (h *Handler) Delete(ctx context.Context, req *prpb.DeleteRequest) (*prpb.DeleteResponse, error) {
+	id, err := primitive.ObjectIDFromHex(req.GetProductId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidProductID.Error())
+	}
+
+	existing, err := h.store.GetProduct(ctx, id)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch product from store")
+	}
+	if err := h.store.DeleteProduct(ctx, id); err != nil {
+		return nil, h.toStatusError(err, "failed to delete product on store")
+	}
+	audit.Track(ctx, audit.EntityProduct, id.Hex(), existing, nil)
+
+	return &prpb.DeleteResponse{}, nil
+}
+
+// validateProduct applies the product validation rules shared with the importer
+// and checks the product categories and variant types, it returns the variant types
+// of the product variants or a gRPC status error.
+func (h *Handler) validateProduct(ctx context.Context, p *product.Product) (map[primitive.ObjectID]*product.VariantType, error) {
+	if err := validation.ValidateProduct(p); err != nil {
+		return nil, services.InvalidArgumentError(err)
+	}
+	for i, v := range p.Variants {
+		if err := validation.ValidateProductVariant(v); err != nil {
+			return nil, services.InvalidArgumentError(variantFieldError(i, err))
+		}
+	}
+
+	categoryl1, err := h.store.GetCategory(ctx, p.Category1ID)
+	if err != nil {
+		if errors.Is(err, ErrCategoryNotFound) {
+			return nil, services.InvalidArgumentError(&validation.FieldError{Field: "category_1", Err: err})
+		}
+		h.logger.Err(err).Msg("failed to fetch product category from store")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	found := false
+	for _, c := range categoryl1.ChildCategories {
+		if c.ID == p.Category2ID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, services.InvalidArgumentError(&validation.FieldError{
+			Field: "category_2",
+			Err:   ErrCategory2NotChildOfParent,
+		})
+	}
+
+	// check if variant SKU is designated with the correct category.
+	for i, v := range p.Variants {
+		if err := validation.ValidateSKU(v.SKU, categoryl1.Abbreviation); err != nil {
+			return nil, services.InvalidArgumentError(variantFieldError(i, err))
+		}
+	}
+
+	// check if variant quantifiers are allowed by their variant type.
+	variantTypes, err := h.getVariantTypes(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range p.Variants {
+		vt, ok := variantTypes[v.VariantTypeID]
+		if !ok {
+			return nil, services.InvalidArgumentError(variantFieldError(i, &validation.FieldError{
+				Field: "variant_type_id",
+				Err:   ErrVariantTypeNotFound,
+			}))
+		}
+		if err := validation.ValidateVariantQuantifier(vt, v); err != nil {
+			return nil, services.InvalidArgumentError(variantFieldError(i, err))
+		}
+	}
+	return variantTypes, nil
+}
+
+// getVariantTypes fetches the variant types of the products variants indexed by their id,
+// it returns a gRPC status error.
+func (h *Handler) getVariantTypes(ctx context.Context, products ...*product.Product) (map[primitive.ObjectID]*product.VariantType, error) {
+	var ids []primitive.ObjectID
+	seen := map[primitive.ObjectID]bool{}
+	for _, p := range products {
+		for _, v := range p.Variants {
+			if !seen[v.VariantTypeID] {
+				seen[v.VariantTypeID] = true
+				ids = append(ids, v.VariantTypeID)
+			}
+		}
+	}
+
+	variantTypes := make(map[primitive.ObjectID]*product.VariantType, len(ids))
+	if len(ids) < 1 {
+		return variantTypes, nil
+	}
+
+	vts, err := h.store.GetVariantTypes(ctx, ids)
+	if err != nil {
+		h.logger.Err(err).Msg("failed to fetch variant types from store")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	for _, vt := range vts {
+		variantTypes[vt.ID] = vt
+	}
+	return variantTypes, nil
+}
+
+// variantFieldError prefixes the fields of a variant validation error
+// with the variant position, e.g. variants[0].sku.
+func variantFieldError(idx int, err error) error {
+	fields := validation.Fields(err)
+	if len(fields) < 1 {
+		return err
+	}
+	prefixed := make(validation.FieldErrors, 0, len(fields))
+	for _, fe := range fields {
+		prefixed = append(prefixed, &validation.FieldError{
+			Field: fmt.Sprintf("variants[%d].%s", idx, fe.Field),
+			Err:   fe.Err,
+		})
+	}
+	if len(prefixed) == 1 {
+		return prefixed[0]
+	}
+	return prefixed
+}
+
+// toStatusError converts store errors to gRPC status errors,
+// unexpected errors are logged with msg.
+func (h *Handler) toStatusError(err error, msg string) error {
+	switch {
+	case errors.Is(err, ErrProductNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrProductChanged):
+		return status.Error(codes.Aborted, err.Error())
+	}
+	h.logger.Err(err).Msg(msg)
+	return status.Error(codes.Internal, err.Error())
+}
+
+// applyProductUpdate returns a copy of p with the fields of the update mask paths set
+// from update, it returns ErrInvalidUpdateMaskPath for paths which can't be updated.
+func applyProductUpdate(p, update *product.Product, paths []string) (*product.Product, error) {
+	updated := *p
+	for _, path := range paths {
+		switch path {
+		case "name_en":
+			updated.Name_EN = update.Name_EN
+		case "name_id":
+			updated.Name_ID = update.Name_ID
+		case "description_en":
+			updated.Description_EN = update.Description_EN
+		case "description_id":
+			updated.Description_ID = update.Description_ID
+		case "brand_id":
+			updated.BrandID = update.BrandID
+		case "category_1":
+			updated.Category1ID = update.Category1ID
+		case "category_2":
+			updated.Category2ID = update.Category2ID
+		case "images_urls":
+			updated.ImagesURLs = update.ImagesURLs
+		case "variants":
+			updated.Variants = update.Variants
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrInvalidUpdateMaskPath, path)
+		}
+	}
+	return &updated, nil
+}
+
+// toListFilter converts get request parameters to product store list filter.
+func toListFilter(req *prpb.GetRequest) (*ListFilter, error) {
+	after, err := pagination.DecodeToken(req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &ListFilter{
+		NamePrefix: req.GetNamePrefix(),
+		After:      after,
+		Limit:      pagination.PageSize(req.GetPageSize()),
+	}
+	if id := req.GetCategory1Id(); id != "" {
+		if filter.Category1ID, err = primitive.ObjectIDFromHex(id); err != nil {
+			return nil, ErrInvalidCategory1ID
+		}
+	}
+	if id := req.GetCategory2Id(); id != "" {
+		if filter.Category2ID, err = primitive.ObjectIDFromHex(id); err != nil {
+			return nil, ErrInvalidCategory2ID
+		}
+	}
+	if id := req.GetBrandId(); id != "" {
+		if filter.BrandID, err = primitive.ObjectIDFromHex(id); err != nil {
+			return nil, ErrInvalidBrandID
+		}
+	}
+	return filter, nil
+}
+
+func toProductsPb(products []*product.Product, variantTypes map[primitive.ObjectID]*product.VariantType, loc *i18n.Localizer) []*s_prpb.Product {
 	var productsPb []*s_prpb.Product
 	for _, product := range products {
-		productsPb = append(productsPb, &s_prpb.Product{
-			ProductId:  product.ID.Hex(),
-			Name:       product.Name_ID,
-			ImagesUrls: product.ImagesURLs,
-			Category_1: &s_ctpb.Category{
-				CategoryId: product.Category1ID.Hex(),
-			},
-			Category_2: &s_ctpb.Category{
-				CategoryId: product.Category2ID.Hex(),
-			},
-		})
+		productsPb = append(productsPb, toProductPb(product, variantTypes, loc))
 	}
 	return productsPb
 }
+
+// toProductPb converts a product to protobuf message, name and description are
+// localized with loc while the texts of every language are always set for editing.
+// Variants are returned with the name and label of their variant type on variantTypes.
+func toProductPb(p *product.Product, variantTypes map[primitive.ObjectID]*product.VariantType, loc *i18n.Localizer) *s_prpb.Product {
+	pb := &s_prpb.Product{
+		ProductId:     p.ID.Hex(),
+		Name:          loc.String(i18n.Text{ID: p.Name_ID, EN: p.Name_EN}),
+		NameEn:        p.Name_EN,
+		NameId:        p.Name_ID,
+		Description:   loc.String(i18n.Text{ID: p.Description_ID, EN: p.Description_EN}),
+		DescriptionEn: p.Description_EN,
+		DescriptionId: p.Description_ID,
+		BrandId:       p.BrandID.Hex(),
+		ImagesUrls:    p.ImagesURLs,
+		Category_1: &s_ctpb.Category{
+			CategoryId: p.Category1ID.Hex(),
+		},
+		Category_2: &s_ctpb.Category{
+			CategoryId: p.Category2ID.Hex(),
+		},
+		Variants: toProductVariantsPb(p.Variants, variantTypes, loc),
+	}
+	return pb
+}
+
+func toProductVariantsPb(variants []*product.ProductVariant, variantTypes map[primitive.ObjectID]*product.VariantType, loc *i18n.Localizer) []*s_prpb.ProductVariant {
+	var variantsPb []*s_prpb.ProductVariant
+	for _, v := range variants {
+		vpb := &s_prpb.ProductVariant{
+			VariantId:           v.ID.Hex(),
+			ShoptreeVariantId:   v.ShoptreeVariantID,
+			ImagesUrls:          v.ImagesURLs,
+			VariantTypeId:       v.VariantTypeID.Hex(),
+			VariantValue:        v.VariantValue,
+			VariantQuantifier:   loc.String(i18n.Text{ID: v.VariantQuantifier_ID, EN: v.VariantQuantifier_EN}),
+			VariantQuantifierEn: v.VariantQuantifier_EN,
+			VariantQuantifierId: v.VariantQuantifier_ID,
+			MaximumOrder:        v.MaximumOrder,
+			Sku:                 v.SKU,
+			Barcode:             v.Barcode,
+			VariantStatus:       v.VariantStatus,
+		}
+		if vt, ok := variantTypes[v.VariantTypeID]; ok {
+			vpb.VariantTypeName = vt.Name
+			vpb.VariantTypeLabel = loc.String(i18n.Text{ID: vt.Label_ID, EN: vt.Label_EN})
+		}
+		variantsPb = append(variantsPb, vpb)
+	}
+	return variantsPb
+}
+
+// fromProductPb converts a product protobuf message to product model,
+// invalid ids are returned as validation field errors.
+func fromProductPb(pb *s_prpb.Product) (*product.Product, error) {
+	if pb == nil {
+		return nil, ErrProductIsRequired
+	}
+
+	p := &product.Product{
+		Name_EN:        pb.GetNameEn(),
+		Name_ID:        pb.GetNameId(),
+		Description_EN: pb.GetDescriptionEn(),
+		Description_ID: pb.GetDescriptionId(),
+		ImagesURLs:     pb.GetImagesUrls(),
+	}
+
+	var err error
+	ids := []struct {
+		field string
+		hex   string
+		dst   *primitive.ObjectID
+		err   error
+	}{
+		{"product_id", pb.GetProductId(), &p.ID, ErrInvalidProductID},
+		{"brand_id", pb.GetBrandId(), &p.BrandID, ErrInvalidBrandID},
+		{"category_1", pb.GetCategory_1().GetCategoryId(), &p.Category1ID, ErrInvalidCategory1ID},
+		{"category_2", pb.GetCategory_2().GetCategoryId(), &p.Category2ID, ErrInvalidCategory2ID},
+	}
+	for _, id := range ids {
+		if id.hex == "" {
+			continue
+		}
+		if *id.dst, err = primitive.ObjectIDFromHex(id.hex); err != nil {
+			return nil, &validation.FieldError{Field: id.field, Err: id.err}
+		}
+	}
+
+	for i, vpb := range pb.GetVariants() {
+		v := &product.ProductVariant{
+			ShoptreeVariantID:    vpb.GetShoptreeVariantId(),
+			ImagesURLs:           vpb.GetImagesUrls(),
+			VariantValue:         vpb.GetVariantValue(),
+			VariantQuantifier_EN: vpb.GetVariantQuantifierEn(),
+			VariantQuantifier_ID: vpb.GetVariantQuantifierId(),
+			MaximumOrder:         vpb.GetMaximumOrder(),
+			SKU:                  vpb.GetSku(),
+			Barcode:              vpb.GetBarcode(),
+			VariantStatus:        vpb.GetVariantStatus(),
+		}
+		if id := vpb.GetVariantId(); id != "" {
+			if v.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+				return nil, variantFieldError(i, &validation.FieldError{Field: "variant_id", Err: ErrInvalidVariantID})
+			}
+		}
+		if id := vpb.GetVariantTypeId(); id != "" {
+			if v.VariantTypeID, err = primitive.ObjectIDFromHex(id); err != nil {
+				return nil, variantFieldError(i, &validation.FieldError{Field: "variant_type_id", Err: ErrInvalidVariantTypeID})
+			}
+		}
+		p.Variants = append(p.Variants, v)
+	}
+	return p, nil
+}
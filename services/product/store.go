@@ -0,0 +1,294 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/category"
+	"github.com/dropezy/storefront-backend/internal/storage/model/product"
+)
+
+const (
+	productCollection     = "product"
+	categoryCollection    = "category"
+	variantTypeCollection = "variant_type"
+)
+
+// nameCollation compares product names regardless of their case, the name indexes
+// use it so name prefix filters can be answered from them.
+var nameCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// Store is the storage contract required by the product service.
+type Store interface {
+	ListProducts(ctx context.Context, filter *ListFilter) ([]*product.Product, error)
+	GetProduct(ctx context.Context, id primitive.ObjectID) (*product.Product, error)
+	GetProductBySKU(ctx context.Context, sku string) (*product.Product, error)
+	GetProductByBarcode(ctx context.Context, barcode string) (*product.Product, error)
+	GetProductByShoptreeVariantID(ctx context.Context, shoptreeVariantID string) (*product.Product, error)
+	CreateProduct(ctx context.Context, p *product.Product) error
+	UpdateProduct(ctx context.Context, update *ProductUpdate) (*product.Product, error)
+	DeleteProduct(ctx context.Context, id primitive.ObjectID) error
+
+	GetCategory(ctx context.Context, id primitive.ObjectID) (*category.Category, error)
+	GetVariantTypes(ctx context.Context, ids []primitive.ObjectID) ([]*product.VariantType, error)
+}
+
+// ListFilter holds the parameters to filter and paginate products.
+type ListFilter struct {
+	Category1ID primitive.ObjectID
+	Category2ID primitive.ObjectID
+	BrandID     primitive.ObjectID
+	NamePrefix  string
+
+	// After is the id of the last product on the previous page.
+	After primitive.ObjectID
+	Limit int64
+}
+
+// ProductUpdate holds the product fields to update.
+type ProductUpdate struct {
+	// Product holds the updated product, only the fields of Paths are stored.
+	Product *product.Product
+	// Paths are the update mask paths of the fields to update.
+	Paths []string
+
+	// Category1ID and Category2ID are the stored categories the update was validated
+	// with, the update is rejected with ErrProductChanged when they changed since.
+	Category1ID primitive.ObjectID
+	Category2ID primitive.ObjectID
+}
+
+// productUpdateFields maps the product update mask paths to their product document fields.
+var productUpdateFields = map[string]string{
+	"name_en":        "name_en",
+	"name_id":        "name_id",
+	"description_en": "description_en",
+	"description_id": "description_id",
+	"brand_id":       "brand_id",
+	"category_1":     "category1_id",
+	"category_2":     "category2_id",
+	"images_urls":    "images_urls",
+	"variants":       "variants",
+}
+
+// MongoStore implements Store backed by mongo database.
+type MongoStore struct {
+	db *mongo.Database
+}
+
+// NewMongoStore returns a new product store backed by db.
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{db: db}
+}
+
+// CreateIndexes creates the indexes of the product collection, names are indexed
+// with the name collation for the name prefix filter of ListProducts and the
+// variant identifiers for the product lookups.
+func (s *MongoStore) CreateIndexes(ctx context.Context) error {
+	if _, err := s.db.Collection(productCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "name_en", Value: 1}},
+			Options: options.Index().SetCollation(nameCollation),
+		},
+		{
+			Keys:    bson.D{{Key: "name_id", Value: 1}},
+			Options: options.Index().SetCollation(nameCollation),
+		},
+		{Keys: bson.D{{Key: "variants.sku_structured", Value: 1}}},
+		{Keys: bson.D{{Key: "variants.barcode", Value: 1}}},
+		{Keys: bson.D{{Key: "variants.shoptree_variant_id", Value: 1}}},
+	}); err != nil {
+		return fmt.Errorf("failed to create product indexes: %w", err)
+	}
+	return nil
+}
+
+// ListProducts fetches products matching the filter ordered by their id.
+func (s *MongoStore) ListProducts(ctx context.Context, filter *ListFilter) ([]*product.Product, error) {
+	query := bson.M{"deleted_at": nil}
+	if filter.After != primitive.NilObjectID {
+		query["_id"] = bson.M{"$gt": filter.After}
+	}
+	if filter.Category1ID != primitive.NilObjectID {
+		query["category1_id"] = filter.Category1ID
+	}
+	if filter.Category2ID != primitive.NilObjectID {
+		query["category2_id"] = filter.Category2ID
+	}
+	if filter.BrandID != primitive.NilObjectID {
+		query["brand_id"] = filter.BrandID
+	}
+	if filter.NamePrefix != "" {
+		// under the name collation the names starting with the prefix sort between
+		// the prefix and the prefix followed by U+FFFF, which has the greatest weight.
+		prefix := bson.M{"$gte": filter.NamePrefix, "$lt": filter.NamePrefix + "\uffff"}
+		query["$or"] = bson.A{
+			bson.M{"name_en": prefix},
+			bson.M{"name_id": prefix},
+		}
+	}
+
+	opts := options.Find().
+		SetCollation(nameCollation).
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(filter.Limit)
+
+	cur, err := s.db.Collection(productCollection).Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Find products: %w", err)
+	}
+
+	var products []*product.Product
+	if err := cur.All(ctx, &products); err != nil {
+		return nil, fmt.Errorf("failed to decode products: %w", err)
+	}
+	return products, nil
+}
+
+// GetProduct fetches a product by its id.
+func (s *MongoStore) GetProduct(ctx context.Context, id primitive.ObjectID) (*product.Product, error) {
+	return s.findProduct(ctx, bson.M{"_id": id})
+}
+
+// GetProductBySKU fetches the product owning the variant with the given structured sku.
+func (s *MongoStore) GetProductBySKU(ctx context.Context, sku string) (*product.Product, error) {
+	return s.findProduct(ctx, bson.M{"variants.sku_structured": sku})
+}
+
+// GetProductByBarcode fetches the product owning the variant with the given barcode.
+func (s *MongoStore) GetProductByBarcode(ctx context.Context, barcode string) (*product.Product, error) {
+	return s.findProduct(ctx, bson.M{"variants.barcode": barcode})
+}
+
+// GetProductByShoptreeVariantID fetches the product owning the variant
+// with the given shoptree variant id.
+func (s *MongoStore) GetProductByShoptreeVariantID(ctx context.Context, shoptreeVariantID string) (*product.Product, error) {
+	return s.findProduct(ctx, bson.M{"variants.shoptree_variant_id": shoptreeVariantID})
+}
+
+// findProduct fetches the first product matching the query which has not been deleted,
+// it returns ErrProductNotFound when there is no matching product.
+func (s *MongoStore) findProduct(ctx context.Context, query bson.M) (*product.Product, error) {
+	query["deleted_at"] = nil
+
+	p := &product.Product{}
+	if err := s.db.Collection(productCollection).FindOne(ctx, query).Decode(p); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrProductNotFound
+		}
+		return nil, fmt.Errorf("failed to execute FindOne product: %w", err)
+	}
+	return p, nil
+}
+
+// CreateProduct inserts a new product.
+func (s *MongoStore) CreateProduct(ctx context.Context, p *product.Product) error {
+	if _, err := s.db.Collection(productCollection).InsertOne(ctx, p); err != nil {
+		return fmt.Errorf("failed to execute InsertOne product: %w", err)
+	}
+	return nil
+}
+
+// UpdateProduct sets the fields of the update paths on an existing product and returns
+// the product as it was before the update, other stored fields are left untouched.
+// It returns ErrProductNotFound when the product doesn't exist or has been deleted.
+func (s *MongoStore) UpdateProduct(ctx context.Context, update *ProductUpdate) (*product.Product, error) {
+	doc, err := toBSONDocument(update.Product)
+	if err != nil {
+		return nil, err
+	}
+	set := bson.M{}
+	for _, path := range update.Paths {
+		field, ok := productUpdateFields[path]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidUpdateMaskPath, path)
+		}
+		set[field] = doc[field]
+	}
+
+	before := &product.Product{}
+	err = s.db.Collection(productCollection).FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":          update.Product.ID,
+			"deleted_at":   nil,
+			"category1_id": update.Category1ID,
+			"category2_id": update.Category2ID,
+		},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(before)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// tell a missing product from one whose categories changed.
+		if _, err := s.GetProduct(ctx, update.Product.ID); err != nil {
+			return nil, err
+		}
+		return nil, ErrProductChanged
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute FindOneAndUpdate product: %w", err)
+	}
+	return before, nil
+}
+
+// DeleteProduct soft deletes a product by marking it with its deletion time.
+func (s *MongoStore) DeleteProduct(ctx context.Context, id primitive.ObjectID) error {
+	res, err := s.db.Collection(productCollection).UpdateOne(ctx,
+		bson.M{"_id": id, "deleted_at": nil},
+		bson.M{"$set": bson.M{"deleted_at": time.Now().UTC()}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute UpdateOne product: %w", err)
+	}
+	if res.MatchedCount < 1 {
+		return ErrProductNotFound
+	}
+	return nil
+}
+
+// GetCategory fetches a level 1 category along with its child categories.
+func (s *MongoStore) GetCategory(ctx context.Context, id primitive.ObjectID) (*category.Category, error) {
+	c := &category.Category{}
+	if err := s.db.Collection(categoryCollection).FindOne(ctx, bson.M{"_id": id}).Decode(c); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, fmt.Errorf("failed to execute FindOne category: %w", err)
+	}
+	return c, nil
+}
+
+// GetVariantTypes fetches the variant types with the given ids,
+// unknown ids are ignored.
+func (s *MongoStore) GetVariantTypes(ctx context.Context, ids []primitive.ObjectID) ([]*product.VariantType, error) {
+	cur, err := s.db.Collection(variantTypeCollection).Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Find variant types: %w", err)
+	}
+
+	var variantTypes []*product.VariantType
+	if err := cur.All(ctx, &variantTypes); err != nil {
+		return nil, fmt.Errorf("failed to decode variant types: %w", err)
+	}
+	return variantTypes, nil
+}
+
+// toBSONDocument converts v to a bson document which can be used on update operations.
+func toBSONDocument(v interface{}) (bson.M, error) {
+	b, err := bson.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal document: %w", err)
+	}
+
+	doc := bson.M{}
+	if err := bson.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal document: %w", err)
+	}
+	return doc, nil
+}
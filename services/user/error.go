@@ -0,0 +1,10 @@
+package user
+
+import "errors"
+
+var (
+	ErrInvalidUserID         = errors.New("invalid user id")
+	ErrUserIsRequired        = errors.New("user is required")
+	ErrUserNotFound          = errors.New("user not found")
+	ErrUsernameAlreadyExists = errors.New("username already exists")
+)
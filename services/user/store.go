@@ -0,0 +1,136 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/dropezy/storefront-backend/ems-api/services/auth"
+)
+
+const userCollection = "user"
+
+// Store is the storage contract required by the user service.
+type Store interface {
+	ListUsers(ctx context.Context) ([]*auth.User, error)
+	GetUser(ctx context.Context, id primitive.ObjectID) (*auth.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*auth.User, error)
+	UsernameExists(ctx context.Context, username string, excludeID primitive.ObjectID) (bool, error)
+	UsersExist(ctx context.Context) (bool, error)
+	CreateUser(ctx context.Context, u *auth.User) error
+	UpdateUser(ctx context.Context, u *auth.User) error
+}
+
+// MongoStore implements Store backed by mongo database.
+type MongoStore struct {
+	db *mongo.Database
+}
+
+// NewMongoStore returns a new user store backed by db.
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{db: db}
+}
+
+// CreateIndexes creates the indexes of the user collection, usernames are unique.
+func (s *MongoStore) CreateIndexes(ctx context.Context) error {
+	if _, err := s.db.Collection(userCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "username", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return fmt.Errorf("failed to create user username index: %w", err)
+	}
+	return nil
+}
+
+// ListUsers fetches all users ordered by their username.
+func (s *MongoStore) ListUsers(ctx context.Context) ([]*auth.User, error) {
+	cur, err := s.db.Collection(userCollection).Find(ctx, bson.M{},
+		options.Find().SetSort(bson.D{{Key: "username", Value: 1}}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Find users: %w", err)
+	}
+
+	var users []*auth.User
+	if err := cur.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %w", err)
+	}
+	return users, nil
+}
+
+// GetUser fetches a user by its id.
+func (s *MongoStore) GetUser(ctx context.Context, id primitive.ObjectID) (*auth.User, error) {
+	return s.findUser(ctx, bson.M{"_id": id})
+}
+
+// GetUserByUsername fetches a user by its username.
+func (s *MongoStore) GetUserByUsername(ctx context.Context, username string) (*auth.User, error) {
+	return s.findUser(ctx, bson.M{"username": username})
+}
+
+func (s *MongoStore) findUser(ctx context.Context, filter bson.M) (*auth.User, error) {
+	u := &auth.User{}
+	if err := s.db.Collection(userCollection).FindOne(ctx, filter).Decode(u); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to execute FindOne user: %w", err)
+	}
+	return u, nil
+}
+
+// UsernameExists checks whether a user other than excludeID already uses the username.
+func (s *MongoStore) UsernameExists(ctx context.Context, username string, excludeID primitive.ObjectID) (bool, error) {
+	n, err := s.db.Collection(userCollection).CountDocuments(ctx, bson.M{
+		"_id":      bson.M{"$ne": excludeID},
+		"username": username,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to execute CountDocuments user: %w", err)
+	}
+	return n > 0, nil
+}
+
+// UsersExist checks whether any user has been created, disabled users included.
+func (s *MongoStore) UsersExist(ctx context.Context) (bool, error) {
+	n, err := s.db.Collection(userCollection).CountDocuments(ctx, bson.M{},
+		options.Count().SetLimit(1),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute CountDocuments user: %w", err)
+	}
+	return n > 0, nil
+}
+
+// CreateUser inserts a new user,
+// it returns ErrUsernameAlreadyExists when the username is already used.
+func (s *MongoStore) CreateUser(ctx context.Context, u *auth.User) error {
+	if _, err := s.db.Collection(userCollection).InsertOne(ctx, u); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrUsernameAlreadyExists
+		}
+		return fmt.Errorf("failed to execute InsertOne user: %w", err)
+	}
+	return nil
+}
+
+// UpdateUser replaces an existing user,
+// it returns ErrUsernameAlreadyExists when the username is already used.
+func (s *MongoStore) UpdateUser(ctx context.Context, u *auth.User) error {
+	res, err := s.db.Collection(userCollection).ReplaceOne(ctx, bson.M{"_id": u.ID}, u)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrUsernameAlreadyExists
+		}
+		return fmt.Errorf("failed to execute ReplaceOne user: %w", err)
+	}
+	if res.MatchedCount < 1 {
+		return ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/dropezy/storefront-backend/ems-api/services/auth"
+)
+
+// newTestDatabase returns a new database on the MONGO_CONNECTION server,
+// dropped once the test is done, the test is skipped when it is not set.
+func newTestDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+
+	const dbConnEnv = "MONGO_CONNECTION"
+	dbstring := os.Getenv(dbConnEnv)
+	if dbstring == "" {
+		t.Skipf("%s is not set, skipping", dbConnEnv)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(dbstring))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := client.Database(uuid.New().String())
+	t.Cleanup(func() {
+		if err := db.Drop(context.Background()); err != nil {
+			t.Error(err)
+		}
+		if err := client.Disconnect(context.Background()); err != nil {
+			t.Error(err)
+		}
+	})
+	return db
+}
+
+func TestMongoStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewMongoStore(newTestDatabase(t))
+	if err := store.CreateIndexes(ctx); err != nil {
+		t.Fatalf("CreateIndexes(_) error, got = %v", err)
+	}
+
+	exists, err := store.UsersExist(ctx)
+	if err != nil || exists {
+		t.Fatalf("UsersExist(_) on empty store, got = %v, %v, want = false", exists, err)
+	}
+
+	u := &auth.User{
+		ID:           primitive.NewObjectID(),
+		Username:     "ops.jakarta",
+		PasswordHash: "hash",
+		Role:         auth.RoleInventoryOperator,
+		Disabled:     true,
+	}
+	if err := store.CreateUser(ctx, u); err != nil {
+		t.Fatalf("CreateUser(_, _) error, got = %v", err)
+	}
+
+	other := &auth.User{ID: primitive.NewObjectID(), Username: u.Username, PasswordHash: "hash", Role: auth.RoleViewer}
+	if err := store.CreateUser(ctx, other); !errors.Is(err, ErrUsernameAlreadyExists) {
+		t.Fatalf("CreateUser(_, duplicate) error, got = %v, want = %v", err, ErrUsernameAlreadyExists)
+	}
+
+	// disabled users still close the bootstrap credentials.
+	exists, err = store.UsersExist(ctx)
+	if err != nil || !exists {
+		t.Fatalf("UsersExist(_), got = %v, %v, want = true", exists, err)
+	}
+
+	got, err := store.GetUserByUsername(ctx, u.Username)
+	if err != nil {
+		t.Fatalf("GetUserByUsername(_, _) error, got = %v", err)
+	}
+	if got.ID != u.ID || got.PasswordHash != u.PasswordHash || got.Role != u.Role {
+		t.Fatalf("GetUserByUsername(_, _), got = %+v, want = %+v", got, u)
+	}
+	if _, err := store.GetUserByUsername(ctx, "unknown"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("GetUserByUsername(_, unknown) error, got = %v, want = %v", err, ErrUserNotFound)
+	}
+
+	if exists, err := store.UsernameExists(ctx, u.Username, u.ID); err != nil || exists {
+		t.Fatalf("UsernameExists(_, _, self), got = %v, %v, want = false", exists, err)
+	}
+	if exists, err := store.UsernameExists(ctx, u.Username, primitive.NewObjectID()); err != nil || !exists {
+		t.Fatalf("UsernameExists(_, _, other), got = %v, %v, want = true", exists, err)
+	}
+
+	u.Role = auth.RoleAdmin
+	if err := store.UpdateUser(ctx, u); err != nil {
+		t.Fatalf("UpdateUser(_, _) error, got = %v", err)
+	}
+	if got, err := store.GetUser(ctx, u.ID); err != nil || got.Role != auth.RoleAdmin {
+		t.Fatalf("GetUser(_, _), got = %+v, %v, want role = %s", got, err, auth.RoleAdmin)
+	}
+	if err := store.UpdateUser(ctx, &auth.User{ID: primitive.NewObjectID()}); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("UpdateUser(_, unknown) error, got = %v, want = %v", err, ErrUserNotFound)
+	}
+
+	users, err := store.ListUsers(ctx)
+	if err != nil || len(users) != 1 {
+		t.Fatalf("ListUsers(_), got = %d users, %v, want = 1", len(users), err)
+	}
+}
@@ -0,0 +1,205 @@
+// Package user implements user gRPC service methods
+// to manage the ems users and their roles.
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dropezy/storefront-backend/ems-api/services"
+	"github.com/dropezy/storefront-backend/ems-api/services/audit"
+	"github.com/dropezy/storefront-backend/ems-api/services/auth"
+	"github.com/dropezy/storefront-backend/ems-api/validation"
+
+	// protobuf
+	uspb "github.com/dropezy/proto/ems/v1/user"
+)
+
+const serviceName = "user"
+
+// Handler holds user gRPC service implementation.
+type Handler struct {
+	// utilities
+	logger zerolog.Logger
+
+	// service dependencies
+	store Store
+}
+
+// NewHandler returns a new user service handler.
+func NewHandler(logger zerolog.Logger, store Store) *Handler {
+	return &Handler{
+		logger: logger.With().Str("service", serviceName).Logger(),
+		store:  store,
+	}
+}
+
+// RegisterService registers the user service to the gRPC server.
+func RegisterService(logger zerolog.Logger, store Store) func(srv *grpc.Server) error {
+	return func(srv *grpc.Server) error {
+		h := NewHandler(logger, store)
+		uspb.RegisterUserServiceServer(srv, h)
+		return nil
+	}
+}
+
+func RegisterGateway(ctx context.Context, mux *runtime.ServeMux, addr string, opts []grpc.DialOption) error {
+	return uspb.RegisterUserServiceHandlerFromEndpoint(ctx, mux, addr, opts)
+}
+
+// List will fetch users from storage, password hashes are never returned.
+func (h *Handler) List(ctx context.Context, req *uspb.ListRequest) (*uspb.ListResponse, error) {
+	users, err := h.store.ListUsers(ctx)
+	if err != nil {
+		h.logger.Err(err).Msg("failed to fetch users from store")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	var usersPb []*uspb.User
+	for _, u := range users {
+		usersPb = append(usersPb, toUserPb(u))
+	}
+
+	return &uspb.ListResponse{
+		Users: usersPb,
+	}, nil
+}
+
+// Create will validate and insert a new user with its hashed password.
+func (h *Handler) Create(ctx context.Context, req *uspb.CreateRequest) (*uspb.CreateResponse, error) {
+	if req.GetUser() == nil {
+		return nil, status.Error(codes.InvalidArgument, ErrUserIsRequired.Error())
+	}
+
+	now := time.Now().UTC()
+	u := fromUserPb(req.GetUser())
+	u.ID = primitive.NewObjectID()
+	u.CreatedAt = now
+	u.UpdatedAt = now
+	if err := h.validateUser(ctx, u); err != nil {
+		return nil, err
+	}
+	if err := h.setPassword(u, req.GetPassword()); err != nil {
+		return nil, err
+	}
+
+	if err := h.store.CreateUser(ctx, u); err != nil {
+		return nil, h.toStatusError(err, "failed to create user on store")
+	}
+	audit.Track(ctx, audit.EntityUser, u.ID.Hex(), nil, u)
+
+	return &uspb.CreateResponse{
+		User: toUserPb(u),
+	}, nil
+}
+
+// Update will validate and replace an existing user,
+// the password is kept when the request password is empty.
+func (h *Handler) Update(ctx context.Context, req *uspb.UpdateRequest) (*uspb.UpdateResponse, error) {
+	if req.GetUser() == nil {
+		return nil, status.Error(codes.InvalidArgument, ErrUserIsRequired.Error())
+	}
+	id, err := primitive.ObjectIDFromHex(req.GetUser().GetUserId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidUserID.Error())
+	}
+
+	existing, err := h.store.GetUser(ctx, id)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch user from store")
+	}
+
+	u := fromUserPb(req.GetUser())
+	u.ID = id
+	u.PasswordHash = existing.PasswordHash
+	u.CreatedAt = existing.CreatedAt
+	u.UpdatedAt = time.Now().UTC()
+	if err := h.validateUser(ctx, u); err != nil {
+		return nil, err
+	}
+	if req.GetPassword() != "" {
+		if err := h.setPassword(u, req.GetPassword()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := h.store.UpdateUser(ctx, u); err != nil {
+		return nil, h.toStatusError(err, "failed to update user on store")
+	}
+	audit.Track(ctx, audit.EntityUser, u.ID.Hex(), existing, u)
+
+	return &uspb.UpdateResponse{
+		User: toUserPb(u),
+	}, nil
+}
+
+// validateUser checks the user parameters and makes sure
+// the username is unique, it returns a gRPC status error.
+func (h *Handler) validateUser(ctx context.Context, u *auth.User) error {
+	if err := validation.ValidateUser(u); err != nil {
+		return services.InvalidArgumentError(err)
+	}
+
+	exists, err := h.store.UsernameExists(ctx, u.Username, u.ID)
+	if err != nil {
+		h.logger.Err(err).Msg("failed to check username on store")
+		return status.Error(codes.Internal, err.Error())
+	}
+	if exists {
+		return status.Error(codes.AlreadyExists, ErrUsernameAlreadyExists.Error())
+	}
+	return nil
+}
+
+// setPassword validates password and sets its hash on u, it returns a gRPC status error.
+func (h *Handler) setPassword(u *auth.User, password string) error {
+	if err := validation.ValidatePassword(password); err != nil {
+		return services.InvalidArgumentError(err)
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		h.logger.Err(err).Msg("failed to hash user password")
+		return status.Error(codes.Internal, err.Error())
+	}
+	u.PasswordHash = hash
+	return nil
+}
+
+// toStatusError converts store errors to gRPC status errors,
+// unexpected errors are logged with msg.
+func (h *Handler) toStatusError(err error, msg string) error {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrUsernameAlreadyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	h.logger.Err(err).Msg(msg)
+	return status.Error(codes.Internal, err.Error())
+}
+
+func toUserPb(u *auth.User) *uspb.User {
+	return &uspb.User{
+		UserId:   u.ID.Hex(),
+		Username: u.Username,
+		Role:     string(u.Role),
+		Disabled: u.Disabled,
+	}
+}
+
+func fromUserPb(pb *uspb.User) *auth.User {
+	return &auth.User{
+		Username: pb.GetUsername(),
+		Role:     auth.Role(pb.GetRole()),
+		Disabled: pb.GetDisabled(),
+	}
+}
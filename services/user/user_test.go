@@ -0,0 +1,301 @@
+package user
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dropezy/storefront-backend/ems-api/services/auth"
+
+	// protobuf
+	uspb "github.com/dropezy/proto/ems/v1/user"
+)
+
+// memoryStore is an in memory Store of the handler tests.
+type memoryStore struct {
+	mu    sync.Mutex
+	users map[primitive.ObjectID]*auth.User
+}
+
+func newMemoryStore(users ...*auth.User) *memoryStore {
+	s := &memoryStore{users: map[primitive.ObjectID]*auth.User{}}
+	for _, u := range users {
+		s.users[u.ID] = u
+	}
+	return s
+}
+
+func (s *memoryStore) ListUsers(ctx context.Context) ([]*auth.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var users []*auth.User
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+func (s *memoryStore) GetUser(ctx context.Context, id primitive.ObjectID) (*auth.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (s *memoryStore) GetUserByUsername(ctx context.Context, username string) (*auth.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (s *memoryStore) UsernameExists(ctx context.Context, username string, excludeID primitive.ObjectID) (bool, error) {
+	u, err := s.GetUserByUsername(ctx, username)
+	if err != nil {
+		return false, nil
+	}
+	return u.ID != excludeID, nil
+}
+
+func (s *memoryStore) UsersExist(ctx context.Context) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.users) > 0, nil
+}
+
+func (s *memoryStore) CreateUser(ctx context.Context, u *auth.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[u.ID] = u
+	return nil
+}
+
+func (s *memoryStore) UpdateUser(ctx context.Context, u *auth.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[u.ID]; !ok {
+		return ErrUserNotFound
+	}
+	s.users[u.ID] = u
+	return nil
+}
+
+const testPassword = "correct-horse-battery"
+
+func newTestUser(t *testing.T, username string, role auth.Role) *auth.User {
+	t.Helper()
+
+	hash, err := auth.HashPassword(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &auth.User{ID: primitive.NewObjectID(), Username: username, Role: role, PasswordHash: hash}
+}
+
+func TestCreate(t *testing.T) {
+	t.Parallel()
+
+	existing := newTestUser(t, "ops.jakarta", auth.RoleInventoryOperator)
+
+	tests := []struct {
+		name     string
+		req      *uspb.CreateRequest
+		wantCode codes.Code
+	}{
+		{
+			name: "Valid",
+			req: &uspb.CreateRequest{
+				User:     &uspb.User{Username: "merch.team", Role: string(auth.RoleMerchandiser)},
+				Password: testPassword,
+			},
+		},
+		{
+			name:     "WithoutUser",
+			req:      &uspb.CreateRequest{Password: testPassword},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "InvalidUsername",
+			req: &uspb.CreateRequest{
+				User:     &uspb.User{Username: "Merch Team", Role: string(auth.RoleMerchandiser)},
+				Password: testPassword,
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "InvalidRole",
+			req: &uspb.CreateRequest{
+				User:     &uspb.User{Username: "merch.team", Role: "root"},
+				Password: testPassword,
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "ShortPassword",
+			req: &uspb.CreateRequest{
+				User:     &uspb.User{Username: "merch.team", Role: string(auth.RoleMerchandiser)},
+				Password: "short",
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "UsernameExists",
+			req: &uspb.CreateRequest{
+				User:     &uspb.User{Username: existing.Username, Role: string(auth.RoleViewer)},
+				Password: testPassword,
+			},
+			wantCode: codes.AlreadyExists,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := newMemoryStore(existing)
+			h := NewHandler(zerolog.Nop(), store)
+
+			res, err := h.Create(context.Background(), test.req)
+			if got := status.Code(err); got != test.wantCode {
+				t.Fatalf("Create(_, _) code, got = %v, want = %v, error = %v", got, test.wantCode, err)
+			}
+			if test.wantCode != codes.OK {
+				return
+			}
+
+			u, err := store.GetUserByUsername(context.Background(), test.req.GetUser().GetUsername())
+			if err != nil {
+				t.Fatalf("expected user to be created, got = %v", err)
+			}
+			if res.User.UserId != u.ID.Hex() {
+				t.Fatalf("Create(_, _) user id, got = %s, want = %s", res.User.UserId, u.ID.Hex())
+			}
+			if u.PasswordHash == test.req.GetPassword() || !auth.CheckPassword(u.PasswordHash, test.req.GetPassword()) {
+				t.Fatalf("expected password to be stored hashed, got = %s", u.PasswordHash)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		id           string
+		user         *uspb.User
+		password     string
+		wantCode     codes.Code
+		wantPassword string
+	}{
+		{
+			name:         "KeepPassword",
+			user:         &uspb.User{Username: "ops.jakarta", Role: string(auth.RoleAdmin)},
+			wantPassword: testPassword,
+		},
+		{
+			name:         "ChangePassword",
+			user:         &uspb.User{Username: "ops.jakarta", Role: string(auth.RoleViewer), Disabled: true},
+			password:     "another-long-password",
+			wantPassword: "another-long-password",
+		},
+		{
+			name:     "ShortPassword",
+			user:     &uspb.User{Username: "ops.jakarta", Role: string(auth.RoleViewer)},
+			password: "short",
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "InvalidRole",
+			user:     &uspb.User{Username: "ops.jakarta", Role: "root"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "UsernameExists",
+			user:     &uspb.User{Username: "merch.team", Role: string(auth.RoleViewer)},
+			wantCode: codes.AlreadyExists,
+		},
+		{
+			name:     "InvalidID",
+			id:       "42",
+			user:     &uspb.User{Username: "ops.jakarta", Role: string(auth.RoleViewer)},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "NotFound",
+			id:       primitive.NewObjectID().Hex(),
+			user:     &uspb.User{Username: "ops.jakarta", Role: string(auth.RoleViewer)},
+			wantCode: codes.NotFound,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			existing := newTestUser(t, "ops.jakarta", auth.RoleInventoryOperator)
+			store := newMemoryStore(existing, newTestUser(t, "merch.team", auth.RoleMerchandiser))
+			h := NewHandler(zerolog.Nop(), store)
+
+			user := test.user
+			user.UserId = existing.ID.Hex()
+			if test.id != "" {
+				user.UserId = test.id
+			}
+			_, err := h.Update(context.Background(), &uspb.UpdateRequest{User: user, Password: test.password})
+			if got := status.Code(err); got != test.wantCode {
+				t.Fatalf("Update(_, _) code, got = %v, want = %v, error = %v", got, test.wantCode, err)
+			}
+			if test.wantCode != codes.OK {
+				return
+			}
+
+			u, err := store.GetUser(context.Background(), existing.ID)
+			if err != nil {
+				t.Fatalf("unexpected error, got = %v", err)
+			}
+			if string(u.Role) != user.Role || u.Disabled != user.Disabled {
+				t.Fatalf("updated user, got = %+v, want = %+v", u, user)
+			}
+			if !auth.CheckPassword(u.PasswordHash, test.wantPassword) {
+				t.Fatalf("expected user password to be %q", test.wantPassword)
+			}
+			if !u.CreatedAt.Equal(existing.CreatedAt) {
+				t.Fatalf("updated user created at, got = %v, want = %v", u.CreatedAt, existing.CreatedAt)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	t.Parallel()
+
+	store := newMemoryStore(newTestUser(t, "ops.jakarta", auth.RoleInventoryOperator))
+	h := NewHandler(zerolog.Nop(), store)
+
+	res, err := h.List(context.Background(), &uspb.ListRequest{})
+	if err != nil {
+		t.Fatalf("List(_, _) error, got = %v", err)
+	}
+	if len(res.Users) != 1 || res.Users[0].Username != "ops.jakarta" || res.Users[0].Role != string(auth.RoleInventoryOperator) {
+		t.Fatalf("List(_, _) users, got = %+v", res.Users)
+	}
+}
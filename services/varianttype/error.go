@@ -0,0 +1,10 @@
+package varianttype
+
+import "errors"
+
+var (
+	ErrInvalidVariantTypeID  = errors.New("invalid variant type id")
+	ErrVariantTypeIsRequired = errors.New("variant type is required")
+	ErrVariantTypeNotFound   = errors.New("variant type not found")
+	ErrNameAlreadyExists     = errors.New("variant type name already exists")
+)
@@ -0,0 +1,115 @@
+package varianttype
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/product"
+)
+
+const variantTypeCollection = "variant_type"
+
+// nameCollation compares variant type names regardless of their case, the way
+// product variants are linked to variant types by the importer.
+var nameCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// Store is the storage contract required by the variant type service.
+type Store interface {
+	ListVariantTypes(ctx context.Context) ([]*product.VariantType, error)
+	GetVariantType(ctx context.Context, id primitive.ObjectID) (*product.VariantType, error)
+	NameExists(ctx context.Context, name string, excludeID primitive.ObjectID) (bool, error)
+	CreateVariantType(ctx context.Context, vt *product.VariantType) error
+	UpdateVariantType(ctx context.Context, vt *product.VariantType) error
+}
+
+// MongoStore implements Store backed by mongo database.
+type MongoStore struct {
+	db *mongo.Database
+}
+
+// NewMongoStore returns a new variant type store backed by db.
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{db: db}
+}
+
+// CreateIndexes creates the indexes of the variant type collection,
+// variant type names are unique regardless of their case.
+func (s *MongoStore) CreateIndexes(ctx context.Context) error {
+	if _, err := s.db.Collection(variantTypeCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true).SetCollation(nameCollation),
+	}); err != nil {
+		return fmt.Errorf("failed to create variant type name index: %w", err)
+	}
+	return nil
+}
+
+// ListVariantTypes fetches all variant types ordered by their name.
+func (s *MongoStore) ListVariantTypes(ctx context.Context) ([]*product.VariantType, error) {
+	cur, err := s.db.Collection(variantTypeCollection).Find(ctx, bson.M{},
+		options.Find().SetSort(bson.D{{Key: "name", Value: 1}}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute Find variant types: %w", err)
+	}
+
+	var variantTypes []*product.VariantType
+	if err := cur.All(ctx, &variantTypes); err != nil {
+		return nil, fmt.Errorf("failed to decode variant types: %w", err)
+	}
+	return variantTypes, nil
+}
+
+// GetVariantType fetches a variant type by its id.
+func (s *MongoStore) GetVariantType(ctx context.Context, id primitive.ObjectID) (*product.VariantType, error) {
+	vt := &product.VariantType{}
+	if err := s.db.Collection(variantTypeCollection).FindOne(ctx, bson.M{"_id": id}).Decode(vt); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrVariantTypeNotFound
+		}
+		return nil, fmt.Errorf("failed to execute FindOne variant type: %w", err)
+	}
+	return vt, nil
+}
+
+// NameExists checks whether a variant type other than excludeID already uses the name,
+// names are compared regardless of their case.
+func (s *MongoStore) NameExists(ctx context.Context, name string, excludeID primitive.ObjectID) (bool, error) {
+	n, err := s.db.Collection(variantTypeCollection).CountDocuments(ctx,
+		bson.M{
+			"_id":  bson.M{"$ne": excludeID},
+			"name": name,
+		},
+		options.Count().SetCollation(nameCollation),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute CountDocuments variant type: %w", err)
+	}
+	return n > 0, nil
+}
+
+// CreateVariantType inserts a new variant type.
+func (s *MongoStore) CreateVariantType(ctx context.Context, vt *product.VariantType) error {
+	if _, err := s.db.Collection(variantTypeCollection).InsertOne(ctx, vt); err != nil {
+		return fmt.Errorf("failed to execute InsertOne variant type: %w", err)
+	}
+	return nil
+}
+
+// UpdateVariantType replaces an existing variant type.
+func (s *MongoStore) UpdateVariantType(ctx context.Context, vt *product.VariantType) error {
+	res, err := s.db.Collection(variantTypeCollection).ReplaceOne(ctx, bson.M{"_id": vt.ID}, vt)
+	if err != nil {
+		return fmt.Errorf("failed to execute ReplaceOne variant type: %w", err)
+	}
+	if res.MatchedCount < 1 {
+		return ErrVariantTypeNotFound
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+// Package varianttype implements variant type gRPC service methods
+// to manage the types of dropezy product variants, e.g. size or flavour.
+package varianttype
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/product"
+
+	"github.com/dropezy/storefront-backend/ems-api/services"
+	"github.com/dropezy/storefront-backend/ems-api/services/audit"
+	"github.com/dropezy/storefront-backend/ems-api/services/i18n"
+	"github.com/dropezy/storefront-backend/ems-api/validation"
+
+	// protobuf
+	vtpb "github.com/dropezy/proto/ems/v1/varianttype"
+)
+
+const serviceName = "variant-type"
+
+// Handler holds variant type gRPC service implementation.
+type Handler struct {
+	// utilities
+	logger zerolog.Logger
+
+	// service dependencies
+	store Store
+}
+
+// NewHandler returns a new variant type service handler.
+func NewHandler(logger zerolog.Logger, store Store) *Handler {
+	return &Handler{
+		logger: logger.With().Str("service", serviceName).Logger(),
+		store:  store,
+	}
+}
+
+// RegisterService registers the variant type service to the gRPC server.
+func RegisterService(logger zerolog.Logger, store Store) func(srv *grpc.Server) error {
+	return func(srv *grpc.Server) error {
+		h := NewHandler(logger, store)
+		vtpb.RegisterVariantTypeServiceServer(srv, h)
+		return nil
+	}
+}
+
+func RegisterGateway(ctx context.Context, mux *runtime.ServeMux, addr string, opts []grpc.DialOption) error {
+	return vtpb.RegisterVariantTypeServiceHandlerFromEndpoint(ctx, mux, addr, opts)
+}
+
+// List will fetch variant types from storage.
+func (h *Handler) List(ctx context.Context, req *vtpb.ListRequest) (*vtpb.ListResponse, error) {
+	variantTypes, err := h.store.ListVariantTypes(ctx)
+	if err != nil {
+		h.logger.Err(err).Msg("failed to fetch variant types from store")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	loc := i18n.FromContext(ctx)
+	var variantTypesPb []*vtpb.VariantType
+	for _, vt := range variantTypes {
+		variantTypesPb = append(variantTypesPb, toVariantTypePb(vt, loc))
+	}
+
+	return &vtpb.ListResponse{
+		VariantTypes: variantTypesPb,
+	}, nil
+}
+
+// Get will fetch a variant type by its id from storage.
+func (h *Handler) Get(ctx context.Context, req *vtpb.GetRequest) (*vtpb.GetResponse, error) {
+	id, err := primitive.ObjectIDFromHex(req.GetVariantTypeId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidVariantTypeID.Error())
+	}
+
+	vt, err := h.store.GetVariantType(ctx, id)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch variant type from store")
+	}
+
+	return &vtpb.GetResponse{
+		VariantType: toVariantTypePb(vt, i18n.FromContext(ctx)),
+	}, nil
+}
+
+// Create will validate and insert a new variant type.
+func (h *Handler) Create(ctx context.Context, req *vtpb.CreateRequest) (*vtpb.CreateResponse, error) {
+	if req.GetVariantType() == nil {
+		return nil, status.Error(codes.InvalidArgument, ErrVariantTypeIsRequired.Error())
+	}
+
+	vt := fromVariantTypePb(req.GetVariantType())
+	vt.ID = primitive.NewObjectID()
+	if err := h.validateVariantType(ctx, vt); err != nil {
+		return nil, err
+	}
+
+	if err := h.store.CreateVariantType(ctx, vt); err != nil {
+		h.logger.Err(err).Msg("failed to create variant type on store")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	audit.Track(ctx, audit.EntityVariantType, vt.ID.Hex(), nil, vt)
+
+	return &vtpb.CreateResponse{
+		VariantType: toVariantTypePb(vt, i18n.FromContext(ctx)),
+	}, nil
+}
+
+// Update will validate and replace an existing variant type.
+func (h *Handler) Update(ctx context.Context, req *vtpb.UpdateRequest) (*vtpb.UpdateResponse, error) {
+	if req.GetVariantType() == nil {
+		return nil, status.Error(codes.InvalidArgument, ErrVariantTypeIsRequired.Error())
+	}
+	id, err := primitive.ObjectIDFromHex(req.GetVariantType().GetVariantTypeId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, ErrInvalidVariantTypeID.Error())
+	}
+
+	vt := fromVariantTypePb(req.GetVariantType())
+	vt.ID = id
+	if err := h.validateVariantType(ctx, vt); err != nil {
+		return nil, err
+	}
+
+	existing, err := h.store.GetVariantType(ctx, vt.ID)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch variant type from store")
+	}
+	if err := h.store.UpdateVariantType(ctx, vt); err != nil {
+		return nil, h.toStatusError(err, "failed to update variant type on store")
+	}
+
+	vt, err = h.store.GetVariantType(ctx, vt.ID)
+	if err != nil {
+		return nil, h.toStatusError(err, "failed to fetch variant type from store")
+	}
+	audit.Track(ctx, audit.EntityVariantType, vt.ID.Hex(), existing, vt)
+
+	return &vtpb.UpdateResponse{
+		VariantType: toVariantTypePb(vt, i18n.FromContext(ctx)),
+	}, nil
+}
+
+// validateVariantType checks the variant type parameters and makes sure
+// the name is unique, it returns a gRPC status error.
+func (h *Handler) validateVariantType(ctx context.Context, vt *product.VariantType) error {
+	if err := validation.ValidateVariantType(vt); err != nil {
+		return services.InvalidArgumentError(err)
+	}
+
+	exists, err := h.store.NameExists(ctx, vt.Name, vt.ID)
+	if err != nil {
+		h.logger.Err(err).Msg("failed to check variant type name on store")
+		return status.Error(codes.Internal, err.Error())
+	}
+	if exists {
+		return status.Error(codes.AlreadyExists, ErrNameAlreadyExists.Error())
+	}
+	return nil
+}
+
+// toStatusError converts store errors to gRPC status errors,
+// unexpected errors are logged with msg.
+func (h *Handler) toStatusError(err error, msg string) error {
+	if errors.Is(err, ErrVariantTypeNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	h.logger.Err(err).Msg(msg)
+	return status.Error(codes.Internal, err.Error())
+}
+
+// toVariantTypePb converts a variant type to protobuf message, the label is
+// localized with loc while the labels of every language are always set for editing.
+func toVariantTypePb(vt *product.VariantType, loc *i18n.Localizer) *vtpb.VariantType {
+	pb := &vtpb.VariantType{
+		VariantTypeId: vt.ID.Hex(),
+		Name:          vt.Name,
+		Label:         loc.String(i18n.Text{ID: vt.Label_ID, EN: vt.Label_EN}),
+		LabelEn:       vt.Label_EN,
+		LabelId:       vt.Label_ID,
+	}
+	for _, q := range vt.Quantifiers {
+		pb.Quantifiers = append(pb.Quantifiers, &vtpb.Quantifier{
+			NameEn: q.Name_EN,
+			NameId: q.Name_ID,
+		})
+	}
+	return pb
+}
+
+func fromVariantTypePb(pb *vtpb.VariantType) *product.VariantType {
+	vt := &product.VariantType{
+		Name:     pb.GetName(),
+		Label_EN: pb.GetLabelEn(),
+		Label_ID: pb.GetLabelId(),
+	}
+	for _, q := range pb.GetQuantifiers() {
+		vt.Quantifiers = append(vt.Quantifiers, &product.Quantifier{
+			Name_EN: q.GetNameEn(),
+			Name_ID: q.GetNameId(),
+		})
+	}
+	return vt
+}
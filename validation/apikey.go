@@ -0,0 +1,46 @@
+package validation
+
+import (
+	"errors"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/dropezy/storefront-backend/ems-api/services/auth"
+)
+
+var (
+	ErrAPIKeyNameIsRequired    = errors.New("api key name is required")
+	ErrAPIKeyScopesAreRequired = errors.New("api key scopes are required")
+	ErrInvalidAPIKeyScope      = errors.New("api key scope must be a full method name, e.g. /ems.v1.brand.BrandService/List, or a service wildcard, e.g. /ems.v1.brand.BrandService/*")
+	ErrInvalidAPIKeyStoreID    = errors.New("api key store id must be a valid store id")
+	ErrAPIKeyAlreadyExpired    = errors.New("api key expiry must be in the future")
+)
+
+// scopePattern matches full RPC method names and service wildcards.
+var scopePattern = regexp.MustCompile(`^/[A-Za-z0-9_.]+/([A-Za-z0-9_]+|\*)$`)
+
+// ValidateAPIKey checks whether all required API key parameters are fulfilled.
+func ValidateAPIKey(k *auth.APIKey) error {
+	if k.Name == "" {
+		return fieldError("name", ErrAPIKeyNameIsRequired)
+	}
+	if len(k.Scopes) == 0 {
+		return fieldError("scopes", ErrAPIKeyScopesAreRequired)
+	}
+	for _, scope := range k.Scopes {
+		if !scopePattern.MatchString(scope) {
+			return fieldError("scopes", ErrInvalidAPIKeyScope)
+		}
+	}
+	for _, id := range k.StoreIDs {
+		if !primitive.IsValidObjectID(id) {
+			return fieldError("store_ids", ErrInvalidAPIKeyStoreID)
+		}
+	}
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(time.Now()) {
+		return fieldError("expires_at", ErrAPIKeyAlreadyExpired)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package validation
+
+import (
+	"errors"
+	"regexp"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/brand"
+)
+
+var (
+	ErrBrandNameIsRequired = errors.New("brand name is required")
+	ErrBrandSlugIsRequired = errors.New("brand slug is required")
+	ErrBrandInvalidSlug    = errors.New("brand slug must only contain lowercase letters, numbers and dashes")
+)
+
+// slugPattern matches lowercase words separated by single dashes, e.g. "indomie-goreng".
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// ValidateBrand checks whether all required brand parameters are fulfilled,
+// every invalid parameter is reported.
+func ValidateBrand(b *brand.Brand) error {
+	var errs fieldErrors
+	if b.Name == "" {
+		errs.add("name", ErrBrandNameIsRequired)
+	}
+	switch {
+	case b.Slug == "":
+		errs.add("slug", ErrBrandSlugIsRequired)
+	case !slugPattern.MatchString(b.Slug):
+		errs.add("slug", ErrBrandInvalidSlug)
+	}
+	return errs.err()
+}
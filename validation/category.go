@@ -0,0 +1,55 @@
+package validation
+
+import (
+	"errors"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/category"
+)
+
+var (
+	ErrCategoryNameENIsRequired   = errors.New("category name EN is required")
+	ErrCategoryNameIDIsRequired   = errors.New("category name ID is required")
+	ErrAbbreviationIsRequired     = errors.New("abbreviation is required")
+	ErrCategoryImageURLIsRequired = errors.New("category image url is required")
+
+	ErrSubcategoryNameENIsRequired   = errors.New("subcategory name EN is required")
+	ErrSubcategoryNameIDIsRequired   = errors.New("subcategory name ID is required")
+	ErrSubcategoryImageURLIsRequired = errors.New("subcategory image url is required")
+)
+
+// ValidateParentCategory checks whether all required level 1 category parameters are fulfilled,
+// every missing parameter is reported.
+func ValidateParentCategory(ct *category.Category) error {
+	var errs fieldErrors
+	if ct.Name_EN == "" {
+		errs.add("name_en", ErrCategoryNameENIsRequired)
+	}
+	if ct.Name_ID == "" {
+		errs.add("name_id", ErrCategoryNameIDIsRequired)
+	}
+	if ct.Abbreviation == "" {
+		errs.add("abbreviation", ErrAbbreviationIsRequired)
+	}
+	// for now checking the 0 index should suffice
+	if len(ct.ImagesURLs) < 1 || ct.ImagesURLs[0] == "" {
+		errs.add("images_urls", ErrCategoryImageURLIsRequired)
+	}
+	return errs.err()
+}
+
+// ValidateChildCategory checks whether all required level 2 category parameters are fulfilled,
+// every missing parameter is reported.
+func ValidateChildCategory(ct *category.Category) error {
+	var errs fieldErrors
+	if ct.Name_EN == "" {
+		errs.add("name_en", ErrSubcategoryNameENIsRequired)
+	}
+	if ct.Name_ID == "" {
+		errs.add("name_id", ErrSubcategoryNameIDIsRequired)
+	}
+	// for now checking the 0 index should suffice
+	if len(ct.ImagesURLs) < 1 || ct.ImagesURLs[0] == "" {
+		errs.add("images_urls", ErrSubcategoryImageURLIsRequired)
+	}
+	return errs.err()
+}
@@ -0,0 +1,102 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/product"
+)
+
+var (
+	ErrProductIDIsRequired          = errors.New("product id is required")
+	ErrProductNameENIsRequired      = errors.New("product name EN is required")
+	ErrProductNameIDIsRequired      = errors.New("product name ID is required")
+	ErrProductBrandIDIsRequired     = errors.New("product brand id is required")
+	ErrProductCategory1IDIsRequired = errors.New("product category 1 id is required")
+	ErrProductCategory2IDIsRequired = errors.New("product categories 2 id is required")
+	ErrProductVariantsIsRequired    = errors.New("product variants is required")
+
+	ErrProductVariantIDIsRequired            = errors.New("product variant id is required")
+	ErrShoptreeVariantIDIsRequired           = errors.New("shoptree variant id is required")
+	ErrProductVariantImageURLIsRequired      = errors.New("product variant image url is required")
+	ErrProductVariantVariantTypeIDIsRequired = errors.New("product variant variant id is required")
+	ErrVariantValueIsRequired                = errors.New("variant value is required")
+	ErrVariantQuantifierIDIsRequired         = errors.New("variant quantifier ID is required")
+	ErrVariantQuantifierENIsRequired         = errors.New("variant quantifier EN is required")
+	ErrSKUIsRequired                         = errors.New("sku is required")
+	ErrSKUAndCategoryAbbreviationMismatch    = errors.New("sku and category abbreviation mismatch")
+	ErrBarcodeIsRequired                     = errors.New("barcode is required")
+)
+
+// ValidateProduct checks whether all required product parameters are fulfilled,
+// every missing parameter is reported.
+func ValidateProduct(p *product.Product) error {
+	var errs fieldErrors
+	if p.ID == primitive.NilObjectID { // just in case id is not properly generated.
+		errs.add("product_id", ErrProductIDIsRequired)
+	}
+	if p.Name_ID == "" {
+		errs.add("name_id", ErrProductNameIDIsRequired)
+	}
+	if p.Name_EN == "" {
+		errs.add("name_en", ErrProductNameENIsRequired)
+	}
+	if p.BrandID == primitive.NilObjectID {
+		errs.add("brand_id", ErrProductBrandIDIsRequired)
+	}
+	if p.Category1ID == primitive.NilObjectID {
+		errs.add("category_1", ErrProductCategory1IDIsRequired)
+	}
+	if p.Category2ID == primitive.NilObjectID {
+		errs.add("category_2", ErrProductCategory2IDIsRequired)
+	}
+	if len(p.Variants) < 1 {
+		errs.add("variants", ErrProductVariantsIsRequired)
+	}
+	return errs.err()
+}
+
+// ValidateProductVariant checks whether all required product variant parameters are fulfilled,
+// every missing parameter is reported.
+func ValidateProductVariant(pv *product.ProductVariant) error {
+	var errs fieldErrors
+	if pv.ID == primitive.NilObjectID {
+		errs.add("variant_id", ErrProductVariantIDIsRequired)
+	}
+	if pv.ShoptreeVariantID == "" {
+		errs.add("shoptree_variant_id", ErrShoptreeVariantIDIsRequired)
+	}
+	if len(pv.ImagesURLs) < 1 || pv.ImagesURLs[0] == "" {
+		errs.add("images_urls", ErrProductVariantImageURLIsRequired)
+	}
+	if pv.VariantTypeID == primitive.NilObjectID {
+		errs.add("variant_type_id", ErrProductVariantVariantTypeIDIsRequired)
+	}
+	if pv.VariantValue == "" {
+		errs.add("variant_value", ErrVariantValueIsRequired)
+	}
+	if pv.VariantQuantifier_ID == "" {
+		errs.add("variant_quantifier_id", ErrVariantQuantifierIDIsRequired)
+	}
+	if pv.VariantQuantifier_EN == "" {
+		errs.add("variant_quantifier_en", ErrVariantQuantifierENIsRequired)
+	}
+	if pv.SKU == "" {
+		errs.add("sku", ErrSKUIsRequired)
+	}
+	if pv.Barcode == "" {
+		errs.add("barcode", ErrBarcodeIsRequired)
+	}
+	return errs.err()
+}
+
+// ValidateSKU checks whether the variant sku is designated with
+// the abbreviation of its level 1 category.
+func ValidateSKU(sku, abbreviation string) error {
+	if !strings.Contains(sku, abbreviation) {
+		return fieldError("sku", ErrSKUAndCategoryAbbreviationMismatch)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/product"
+)
+
+func TestValidateProductVariant(t *testing.T) {
+	t.Parallel()
+
+	validVariant := func() *product.ProductVariant {
+		return &product.ProductVariant{
+			ID:                   primitive.NewObjectID(),
+			ShoptreeVariantID:    "shoptree-variant-id",
+			ImagesURLs:           []string{"DNE0001-0.webp"},
+			VariantTypeID:        primitive.NewObjectID(),
+			VariantValue:         "10",
+			VariantQuantifier_ID: "pcs",
+			VariantQuantifier_EN: "pcs",
+			SKU:                  "DNE0001",
+			Barcode:              "111199",
+		}
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+
+		if err := ValidateProductVariant(validVariant()); err != nil {
+			t.Fatalf("expected nil error, got = %v", err)
+		}
+	})
+
+	tests := []struct {
+		name      string
+		modify    func(pv *product.ProductVariant)
+		wantField string
+		wantErr   error
+	}{
+		{
+			name:      "EmptyImagesURLs",
+			modify:    func(pv *product.ProductVariant) { pv.ImagesURLs = nil },
+			wantField: "images_urls",
+			wantErr:   ErrProductVariantImageURLIsRequired,
+		},
+		{
+			name:      "EmptySKU",
+			modify:    func(pv *product.ProductVariant) { pv.SKU = "" },
+			wantField: "sku",
+			wantErr:   ErrSKUIsRequired,
+		},
+		{
+			name:      "EmptyBarcode",
+			modify:    func(pv *product.ProductVariant) { pv.Barcode = "" },
+			wantField: "barcode",
+			wantErr:   ErrBarcodeIsRequired,
+		},
+	}
+	t.Run("EveryField", func(t *testing.T) {
+		t.Parallel()
+
+		pv := validVariant()
+		for _, test := range tests {
+			test.modify(pv)
+		}
+
+		fields := Fields(ValidateProductVariant(pv))
+		if len(fields) != len(tests) {
+			t.Fatalf("expected %d field errors, got = %v", len(tests), fields)
+		}
+		for i, test := range tests {
+			if fields[i].Field != test.wantField || !errors.Is(fields[i], test.wantErr) {
+				t.Fatalf("field error %d, got = %s: %v, want = %s: %v", i, fields[i].Field, fields[i], test.wantField, test.wantErr)
+			}
+		}
+	})
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			pv := validVariant()
+			test.modify(pv)
+
+			err := ValidateProductVariant(pv)
+			var fe *FieldError
+			if !errors.As(err, &fe) || !errors.Is(err, test.wantErr) || fe.Field != test.wantField {
+				t.Fatalf("ValidateProductVariant(_) error, got = %v, want = %s: %v", err, test.wantField, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSKU(t *testing.T) {
+	t.Parallel()
+
+	if err := ValidateSKU("DNE0001", "DNE"); err != nil {
+		t.Fatalf("expected nil error, got = %v", err)
+	}
+	if err := ValidateSKU("BNM0001", "DNE"); !errors.Is(err, ErrSKUAndCategoryAbbreviationMismatch) {
+		t.Fatalf("ValidateSKU(_, _) error, got = %v, want = %v", err, ErrSKUAndCategoryAbbreviationMismatch)
+	}
+}
@@ -0,0 +1,66 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/darkstore"
+)
+
+var (
+	ErrStoreNameIsRequired               = errors.New("store name is required")
+	ErrStoreLocationCodeIsRequired       = errors.New("store location code is required")
+	ErrStoreShoptreeLocationIDIsRequired = errors.New("store shoptree location id is required")
+	ErrStoreInvalidLatitude              = errors.New("store latitude must be between -90 and 90")
+	ErrStoreInvalidLongitude             = errors.New("store longitude must be between -180 and 180")
+	ErrStoreInvalidOpeningHours          = errors.New("store opening hours are invalid")
+)
+
+// openingHoursLayout is the time layout of store opening and closing times.
+const openingHoursLayout = "15:04"
+
+// ValidateStore checks whether all required store parameters are fulfilled,
+// every invalid parameter is reported.
+func ValidateStore(s *darkstore.Store) error {
+	var errs fieldErrors
+	if s.Name == "" {
+		errs.add("name", ErrStoreNameIsRequired)
+	}
+	if s.LocationCode == "" {
+		errs.add("location_code", ErrStoreLocationCodeIsRequired)
+	}
+	if s.ShoptreeLocationID == "" {
+		errs.add("shoptree_location_id", ErrStoreShoptreeLocationIDIsRequired)
+	}
+	if s.Latitude < -90 || s.Latitude > 90 {
+		errs.add("latitude", ErrStoreInvalidLatitude)
+	}
+	if s.Longitude < -180 || s.Longitude > 180 {
+		errs.add("longitude", ErrStoreInvalidLongitude)
+	}
+
+	for i, oh := range s.OpeningHours {
+		if err := validateOpeningHours(oh); err != nil {
+			errs.add(fmt.Sprintf("opening_hours[%d]", i), err)
+		}
+	}
+	return errs.err()
+}
+
+// validateOpeningHours checks whether the day and times of opening hours are valid,
+// stores closing after midnight are not supported.
+func validateOpeningHours(oh *darkstore.OpeningHours) error {
+	if oh.Day < time.Sunday || oh.Day > time.Saturday {
+		return ErrStoreInvalidOpeningHours
+	}
+	open, err := time.Parse(openingHoursLayout, oh.Open)
+	if err != nil {
+		return ErrStoreInvalidOpeningHours
+	}
+	closing, err := time.Parse(openingHoursLayout, oh.Close)
+	if err != nil || !closing.After(open) {
+		return ErrStoreInvalidOpeningHours
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package validation
+
+import (
+	"errors"
+	"regexp"
+
+	"github.com/dropezy/storefront-backend/ems-api/services/auth"
+)
+
+var (
+	ErrUsernameIsRequired = errors.New("username is required")
+	ErrInvalidUsername    = errors.New("username must only contain lowercase letters, numbers, dots, dashes and underscores")
+	ErrInvalidUserRole    = errors.New("user role must be one of viewer, merchandiser, inventory-operator or admin")
+	ErrPasswordTooShort   = errors.New("password must be at least 12 characters")
+)
+
+// minPasswordLength is the minimum length of user passwords.
+const minPasswordLength = 12
+
+// usernamePattern matches lowercase usernames, e.g. "ops.jakarta".
+var usernamePattern = regexp.MustCompile(`^[a-z0-9._-]+$`)
+
+// ValidateUser checks whether all required user parameters are fulfilled.
+func ValidateUser(u *auth.User) error {
+	switch {
+	case u.Username == "":
+		return fieldError("username", ErrUsernameIsRequired)
+	case !usernamePattern.MatchString(u.Username):
+		return fieldError("username", ErrInvalidUsername)
+	}
+	if _, err := auth.ParseRole(string(u.Role)); err != nil {
+		return fieldError("role", ErrInvalidUserRole)
+	}
+	return nil
+}
+
+// ValidatePassword checks whether the password is long enough.
+func ValidatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return fieldError("password", ErrPasswordTooShort)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dropezy/storefront-backend/ems-api/services/auth"
+)
+
+func TestValidateUser(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		user    *auth.User
+		wantErr error
+	}{
+		{name: "Valid", user: &auth.User{Username: "ops.jakarta-1_a", Role: auth.RoleViewer}},
+		{name: "EmptyUsername", user: &auth.User{Role: auth.RoleViewer}, wantErr: ErrUsernameIsRequired},
+		{name: "UppercaseUsername", user: &auth.User{Username: "Ops", Role: auth.RoleViewer}, wantErr: ErrInvalidUsername},
+		{name: "UsernameWithSpace", user: &auth.User{Username: "ops jakarta", Role: auth.RoleViewer}, wantErr: ErrInvalidUsername},
+		{name: "EmptyRole", user: &auth.User{Username: "ops"}, wantErr: ErrInvalidUserRole},
+		{name: "UnknownRole", user: &auth.User{Username: "ops", Role: "root"}, wantErr: ErrInvalidUserRole},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := ValidateUser(test.user); !errors.Is(err, test.wantErr) {
+				t.Fatalf("ValidateUser(_) error, got = %v, want = %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	t.Parallel()
+
+	if err := ValidatePassword("twelve-chars"); err != nil {
+		t.Fatalf("ValidatePassword(_) error, got = %v", err)
+	}
+	if err := ValidatePassword("eleven-char"); !errors.Is(err, ErrPasswordTooShort) {
+		t.Fatalf("ValidatePassword(_) error, got = %v, want = %v", err, ErrPasswordTooShort)
+	}
+}
@@ -0,0 +1,80 @@
+// Package validation holds catalog validation rules shared by
+// the ems gRPC services and the data importer.
+package validation
+
+import (
+	"errors"
+	"strings"
+)
+
+// FieldError describes a validation failure on a single field.
+type FieldError struct {
+	// Field is the name of the invalid field, e.g. name_en.
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// fieldError returns a FieldError for field.
+func fieldError(field string, err error) error {
+	return &FieldError{Field: field, Err: err}
+}
+
+// FieldErrors describes validation failures on several fields, it unwraps
+// to its first field error.
+type FieldErrors []*FieldError
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e FieldErrors) Unwrap() error {
+	if len(e) < 1 {
+		return nil
+	}
+	return e[0]
+}
+
+// fieldErrors collects the field errors of a validation.
+type fieldErrors FieldErrors
+
+// add records err on field.
+func (e *fieldErrors) add(field string, err error) {
+	*e = append(*e, &FieldError{Field: field, Err: err})
+}
+
+// err returns nil when no field error was recorded, the single FieldError
+// when there is only one or FieldErrors otherwise.
+func (e fieldErrors) err() error {
+	switch len(e) {
+	case 0:
+		return nil
+	case 1:
+		return e[0]
+	}
+	return FieldErrors(e)
+}
+
+// Fields returns the field errors of err, err is either a FieldError or FieldErrors.
+func Fields(err error) []*FieldError {
+	var fes FieldErrors
+	if errors.As(err, &fes) {
+		return fes
+	}
+	var fe *FieldError
+	if errors.As(err, &fe) {
+		return []*FieldError{fe}
+	}
+	return nil
+}
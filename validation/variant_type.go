@@ -0,0 +1,63 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/product"
+)
+
+var (
+	ErrVariantTypeNameIsRequired       = errors.New("variant type name is required")
+	ErrVariantTypeLabelENIsRequired    = errors.New("variant type label EN is required")
+	ErrVariantTypeLabelIDIsRequired    = errors.New("variant type label ID is required")
+	ErrVariantTypeQuantifierIsRequired = errors.New("variant type quantifier EN and ID names are required")
+	ErrVariantTypeDuplicateQuantifier  = errors.New("variant type quantifier is shown more than once")
+	ErrVariantQuantifierNotAllowed     = errors.New("variant quantifier is not allowed for the variant type")
+)
+
+// ValidateVariantType checks whether all required variant type parameters are fulfilled,
+// quantifiers are optional since variant types without quantifiers allow any quantifier.
+// Every invalid parameter is reported.
+func ValidateVariantType(vt *product.VariantType) error {
+	var errs fieldErrors
+	if vt.Name == "" {
+		errs.add("name", ErrVariantTypeNameIsRequired)
+	}
+	if vt.Label_EN == "" {
+		errs.add("label_en", ErrVariantTypeLabelENIsRequired)
+	}
+	if vt.Label_ID == "" {
+		errs.add("label_id", ErrVariantTypeLabelIDIsRequired)
+	}
+
+	seen := map[string]bool{}
+	for i, q := range vt.Quantifiers {
+		field := fmt.Sprintf("quantifiers[%d]", i)
+		switch {
+		case q.Name_EN == "" || q.Name_ID == "":
+			errs.add(field, ErrVariantTypeQuantifierIsRequired)
+		case seen[strings.ToLower(q.Name_EN)]:
+			errs.add(field, ErrVariantTypeDuplicateQuantifier)
+		}
+		seen[strings.ToLower(q.Name_EN)] = true
+	}
+	return errs.err()
+}
+
+// ValidateVariantQuantifier checks whether the product variant quantifier
+// is one of the quantifiers allowed by its variant type. Variant types without
+// quantifiers, e.g. the legacy UOM variant type, allow any quantifier.
+func ValidateVariantQuantifier(vt *product.VariantType, pv *product.ProductVariant) error {
+	if len(vt.Quantifiers) < 1 {
+		return nil
+	}
+	for _, q := range vt.Quantifiers {
+		if strings.EqualFold(q.Name_EN, pv.VariantQuantifier_EN) &&
+			strings.EqualFold(q.Name_ID, pv.VariantQuantifier_ID) {
+			return nil
+		}
+	}
+	return fieldError("variant_quantifier_en", ErrVariantQuantifierNotAllowed)
+}
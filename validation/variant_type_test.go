@@ -0,0 +1,118 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dropezy/storefront-backend/internal/storage/model/product"
+)
+
+func TestValidateVariantType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		variantType *product.VariantType
+		wantErr     error
+	}{
+		{
+			name: "Valid",
+			variantType: &product.VariantType{
+				Name:        "size",
+				Label_EN:    "Size",
+				Label_ID:    "Ukuran",
+				Quantifiers: []*product.Quantifier{{Name_EN: "ml", Name_ID: "ml"}},
+			},
+		},
+		{
+			name:        "WithoutQuantifiers",
+			variantType: &product.VariantType{Name: "UOM", Label_EN: "Unit of measure", Label_ID: "Satuan"},
+		},
+		{
+			name:        "EmptyLabelID",
+			variantType: &product.VariantType{Name: "UOM", Label_EN: "Unit of measure"},
+			wantErr:     ErrVariantTypeLabelIDIsRequired,
+		},
+		{
+			name: "DuplicateQuantifier",
+			variantType: &product.VariantType{
+				Name:     "size",
+				Label_EN: "Size",
+				Label_ID: "Ukuran",
+				Quantifiers: []*product.Quantifier{
+					{Name_EN: "ml", Name_ID: "ml"},
+					{Name_EN: "ML", Name_ID: "ml"},
+				},
+			},
+			wantErr: ErrVariantTypeDuplicateQuantifier,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := ValidateVariantType(test.variantType); !errors.Is(err, test.wantErr) {
+				t.Fatalf("ValidateVariantType(_) error, got = %v, want = %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateVariantQuantifier(t *testing.T) {
+	t.Parallel()
+
+	vt := &product.VariantType{
+		Name:     "size",
+		Label_EN: "Size",
+		Label_ID: "Ukuran",
+		Quantifiers: []*product.Quantifier{
+			{Name_EN: "ml", Name_ID: "ml"},
+			{Name_EN: "pack", Name_ID: "pak"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		variantType *product.VariantType
+		quantifier  [2]string
+		wantErr     error
+	}{
+		{
+			name:        "Allowed",
+			variantType: vt,
+			quantifier:  [2]string{"Pack", "pak"},
+		},
+		{
+			name:        "NotAllowed",
+			variantType: vt,
+			quantifier:  [2]string{"pcs", "pcs"},
+			wantErr:     ErrVariantQuantifierNotAllowed,
+		},
+		{
+			name:        "MismatchTranslation",
+			variantType: vt,
+			quantifier:  [2]string{"pack", "ml"},
+			wantErr:     ErrVariantQuantifierNotAllowed,
+		},
+		{
+			name:        "WithoutQuantifiers",
+			variantType: &product.VariantType{Name: "UOM"},
+			quantifier:  [2]string{"pcs", "pcs"},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			pv := &product.ProductVariant{
+				VariantQuantifier_EN: test.quantifier[0],
+				VariantQuantifier_ID: test.quantifier[1],
+			}
+			if err := ValidateVariantQuantifier(test.variantType, pv); !errors.Is(err, test.wantErr) {
+				t.Fatalf("ValidateVariantQuantifier(_, _) error, got = %v, want = %v", err, test.wantErr)
+			}
+		})
+	}
+}